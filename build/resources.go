@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 
 	templatev1 "github.com/openshift/api/template/v1"
@@ -51,18 +52,31 @@ const (
 	hsClusterLabel = "hypershift.openshift.io/cluster"
 	//caBundle annotation
 	caBundleAnnotation = "service.beta.openshift.io/inject-cabundle"
+	// Defines the label hive sets on ClusterDeployments to their OpenShift major.minor version
+	versionLabel = "hive.openshift.io/version-major-minor"
+	// Go template condition which is true when package-operator deploys to a cluster
+	// serving the v1 ValidatingAdmissionPolicy API, which is GA in Kubernetes 1.30
+	admissionPolicyPackageCondition = `semverCompare ">=1.30.0-0" .environment.kubernetes.version`
 )
 
 var (
-	listenPort    = flag.Int("port", 5000, "On which port should the Webhook binary listen? (Not the Service port)")
-	secretName    = flag.String("secretname", "webhook-cert", "Secret where TLS certs are created")
-	caBundleName  = flag.String("cabundlename", "webhook-cert", "ConfigMap where CA cert is created")
-	templateFile  = flag.String("syncsetfile", "", "Path to where the SelectorSyncSet template should be written")
-	packageDir    = flag.String("packagedir", "", "Path to where the package manifest and resources should be written")
-	replicas      = flag.Int("replicas", 2, "Number of replicas for Hypershift-based MCVW deployment")
-	excludes      = flag.String("exclude", "debug-hook", "Comma-separated list of webhook names to skip")
-	only          = flag.String("only", "", "Only include these comma-separated webhooks")
-	showHookNames = flag.Bool("showhooks", false, "Print registered webhook names and exit")
+	listenPort         = flag.Int("port", 5000, "On which port should the Webhook binary listen? (Not the Service port)")
+	healthPort         = flag.Int("healthport", 8081, "On which port should the Webhook binary serve /healthz and /readyz?")
+	secretName         = flag.String("secretname", "webhook-cert", "Secret where TLS certs are created")
+	caBundleName       = flag.String("cabundlename", "webhook-cert", "ConfigMap where CA cert is created")
+	clientCAName       = flag.String("clientcaname", "", "ConfigMap with a ca-bundle.crt key to verify the kube-apiserver's client certificate against. Empty disables client certificate verification")
+	clientAllowedNames = flag.String("clientallowednames", "", "Comma-separated subject CNs or DNS SANs accepted in the kube-apiserver's client certificate. Requires -clientcaname")
+	allowlistName      = flag.String("allowlistname", "webhook-allowlist", "Optional ConfigMap holding additional principals each webhook should allow")
+	breakGlassName     = flag.String("breakglassname", "webhook-breakglass", "Optional ConfigMap holding time-bound overrides of webhook denials")
+	templateFile       = flag.String("syncsetfile", "", "Path to where the SelectorSyncSet template should be written")
+	packageDir         = flag.String("packagedir", "", "Path to where the package manifest and resources should be written")
+	replicas           = flag.Int("replicas", 2, "Number of replicas for Hypershift-based MCVW deployment")
+	excludes           = flag.String("exclude", "debug-hook", "Comma-separated list of webhook names to skip")
+	only               = flag.String("only", "", "Only include these comma-separated webhooks")
+	showHookNames      = flag.Bool("showhooks", false, "Print registered webhook names and exit")
+	admissionPolicies  = flag.Bool("admissionpolicies", false, "Render webhooks which can be expressed in CEL as ValidatingAdmissionPolicies on clusters which support them")
+	policyMinVersion   = flag.String("admissionpolicyminversion", "4.17", "OpenShift 4 major.minor version from which clusters get ValidatingAdmissionPolicies instead of webhooks in the SelectorSyncSet. Requires -admissionpolicies")
+	policyExcludes     = flag.String("admissionpolicyexcludedversions", "", "Comma-separated OpenShift major.minor versions from -admissionpolicyminversion on which keep webhooks in the SelectorSyncSet")
 
 	namespace = flag.String("namespace", "openshift-validation-webhook", "In what namespace should resources exist?")
 
@@ -324,8 +338,32 @@ func createPackagedCACertConfigMap(phase string) *corev1.ConfigMap {
 	return cm
 }
 
+// createWebhookConfigMap creates a ConfigMap configuring the webhooks, with no
+// data so that applying it never overwrites what SRE has set. Shipping it
+// means it exists before anyone else can create it; regular-user-validation
+// only lets SRE change it.
+func createWebhookConfigMap(name string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ConfigMap",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: *namespace,
+		},
+	}
+}
+
+func createPackagedWebhookConfigMap(name, phase string) *corev1.ConfigMap {
+	cm := createWebhookConfigMap(name)
+	cm.Annotations = map[string]string{pkoPhaseAnnotation: phase}
+	cm.Namespace = ""
+	return cm
+}
+
 func createPackagedDeployment(replicas int32, phase string) *appsv1.Deployment {
-	return &appsv1.Deployment{
+	deployment := &appsv1.Deployment{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "Deployment",
 			APIVersion: "apps/v1",
@@ -463,6 +501,28 @@ func createPackagedDeployment(replicas int32, phase string) *appsv1.Deployment {
 								},
 							},
 						},
+						{
+							Name: "allowlist",
+							VolumeSource: corev1.VolumeSource{
+								ConfigMap: &corev1.ConfigMapVolumeSource{
+									LocalObjectReference: corev1.LocalObjectReference{
+										Name: *allowlistName,
+									},
+									Optional: pointer.Bool(true),
+								},
+							},
+						},
+						{
+							Name: "breakglass",
+							VolumeSource: corev1.VolumeSource{
+								ConfigMap: &corev1.ConfigMapVolumeSource{
+									LocalObjectReference: corev1.LocalObjectReference{
+										Name: *breakGlassName,
+									},
+									Optional: pointer.Bool(true),
+								},
+							},
+						},
 						{
 							Name: "hosted-kubeconfig",
 							VolumeSource: corev1.VolumeSource{
@@ -503,24 +563,8 @@ func createPackagedDeployment(replicas int32, phase string) *appsv1.Deployment {
 									corev1.ResourceMemory: resource.MustParse("512Mi"),
 								},
 							},
-							LivenessProbe: &corev1.Probe{
-								ProbeHandler: corev1.ProbeHandler{
-									TCPSocket: &corev1.TCPSocketAction{
-										Port: intstr.FromInt(int(*listenPort)),
-									},
-								},
-								InitialDelaySeconds: 10,
-								PeriodSeconds:       30,
-							},
-							ReadinessProbe: &corev1.Probe{
-								ProbeHandler: corev1.ProbeHandler{
-									TCPSocket: &corev1.TCPSocketAction{
-										Port: intstr.FromInt(int(*listenPort)),
-									},
-								},
-								InitialDelaySeconds: 5,
-								PeriodSeconds:       10,
-							},
+							LivenessProbe:  livenessProbe(),
+							ReadinessProbe: readinessProbe(),
 							VolumeMounts: []corev1.VolumeMount{
 								{
 									Name:      "service-certs",
@@ -532,6 +576,16 @@ func createPackagedDeployment(replicas int32, phase string) *appsv1.Deployment {
 									MountPath: "/service-ca",
 									ReadOnly:  true,
 								},
+								{
+									Name:      "allowlist",
+									MountPath: "/allowlist",
+									ReadOnly:  true,
+								},
+								{
+									Name:      "breakglass",
+									MountPath: "/breakglass",
+									ReadOnly:  true,
+								},
 								{
 									Name:      "hosted-kubeconfig",
 									MountPath: "/etc/hosted-kubernetes",
@@ -547,8 +601,10 @@ func createPackagedDeployment(replicas int32, phase string) *appsv1.Deployment {
 								"webhooks",
 								"-tlskey", "/service-certs/tls.key",
 								"-tlscert", "/service-certs/tls.crt",
-								"-cacert", "/service-ca/service-ca.crt",
 								"-tls",
+								"-allowlist-config", "/allowlist/allowlist.yaml",
+								"-breakglass-config", "/breakglass/breakglass.yaml",
+								"-health-bind-address", fmt.Sprintf(":%d", *healthPort),
 							},
 							Env: []corev1.EnvVar{
 								{
@@ -562,10 +618,12 @@ func createPackagedDeployment(replicas int32, phase string) *appsv1.Deployment {
 			},
 		},
 	}
+	configureClientAuth(&deployment.Spec.Template.Spec)
+	return deployment
 }
 
 func createDaemonSet() *appsv1.DaemonSet {
-	return &appsv1.DaemonSet{
+	ds := &appsv1.DaemonSet{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "DaemonSet",
 			APIVersion: "apps/v1",
@@ -651,6 +709,28 @@ func createDaemonSet() *appsv1.DaemonSet {
 								},
 							},
 						},
+						{
+							Name: "allowlist",
+							VolumeSource: corev1.VolumeSource{
+								ConfigMap: &corev1.ConfigMapVolumeSource{
+									LocalObjectReference: corev1.LocalObjectReference{
+										Name: *allowlistName,
+									},
+									Optional: pointer.Bool(true),
+								},
+							},
+						},
+						{
+							Name: "breakglass",
+							VolumeSource: corev1.VolumeSource{
+								ConfigMap: &corev1.ConfigMapVolumeSource{
+									LocalObjectReference: corev1.LocalObjectReference{
+										Name: *breakGlassName,
+									},
+									Optional: pointer.Bool(true),
+								},
+							},
+						},
 					},
 					Containers: []corev1.Container{
 						{
@@ -660,6 +740,8 @@ func createDaemonSet() *appsv1.DaemonSet {
 							TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
 							Name:                     "webhooks",
 							Image:                    "${REGISTRY_IMG}@${IMAGE_DIGEST}",
+							LivenessProbe:            livenessProbe(),
+							ReadinessProbe:           readinessProbe(),
 							VolumeMounts: []corev1.VolumeMount{
 								{
 									Name:      "service-certs",
@@ -671,6 +753,16 @@ func createDaemonSet() *appsv1.DaemonSet {
 									MountPath: "/service-ca",
 									ReadOnly:  true,
 								},
+								{
+									Name:      "allowlist",
+									MountPath: "/allowlist",
+									ReadOnly:  true,
+								},
+								{
+									Name:      "breakglass",
+									MountPath: "/breakglass",
+									ReadOnly:  true,
+								},
 							},
 							Ports: []corev1.ContainerPort{
 								{
@@ -681,8 +773,10 @@ func createDaemonSet() *appsv1.DaemonSet {
 								"webhooks",
 								"-tlskey", "/service-certs/tls.key",
 								"-tlscert", "/service-certs/tls.crt",
-								"-cacert", "/service-ca/service-ca.crt",
 								"-tls",
+								"-allowlist-config", "/allowlist/allowlist.yaml",
+								"-breakglass-config", "/breakglass/breakglass.yaml",
+								"-health-bind-address", fmt.Sprintf(":%d", *healthPort),
 							},
 						},
 					},
@@ -690,6 +784,8 @@ func createDaemonSet() *appsv1.DaemonSet {
 			},
 		},
 	}
+	configureClientAuth(&ds.Spec.Template.Spec)
+	return ds
 }
 
 func createService() *corev1.Service {
@@ -780,7 +876,7 @@ func createValidatingWebhookConfiguration(hook webhooks.Webhook) admissionregv1.
 		},
 		Webhooks: []admissionregv1.ValidatingWebhook{
 			{
-				AdmissionReviewVersions: []string{"v1"},
+				AdmissionReviewVersions: utils.SupportedAdmissionReviewVersions,
 				TimeoutSeconds:          &timeout,
 				SideEffects:             &sideEffects,
 				MatchPolicy:             &matchPolicy,
@@ -837,7 +933,7 @@ func createMutatingWebhookConfiguration(hook webhooks.Webhook) admissionregv1.Mu
 		},
 		Webhooks: []admissionregv1.MutatingWebhook{
 			{
-				AdmissionReviewVersions: []string{"v1"},
+				AdmissionReviewVersions: utils.SupportedAdmissionReviewVersions,
 				TimeoutSeconds:          &timeout,
 				SideEffects:             &sideEffects,
 				MatchPolicy:             &matchPolicy,
@@ -857,6 +953,153 @@ func createMutatingWebhookConfiguration(hook webhooks.Webhook) admissionregv1.Mu
 	}
 }
 
+// admissionPolicyFor returns the ValidatingAdmissionPolicy and binding for hook,
+// or false if it isn't rendered as one
+func admissionPolicyFor(hook webhooks.Webhook) (*admissionregv1.ValidatingAdmissionPolicy, *admissionregv1.ValidatingAdmissionPolicyBinding, bool) {
+	if !*admissionPolicies || strings.HasSuffix(hook.Name(), "-mutation") {
+		return nil, nil, false
+	}
+	spec, actions, ok := webhooks.AdmissionPolicySpec(hook)
+	if !ok {
+		return nil, nil, false
+	}
+	return createValidatingAdmissionPolicy(hook, spec), createValidatingAdmissionPolicyBinding(hook, actions), true
+}
+
+func createValidatingAdmissionPolicy(hook webhooks.Webhook, spec admissionregv1.ValidatingAdmissionPolicySpec) *admissionregv1.ValidatingAdmissionPolicy {
+	return &admissionregv1.ValidatingAdmissionPolicy{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ValidatingAdmissionPolicy",
+			APIVersion: "admissionregistration.k8s.io/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: fmt.Sprintf("sre-%s", hook.Name()),
+		},
+		Spec: spec,
+	}
+}
+
+func createValidatingAdmissionPolicyBinding(hook webhooks.Webhook, actions []admissionregv1.ValidationAction) *admissionregv1.ValidatingAdmissionPolicyBinding {
+	return &admissionregv1.ValidatingAdmissionPolicyBinding{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ValidatingAdmissionPolicyBinding",
+			APIVersion: "admissionregistration.k8s.io/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: fmt.Sprintf("sre-%s", hook.Name()),
+		},
+		Spec: admissionregv1.ValidatingAdmissionPolicyBindingSpec{
+			PolicyName:        fmt.Sprintf("sre-%s", hook.Name()),
+			ValidationActions: actions,
+		},
+	}
+}
+
+// olderVersions returns the OpenShift 4 major.minor versions before min, so
+// that a label selector, which can't compare versions, can express
+// ">= min" as NotIn them, as admissionPolicyPackageCondition does with
+// semverCompare
+func olderVersions(min string) ([]string, error) {
+	major, minor, ok := strings.Cut(min, ".")
+	if !ok || major != "4" {
+		return nil, fmt.Errorf("%q is not an OpenShift 4 major.minor version", min)
+	}
+	n, err := strconv.Atoi(minor)
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("%q is not an OpenShift 4 major.minor version", min)
+	}
+	versions := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		versions = append(versions, fmt.Sprintf("4.%d", i))
+	}
+	return versions, nil
+}
+
+// withVersions returns selector narrowed to the clusters whose version label
+// is, or with operator NotIn is not, one of versions. With operator Exists or
+// DoesNotExist versions must be nil.
+func withVersions(selector metav1.LabelSelector, operator metav1.LabelSelectorOperator, versions []string) metav1.LabelSelector {
+	selector.MatchExpressions = append(append([]metav1.LabelSelectorRequirement{}, selector.MatchExpressions...),
+		metav1.LabelSelectorRequirement{
+			Key:      versionLabel,
+			Operator: operator,
+			Values:   versions,
+		})
+	return selector
+}
+
+// packageResource is a resource of the package, which is only deployed when
+// the Go template condition is true, or when it is false if negated
+type packageResource struct {
+	object    runtime.RawExtension
+	condition string
+	negated   bool
+}
+
+// livenessProbe restarts the webhooks container if it stops serving HTTP
+func livenessProbe() *corev1.Probe {
+	return &corev1.Probe{
+		ProbeHandler: corev1.ProbeHandler{
+			HTTPGet: &corev1.HTTPGetAction{
+				Path: "/healthz",
+				Port: intstr.FromInt(*healthPort),
+			},
+		},
+		InitialDelaySeconds: 10,
+		PeriodSeconds:       30,
+	}
+}
+
+// readinessProbe takes the webhooks container out of the Service while it
+// cannot serve admission requests, including while it shuts down. Its timeout
+// allows for each of the server's three readiness checks taking its full 2s.
+func readinessProbe() *corev1.Probe {
+	return &corev1.Probe{
+		ProbeHandler: corev1.ProbeHandler{
+			HTTPGet: &corev1.HTTPGetAction{
+				Path: "/readyz",
+				Port: intstr.FromInt(*healthPort),
+			},
+		},
+		InitialDelaySeconds: 5,
+		TimeoutSeconds:      8,
+		PeriodSeconds:       10,
+	}
+}
+
+// configureClientAuth mounts the -clientcaname ConfigMap into the webhooks
+// container and tells the server to require client certificates signed by it
+func configureClientAuth(podSpec *corev1.PodSpec) {
+	if *clientCAName == "" {
+		return
+	}
+	podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+		Name: "client-ca",
+		VolumeSource: corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{
+					Name: *clientCAName,
+				},
+			},
+		},
+	})
+	for i := range podSpec.Containers {
+		container := &podSpec.Containers[i]
+		if container.Name != "webhooks" {
+			continue
+		}
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      "client-ca",
+			MountPath: "/client-ca",
+			ReadOnly:  true,
+		})
+		container.Command = append(container.Command, "-client-ca", "/client-ca/ca-bundle.crt")
+		if *clientAllowedNames != "" {
+			container.Command = append(container.Command, "-client-allowed-names", *clientAllowedNames)
+		}
+	}
+}
+
 func sliceContains(needle string, haystack []string) bool {
 	for _, hay := range haystack {
 		if hay == needle {
@@ -894,6 +1137,8 @@ func main() {
 		templateResources.Add(utils.DefaultLabelSelector(), runtime.RawExtension{Object: createPromethusRoleBinding()})
 		templateResources.Add(utils.DefaultLabelSelector(), runtime.RawExtension{Object: createServiceMonitor()})
 		templateResources.Add(utils.DefaultLabelSelector(), runtime.RawExtension{Object: createCACertConfigMap()})
+		templateResources.Add(utils.DefaultLabelSelector(), runtime.RawExtension{Object: createWebhookConfigMap(*allowlistName)})
+		templateResources.Add(utils.DefaultLabelSelector(), runtime.RawExtension{Object: createWebhookConfigMap(*breakGlassName)})
 		templateResources.Add(utils.DefaultLabelSelector(), runtime.RawExtension{Object: createService()})
 
 		encodedDaemonSet, err := syncset.EncodeAndFixDaemonset(createDaemonSet())
@@ -942,6 +1187,24 @@ func main() {
 				continue
 			}
 
+			// Webhooks which can be expressed in CEL are replaced by a
+			// ValidatingAdmissionPolicy on clusters of -admissionpolicyminversion
+			// or later, except the excluded versions. Clusters without a version
+			// label keep the webhook.
+			if policy, binding, ok := admissionPolicyFor(hook()); ok {
+				versions, err := olderVersions(*policyMinVersion)
+				if err != nil {
+					panic(fmt.Sprintf("Invalid -admissionpolicyminversion: %s", err.Error()))
+				}
+				versions = append(versions, strings.FieldsFunc(*policyExcludes, func(r rune) bool { return r == ',' })...)
+				policySelector := withVersions(withVersions(hook().SyncSetLabelSelector(), metav1.LabelSelectorOpExists, nil), metav1.LabelSelectorOpNotIn, versions)
+				templateResources.Add(withVersions(hook().SyncSetLabelSelector(), metav1.LabelSelectorOpIn, versions), runtime.RawExtension{Raw: syncset.Encode(createValidatingWebhookConfiguration(hook()))})
+				templateResources.Add(withVersions(hook().SyncSetLabelSelector(), metav1.LabelSelectorOpDoesNotExist, nil), runtime.RawExtension{Raw: syncset.Encode(createValidatingWebhookConfiguration(hook()))})
+				templateResources.Add(policySelector, runtime.RawExtension{Raw: syncset.Encode(policy)})
+				templateResources.Add(policySelector, runtime.RawExtension{Raw: syncset.Encode(binding)})
+				continue
+			}
+
 			// Now handle all Validating webhooks
 			templateResources.Add(hook().SyncSetLabelSelector(), runtime.RawExtension{Raw: syncset.Encode(createValidatingWebhookConfiguration(hook()))})
 		}
@@ -1005,10 +1268,12 @@ func main() {
 	if buildPackage {
 		// packageResources contains all resources intended for a package-operator package, with the key
 		// being the associated filename to generate
-		packageResources := make([]runtime.RawExtension, 0)
-		packageResources = append(packageResources, runtime.RawExtension{Object: createPackagedCACertConfigMap(configPhase)})
-		packageResources = append(packageResources, runtime.RawExtension{Object: createPackagedService(deployPhase)})
-		packageResources = append(packageResources, runtime.RawExtension{Object: createPackagedDeployment(int32(*replicas), deployPhase)})
+		packageResources := make([]packageResource, 0)
+		packageResources = append(packageResources, packageResource{object: runtime.RawExtension{Object: createPackagedCACertConfigMap(configPhase)}})
+		packageResources = append(packageResources, packageResource{object: runtime.RawExtension{Object: createPackagedWebhookConfigMap(*allowlistName, configPhase)}})
+		packageResources = append(packageResources, packageResource{object: runtime.RawExtension{Object: createPackagedWebhookConfigMap(*breakGlassName, configPhase)}})
+		packageResources = append(packageResources, packageResource{object: runtime.RawExtension{Object: createPackagedService(deployPhase)}})
+		packageResources = append(packageResources, packageResource{object: runtime.RawExtension{Object: createPackagedDeployment(int32(*replicas), deployPhase)}})
 
 		hookNames := make([]string, 0)
 		for name := range webhooks.Webhooks {
@@ -1049,7 +1314,7 @@ func main() {
 					fmt.Printf("Error encoding packaged webhook: %v\n", err)
 					os.Exit(1)
 				}
-				packageResources = append(packageResources, runtime.RawExtension{Raw: encodedWebhook})
+				packageResources = append(packageResources, packageResource{object: runtime.RawExtension{Raw: encodedWebhook}})
 				continue
 			}
 
@@ -1059,16 +1324,39 @@ func main() {
 				fmt.Printf("Error encoding packaged webhook: %v\n", err)
 				os.Exit(1)
 			}
-			packageResources = append(packageResources, runtime.RawExtension{Raw: encodedWebhook})
+
+			// Webhooks which can be expressed in CEL are replaced by a
+			// ValidatingAdmissionPolicy on the versions which support them
+			if policy, binding, ok := admissionPolicyFor(hook()); ok {
+				policy.Annotations = map[string]string{pkoPhaseAnnotation: webhooksPhase}
+				binding.Annotations = map[string]string{pkoPhaseAnnotation: webhooksPhase}
+				packageResources = append(packageResources,
+					packageResource{object: runtime.RawExtension{Raw: encodedWebhook}, condition: admissionPolicyPackageCondition, negated: true},
+					packageResource{object: runtime.RawExtension{Object: policy}, condition: admissionPolicyPackageCondition},
+					packageResource{object: runtime.RawExtension{Object: binding}, condition: admissionPolicyPackageCondition},
+				)
+				continue
+			}
+			packageResources = append(packageResources, packageResource{object: runtime.RawExtension{Raw: encodedWebhook}})
 		}
 		var rb strings.Builder
 		for _, packageResource := range packageResources {
-			resourceYaml, err := yaml.Marshal(packageResource)
+			resourceYaml, err := yaml.Marshal(packageResource.object)
 			if err != nil {
 				panic(fmt.Sprintf("Failed to marshal resource to string: %s", err.Error()))
 			}
+			switch {
+			case packageResource.condition == "":
+			case packageResource.negated:
+				fmt.Fprintf(&rb, "{{- if not (%s) }}\n", packageResource.condition)
+			default:
+				fmt.Fprintf(&rb, "{{- if %s }}\n", packageResource.condition)
+			}
 			rb.WriteString("---\n")
 			rb.Write(resourceYaml)
+			if packageResource.condition != "" {
+				rb.WriteString("{{- end }}\n")
+			}
 		}
 		fname := filepath.Join(*packageDir, "resources.yaml.gotmpl")
 		err := os.WriteFile(fname, []byte(rb.String()), 0644)
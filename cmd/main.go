@@ -3,13 +3,13 @@ package main
 import (
 	"context"
 	"crypto/tls"
-	"crypto/x509"
 	"errors"
 	"flag"
 	"fmt"
 	"net"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/openshift/operator-custom-metrics/pkg/metrics"
@@ -19,9 +19,19 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime/pkg/manager/signals"
 
 	"github.com/openshift/managed-cluster-validating-webhooks/config"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/allowlist"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/auditlog"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/breakglass"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/certwatcher"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/clientauth"
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/dispatcher"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/explain"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/health"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/helpers"
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/k8sutil"
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/localmetrics"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/recorder"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/tracing"
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks"
 )
 
@@ -32,10 +42,50 @@ var (
 	listenPort    = flag.String("port", "5000", "port to listen on")
 	testHooks     = flag.Bool("testhooks", false, "Test webhook URI uniqueness and quit?")
 
-	useTLS  = flag.Bool("tls", false, "Use TLS? Must specify -tlskey, -tlscert, -cacert")
+	useTLS  = flag.Bool("tls", false, "Use TLS? Must specify -tlskey and -tlscert")
 	tlsKey  = flag.String("tlskey", "", "TLS Key for TLS")
 	tlsCert = flag.String("tlscert", "", "TLS Certificate")
-	caCert  = flag.String("cacert", "", "CA Cert file")
+	// -cacert was only ever loaded into the server's RootCAs, which a server
+	// doesn't use. It is still accepted so that older manifests keep working.
+	_ = flag.String("cacert", "", "Deprecated and ignored. Use -client-ca to verify client certificates")
+
+	clientCA           = flag.String("client-ca", "", "CA bundle to verify client certificates against. When set, every request must present a client certificate signed by it")
+	clientAllowedNames = flag.String("client-allowed-names", "", "Comma-separated subject CNs or DNS SANs of which a client certificate must have one. Requires -client-ca")
+	tlsReloadInterval  = flag.Duration("tls-reload-interval", 30*time.Second, "How often to check -tlscert and -tlskey for a rotated certificate")
+
+	enforcementModes      = flag.String("enforcement", "", "Comma-separated webhook=mode pairs, where mode is one of enforce, warn or audit. Overrides -enforcement-config")
+	enforcementConfigFile = flag.String("enforcement-config", "", "YAML file mapping webhook names to an enforcement mode of enforce, warn or audit")
+
+	concurrencyConfigFile = flag.String("concurrency-config", "", "YAML file of in-flight and queue limits for all webhooks together and for each webhook")
+
+	timeoutFallback = flag.String("timeout-fallback", string(dispatcher.FailurePolicyFallback), "Decision to send when a webhook does not reach one before its deadline, or panics: failure-policy, allow or deny")
+
+	supportURL = flag.String("support-url", helpers.DefaultSupportURL, "Where denial messages send customers with questions, for the product the webhooks are deployed for")
+
+	auditLogPath = flag.String("auditlog", auditlog.StdoutSink, "Where to write the structured admission decision log: '-' for stdout, a file path, or empty to disable")
+
+	recordPath          = flag.String("record", "", "File to append AdmissionReview request and response pairs to, with sensitive fields redacted, for the replay command. Empty disables recording")
+	recordSampleRatio   = flag.Float64("record-sample-ratio", 1, "Fraction of the selected requests to record")
+	recordWebhooks      = flag.String("record-webhooks", "", "Comma-separated webhooks to record requests for. Empty records every webhook")
+	recordOutcomes      = flag.String("record-outcomes", "", "Comma-separated outcomes to record, e.g. denied,errored. Empty records every outcome")
+	recordMaxRecordings = flag.Int("record-max", 10000, "Stop recording after this many recordings. Zero is unlimited")
+
+	allowlistConfigFile     = flag.String("allowlist-config", "", "YAML file of additional principals each webhook should allow. Reloaded when it changes")
+	allowlistReloadInterval = flag.Duration("allowlist-reload-interval", 10*time.Second, "How often to check -allowlist-config for changes")
+
+	breakGlassConfigFile     = flag.String("breakglass-config", "", "YAML file of time-bound overrides which allow requests webhooks deny. Reloaded when it changes")
+	breakGlassReloadInterval = flag.Duration("breakglass-reload-interval", 10*time.Second, "How often to check -breakglass-config for changes")
+
+	tracingExporter    = flag.String("tracing-exporter", "none", "Where to send OpenTelemetry traces of admission requests: none, otlp or file")
+	tracingEndpoint    = flag.String("tracing-endpoint", "", "OTLP/HTTP collector URL for -tracing-exporter otlp. Defaults to OTEL_EXPORTER_OTLP_ENDPOINT")
+	tracingFile        = flag.String("tracing-file", "traces.json", "File to write spans to as JSON for -tracing-exporter file")
+	tracingSampleRatio = flag.Float64("tracing-sample-ratio", 1, "Fraction of admission requests to trace when the API server did not send a sampled traceparent")
+
+	explainAddress   = flag.String("explain-bind-address", "", "The address the /explain/<webhook> endpoint binds to, over plain HTTP. Empty disables it. Must be a loopback address unless -explain-token-file is set")
+	explainTokenFile = flag.String("explain-token-file", "", "File holding the bearer token /explain/<webhook> requests must present")
+
+	healthAddress = flag.String("health-bind-address", ":8081", "The address the /healthz and /readyz endpoints bind to. They are served over plain HTTP so that the kubelet does not need a client certificate")
+	shutdownDelay = flag.Duration("shutdown-delay", 5*time.Second, "How long to report not ready at shutdown before draining connections")
 
 	metricsPath = "/metrics"
 	metricsPort = "8080"
@@ -48,22 +98,65 @@ func main() {
 	klog.SetOutput(os.Stdout)
 
 	logf.SetLogger(klogr.New())
+	helpers.SetSupportURL(*supportURL)
 
 	if !*testHooks {
 		log.Info("HTTP server running at", "listen", net.JoinHostPort(*listenAddress, *listenPort))
 	}
-	dispatcher := dispatcher.NewDispatcher(webhooks.Webhooks)
-	seen := make(map[string]bool)
-	for name, hook := range webhooks.Webhooks {
-		realHook := hook()
-		if seen[realHook.GetURI()] {
-			panic(fmt.Errorf("Duplicate webhook trying to listen on %s", realHook.GetURI()))
+	var dispatcherOpts []dispatcher.Option
+	if *auditLogPath != "" && !*testHooks {
+		auditLogger, err := auditlog.NewFromPath(*auditLogPath)
+		if err != nil {
+			log.Error(err, "Couldn't open audit log", "path", *auditLogPath)
+			os.Exit(1)
+		}
+		defer auditLogger.Close()
+		dispatcherOpts = append(dispatcherOpts, dispatcher.WithAuditLogger(auditLogger))
+	}
+	if *recordPath != "" && !*testHooks {
+		rec, err := recorder.NewFromPath(*recordPath, recorder.Options{
+			SampleRatio:   *recordSampleRatio,
+			Webhooks:      splitList(*recordWebhooks),
+			Outcomes:      splitList(*recordOutcomes),
+			MaxRecordings: *recordMaxRecordings,
+		})
+		if err != nil {
+			log.Error(err, "Couldn't open recording file", "path", *recordPath)
+			os.Exit(1)
+		}
+		defer rec.Close()
+		dispatcherOpts = append(dispatcherOpts, dispatcher.WithRecorder(rec))
+	}
+	modes, err := loadEnforcementModes()
+	if err != nil {
+		log.Error(err, "Invalid enforcement modes")
+		os.Exit(1)
+	}
+	dispatcherOpts = append(dispatcherOpts, dispatcher.WithEnforcementModes(modes))
+	fallback, err := dispatcher.ParseTimeoutFallback(*timeoutFallback)
+	if err != nil {
+		log.Error(err, "Invalid timeout fallback")
+		os.Exit(1)
+	}
+	dispatcherOpts = append(dispatcherOpts, dispatcher.WithTimeoutFallback(fallback))
+	if *concurrencyConfigFile != "" {
+		concurrency, err := dispatcher.LoadConcurrencyConfig(*concurrencyConfigFile)
+		if err == nil {
+			err = concurrency.Validate(knownWebhooks())
+		}
+		if err != nil {
+			log.Error(err, "Invalid concurrency limits", "path", *concurrencyConfigFile)
+			os.Exit(1)
 		}
-		seen[name] = true
+		dispatcherOpts = append(dispatcherOpts, dispatcher.WithConcurrencyConfig(concurrency))
+	}
+	// NewDispatcher panics if two webhooks share a URI
+	dispatcher := dispatcher.NewDispatcher(webhooks.Webhooks, dispatcherOpts...)
+	for _, uri := range dispatcher.URIs() {
 		if !*testHooks {
-			log.Info("Listening", "webhookName", name, "URI", realHook.GetURI())
+			log.Info("Listening", "URI", uri)
 		}
-		http.HandleFunc(realHook.GetURI(), dispatcher.HandleRequest)
+		http.HandleFunc(uri, dispatcher.HandleRequest)
 	}
 	if *testHooks {
 		os.Exit(0)
@@ -79,6 +172,24 @@ func main() {
 
 	ctx := ctrl.SetupSignalHandler()
 
+	if *allowlistConfigFile != "" {
+		watcher := allowlist.NewWatcher(*allowlistConfigFile, *allowlistReloadInterval, dispatcher.AllowlistWebhooks())
+		if err := watcher.Load(); err != nil {
+			log.Error(err, "Couldn't load allowlist configuration", "path", *allowlistConfigFile)
+			os.Exit(1)
+		}
+		go watcher.Watch(ctx)
+	}
+
+	if *breakGlassConfigFile != "" {
+		watcher := breakglass.NewWatcher(*breakGlassConfigFile, *breakGlassReloadInterval)
+		if err := watcher.Load(); err != nil {
+			log.Error(err, "Couldn't load break-glass configuration", "path", *breakGlassConfigFile)
+			os.Exit(1)
+		}
+		go watcher.Watch(ctx)
+	}
+
 	// get the namespace we're running in to confirm if running in a cluster
 	if _, err := k8sutil.GetOperatorNamespace(); err != nil {
 		if errors.Is(err, k8sutil.ErrRunLocal) {
@@ -94,6 +205,30 @@ func main() {
 		}
 	}
 
+	exporter, err := tracing.ParseExporter(*tracingExporter)
+	if err != nil {
+		log.Error(err, "Invalid tracing exporter")
+		os.Exit(1)
+	}
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Config{
+		Exporter:    exporter,
+		Endpoint:    *tracingEndpoint,
+		Path:        *tracingFile,
+		SampleRatio: *tracingSampleRatio,
+	})
+	if err != nil {
+		log.Error(err, "Couldn't set up tracing")
+		os.Exit(1)
+	}
+
+	// Every webhook must answer a canned request, and those which call the API
+	// server must reach it, before the server is first ready. Both are retried
+	// on each probe until they pass. They are not checked after that, as the
+	// webhooks' failure policies already cover a brief outage.
+	healthChecks := health.New()
+	healthChecks.AddStartupCheck("self-test", dispatcher.SelfTest)
+	healthChecks.AddStartupCheck("cluster-access", dispatcher.CheckClusterAccess)
+
 	server := &http.Server{
 		Addr:              net.JoinHostPort(*listenAddress, *listenPort),
 		ReadHeaderTimeout: 5 * time.Second,
@@ -101,38 +236,83 @@ func main() {
 		WriteTimeout:      10 * time.Second,
 	}
 	if *useTLS {
-		cafile, err := os.ReadFile(*caCert)
+		certWatcher, err := certwatcher.New(*tlsCert, *tlsKey, *tlsReloadInterval)
 		if err != nil {
-			log.Error(err, "Couldn't read CA cert file")
+			log.Error(err, "Couldn't load serving certificate")
 			os.Exit(1)
 		}
-		certpool := x509.NewCertPool()
-		certpool.AppendCertsFromPEM(cafile)
+		go certWatcher.Watch(ctx)
 
 		server.TLSConfig = &tls.Config{
-			RootCAs: certpool,
+			GetCertificate: certWatcher.GetCertificate,
 		}
+		healthChecks.AddReadinessCheck("tls", func(context.Context) error {
+			_, err := certWatcher.GetCertificate(nil)
+			return err
+		})
+		if *clientCA != "" {
+			if err := clientauth.Configure(server.TLSConfig, *clientCA, splitList(*clientAllowedNames)); err != nil {
+				log.Error(err, "Couldn't configure client certificate verification", "path", *clientCA)
+				os.Exit(1)
+			}
+			log.Info("Requiring client certificates", "clientCA", *clientCA, "allowedNames", *clientAllowedNames)
+		}
+	} else if *clientCA != "" {
+		log.Error(errors.New("-client-ca requires -tls"), "Invalid flags")
+		os.Exit(1)
 	}
 
 	// Start server in background
-	errCh := make(chan error, 1)
+	errCh := make(chan error, 3)
 	go func() {
 		if *useTLS {
-			errCh <- server.ListenAndServeTLS(*tlsCert, *tlsKey)
+			// The certificate is served by TLSConfig.GetCertificate
+			errCh <- server.ListenAndServeTLS("", "")
 		} else {
 			errCh <- server.ListenAndServe()
 		}
 	}()
 
+	healthServer := &http.Server{
+		Addr:              *healthAddress,
+		Handler:           healthChecks.Handler(),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go func() {
+		if err := healthServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			errCh <- err
+		}
+	}()
+
+	var explainServer *http.Server
+	if *explainAddress != "" {
+		explainServer, err = newExplainServer(dispatcher)
+		if err != nil {
+			log.Error(err, "Couldn't set up the explain endpoint")
+			os.Exit(1)
+		}
+		log.Info("Explaining decisions", "address", *explainAddress, "tokenRequired", *explainTokenFile != "")
+		go func() {
+			if err := explainServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				errCh <- err
+			}
+		}()
+	}
+
 	// Wait for signal or server error
 	select {
 	case err := <-errCh:
 		log.Error(err, "Server failed")
 		os.Exit(1)
 	case <-ctx.Done():
-		log.Info("Shutdown signal received, draining connections")
+		log.Info("Shutdown signal received, reporting not ready before draining connections", "delay", *shutdownDelay)
 	}
 
+	// Give the kubelet time to see the server is not ready and remove it from
+	// the Service's endpoints while it still answers admission requests
+	healthChecks.SetShuttingDown()
+	time.Sleep(*shutdownDelay)
+
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
@@ -140,5 +320,95 @@ func main() {
 		log.Error(err, "Server shutdown error")
 		os.Exit(1)
 	}
+	if err := healthServer.Shutdown(shutdownCtx); err != nil {
+		log.Error(err, "Health server shutdown error")
+	}
+	if explainServer != nil {
+		if err := explainServer.Shutdown(shutdownCtx); err != nil {
+			log.Error(err, "Explain server shutdown error")
+		}
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		log.Error(err, "Couldn't flush traces")
+	}
 	log.Info("Server stopped gracefully")
 }
+
+// newExplainServer returns the server for the /explain/<webhook> endpoint on
+// -explain-bind-address, which requires the bearer token in
+// -explain-token-file if it is set
+func newExplainServer(d *dispatcher.Dispatcher) (*http.Server, error) {
+	if err := explain.CheckBindAddress(*explainAddress, *explainTokenFile != ""); err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	var handler http.Handler = http.HandlerFunc(d.Explain)
+	if *explainTokenFile != "" {
+		token, err := explain.LoadToken(*explainTokenFile)
+		if err != nil {
+			return nil, err
+		}
+		handler = explain.RequireToken(token, handler)
+	}
+	mux.Handle(explain.PathPrefix, handler)
+	return &http.Server{
+		Addr:              *explainAddress,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       10 * time.Second,
+		WriteTimeout:      10 * time.Second,
+	}, nil
+}
+
+// loadEnforcementModes merges the -enforcement-config file with the
+// -enforcement flag, the latter taking precedence, and validates the result
+// against the registered webhooks.
+func loadEnforcementModes() (dispatcher.EnforcementModes, error) {
+	modes := dispatcher.EnforcementModes{}
+	if *enforcementConfigFile != "" {
+		fileModes, err := dispatcher.LoadEnforcementModes(*enforcementConfigFile)
+		if err != nil {
+			return nil, err
+		}
+		for name, mode := range fileModes {
+			modes[name] = mode
+		}
+	}
+	flagModes, err := dispatcher.ParseEnforcementModes(*enforcementModes)
+	if err != nil {
+		return nil, err
+	}
+	for name, mode := range flagModes {
+		modes[name] = mode
+	}
+
+	if err := modes.Validate(knownWebhooks()); err != nil {
+		return nil, err
+	}
+	for name, mode := range modes {
+		if mode != dispatcher.EnforceMode {
+			log.Info("Webhook denials will not be enforced", "webhookName", name, "mode", mode)
+		}
+	}
+	return modes, nil
+}
+
+// knownWebhooks returns the names of the registered webhooks
+func knownWebhooks() []string {
+	known := make([]string, 0, len(webhooks.Webhooks))
+	for name := range webhooks.Webhooks {
+		known = append(known, name)
+	}
+	return known
+}
+
+// splitList splits a comma-separated flag value, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
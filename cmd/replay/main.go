@@ -0,0 +1,94 @@
+// Command replay feeds AdmissionReviews recorded by the webhook server's
+// -record flag back through the webhooks registered in this build and reports
+// each decision which has changed. It exits non-zero if any has.
+//
+//	go run ./cmd/replay -recording recordings.json
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/go-logr/logr"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/klog/v2/klogr"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/recorder"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks"
+)
+
+var (
+	recordingPath = flag.String("recording", "-", "File written by the webhook server's -record flag, or '-' for stdin")
+	verbose       = flag.Bool("v", false, "Also report decisions which have not changed, and log what the webhooks log")
+)
+
+func main() {
+	flag.Parse()
+	if *verbose {
+		logf.SetLogger(klogr.New())
+	} else {
+		logf.SetLogger(logr.Discard())
+	}
+
+	var in io.Reader = os.Stdin
+	if *recordingPath != "-" {
+		f, err := os.Open(*recordingPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		defer f.Close()
+		in = f
+	}
+	recordings, err := recorder.Read(in)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "couldn't read recordings: %v\n", err)
+		os.Exit(2)
+	}
+
+	var changed, failed int
+	for _, result := range recorder.Replay(context.Background(), recordings, webhooks.Webhooks) {
+		switch {
+		case result.Err != nil:
+			failed++
+			fmt.Printf("ERROR     %s: %v\n", describe(result.Recording), result.Err)
+		case result.Changed():
+			changed++
+			fmt.Printf("CHANGED   %s: %s -> %s\n", describe(result.Recording), decision(result.Recording.Response), decision(result.Replayed))
+		case *verbose:
+			fmt.Printf("UNCHANGED %s: %s\n", describe(result.Recording), decision(result.Replayed))
+		}
+	}
+	fmt.Printf("%d replayed, %d changed, %d could not be replayed\n", len(recordings), changed, failed)
+	if changed > 0 || failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// describe identifies a recorded request
+func describe(r recorder.Recording) string {
+	name := r.Request.Name
+	if r.Request.Namespace != "" {
+		name = r.Request.Namespace + "/" + name
+	}
+	return fmt.Sprintf("%s %s %s %s %s by %s (uid %s)", r.Time.Format("2006-01-02T15:04:05Z"), r.Webhook, r.Request.Operation, r.Request.Kind.Kind, name, r.Request.UserInfo.Username, r.Request.UID)
+}
+
+// decision summarises a response
+func decision(resp admissionv1.AdmissionResponse) string {
+	verdict := "denied"
+	if resp.Allowed {
+		verdict = "allowed"
+		if len(resp.Patch) > 0 {
+			verdict = "allowed with patch"
+		}
+	}
+	if resp.Result == nil {
+		return verdict
+	}
+	return fmt.Sprintf("%s (%d %q)", verdict, resp.Result.Code, resp.Result.Message)
+}
@@ -8,7 +8,9 @@ import (
 	"fmt"
 	"os"
 	"sort"
+	"strings"
 
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/helpers"
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks"
 	admissionregv1 "k8s.io/api/admissionregistration/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -16,6 +18,7 @@ import (
 
 var (
 	hideRules = flag.Bool("hideRules", false, "Hide the Admission Rules?")
+	denials   = flag.Bool("denials", false, "Write the denial message catalog as Markdown instead")
 )
 
 type docuhook struct {
@@ -60,7 +63,27 @@ func WriteDocs() {
 
 }
 
+// WriteDenials writes out the message catalog, with an anchor for each code
+// to match its documentation link.
+func WriteDenials() {
+	var b strings.Builder
+	b.WriteString("# Denials\n\n")
+	b.WriteString("Every denial has a stable code, which is at the start of its message and is the `reason` of the status returned to the client. ")
+	b.WriteString("This file is generated from [pkg/helpers/messages.go](../pkg/helpers/messages.go) by `make DOCFLAGS=-denials docs`.\n")
+	for _, m := range helpers.Catalog() {
+		fmt.Fprintf(&b, "\n## %s\n\n%s\n\n```\n%s\n```\n", m.Code, m.Doc, m.Template)
+	}
+	if _, err := os.Stdout.WriteString(b.String()); err != nil {
+		fmt.Printf("Error Writing: %s\n", err.Error())
+		os.Exit(1)
+	}
+}
+
 func main() {
 	flag.Parse()
+	if *denials {
+		WriteDenials()
+		return
+	}
 	WriteDocs()
 }
@@ -0,0 +1,202 @@
+// Package allowlist holds principals which are exempted from webhooks' checks
+// in addition to those hardcoded by each webhook. The configuration is read
+// from a file, normally mounted from a ConfigMap, and can be swapped at
+// runtime without restarting the server.
+package allowlist
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/ghodss/yaml"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/configwatch"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/localmetrics"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+const (
+	// Version is the only configuration version understood by this server
+	Version = "v1"
+	// serviceAccountPrefix starts the username of every serviceaccount
+	serviceAccountPrefix = "system:serviceaccount:"
+)
+
+var (
+	log = logf.Log.WithName("allowlist")
+
+	// current is the last good configuration, nil until one has been loaded
+	current atomic.Pointer[Config]
+)
+
+// Principals are the users, groups and serviceaccounts a webhook should allow
+type Principals struct {
+	// Users are exact usernames
+	Users []string `json:"users,omitempty"`
+	// Groups are exact group names
+	Groups []string `json:"groups,omitempty"`
+	// ServiceAccounts are regular expressions matched against the whole of a
+	// serviceaccount's username, e.g. ^system:serviceaccount:openshift-foo:.*
+	// They must start with system:serviceaccount: and never match other users.
+	ServiceAccounts []string `json:"serviceAccounts,omitempty"`
+
+	principals      utils.Principals
+	serviceAccounts utils.Principals
+}
+
+// match returns which of the principals matched userInfo
+func (p *Principals) match(userInfo authenticationv1.UserInfo) (utils.PrincipalMatch, bool) {
+	if match, ok := p.principals.Match(userInfo); ok {
+		return match, true
+	}
+	if !strings.HasPrefix(userInfo.Username, serviceAccountPrefix) {
+		return utils.PrincipalMatch{}, false
+	}
+	return p.serviceAccounts.Match(userInfo)
+}
+
+// Config is the versioned allowlist configuration
+type Config struct {
+	Version string `json:"version"`
+	// Webhooks maps a webhook's name to the principals it should allow
+	Webhooks map[string]*Principals `json:"webhooks,omitempty"`
+}
+
+// Parse decodes and validates a YAML or JSON configuration. Unknown fields
+// are rejected so that a misspelt field cannot silently grant nothing.
+func Parse(b []byte) (*Config, error) {
+	c := &Config{}
+	disallowUnknownFields := func(d *json.Decoder) *json.Decoder {
+		d.DisallowUnknownFields()
+		return d
+	}
+	if err := yaml.Unmarshal(b, c, disallowUnknownFields); err != nil {
+		return nil, err
+	}
+	if c.Version != Version {
+		return nil, fmt.Errorf("unsupported allowlist version %q, expected %q", c.Version, Version)
+	}
+	for name, p := range c.Webhooks {
+		if p == nil {
+			return nil, fmt.Errorf("webhook %s has no principals", name)
+		}
+		p.principals = utils.Principals{Users: p.Users, Groups: p.Groups}
+		for _, sa := range p.ServiceAccounts {
+			if !strings.HasPrefix(strings.TrimPrefix(sa, "^"), serviceAccountPrefix) {
+				return nil, fmt.Errorf("serviceaccount pattern %q for webhook %s must start with %s", sa, name, serviceAccountPrefix)
+			}
+			// Anchored so that the pattern must match the whole username
+			re, err := regexp.Compile("^(?:" + sa + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("invalid serviceaccount pattern %q for webhook %s: %w", sa, name, err)
+			}
+			p.serviceAccounts.UserPatterns = append(p.serviceAccounts.UserPatterns, re)
+		}
+	}
+	return c, nil
+}
+
+// Validate returns an error if any webhook name is not one of known, the
+// webhooks which consult the allowlist, so that a typo or a webhook which
+// ignores the allowlist cannot grant nothing or grant to the wrong webhook
+func (c *Config) Validate(known []string) error {
+	for name := range c.Webhooks {
+		if !slices.Contains(known, name) {
+			return fmt.Errorf("allowlist set for unknown webhook %s", name)
+		}
+	}
+	return nil
+}
+
+// Match returns which of the principals configured for the named webhook
+// matched userInfo
+func (c *Config) Match(hookName string, userInfo authenticationv1.UserInfo) (utils.PrincipalMatch, bool) {
+	if c == nil {
+		return utils.PrincipalMatch{}, false
+	}
+	p, ok := c.Webhooks[hookName]
+	if !ok {
+		return utils.PrincipalMatch{}, false
+	}
+	return p.match(userInfo)
+}
+
+// Allows returns true if userInfo is one of the principals configured for
+// the named webhook
+func (c *Config) Allows(hookName string, userInfo authenticationv1.UserInfo) bool {
+	_, ok := c.Match(hookName, userInfo)
+	return ok
+}
+
+// Current returns the active configuration, which is nil if none has been loaded
+func Current() *Config {
+	return current.Load()
+}
+
+// Set atomically replaces the active configuration
+func Set(c *Config) {
+	current.Store(c)
+}
+
+// Allowed returns true if the active configuration allows userInfo for the
+// named webhook. Webhooks call this alongside their hardcoded exemptions, and
+// implement webhooks.AllowlistWebhook so that the allowlist may name them.
+func Allowed(hookName string, userInfo authenticationv1.UserInfo) bool {
+	return Current().Allows(hookName, userInfo)
+}
+
+// Watcher loads the configuration from a file and reloads it when the file's
+// content changes
+type Watcher struct {
+	watcher *configwatch.Watcher[Config]
+}
+
+// NewWatcher returns a Watcher for the file at path, which may only allow
+// principals for the known webhooks, those which consult the allowlist. A missing file is an empty configuration
+// since the ConfigMap is optional.
+func NewWatcher(path string, interval time.Duration, known []string) *Watcher {
+	parse := func(contents [][]byte) (*Config, error) {
+		if len(contents[0]) == 0 {
+			return &Config{Version: Version}, nil
+		}
+		c, err := Parse(contents[0])
+		if err == nil {
+			err = c.Validate(known)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return c, nil
+	}
+	return &Watcher{
+		watcher: configwatch.New("allowlist configuration", []string{path}, interval, parse).
+			AllowMissing().
+			OnLoad(func(c *Config, err error) {
+				localmetrics.IncrementAllowlistReload(err == nil)
+				if err != nil {
+					return
+				}
+				Set(c)
+				log.Info("Loaded allowlist configuration", "path", path, "webhooks", len(c.Webhooks))
+			}),
+	}
+}
+
+// Load reads and activates the configuration file if it has changed. An
+// invalid configuration is rejected, leaving the last good one active.
+func (w *Watcher) Load() error {
+	return w.watcher.Load()
+}
+
+// Watch reloads the configuration every interval until ctx is done
+func (w *Watcher) Watch(ctx context.Context) {
+	w.watcher.Watch(ctx)
+}
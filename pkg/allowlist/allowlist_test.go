@@ -0,0 +1,148 @@
+package allowlist
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+)
+
+const validConfig = `
+version: v1
+webhooks:
+  clusterrole-validation:
+    users:
+    - alice
+    groups:
+    - breakglass
+    serviceAccounts:
+    - ^system:serviceaccount:openshift-foo:.*
+  scc-validation:
+    serviceAccounts:
+    - system:serviceaccount:openshift-foo:bar
+`
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr bool
+	}{
+		{name: "valid", config: validConfig},
+		{name: "empty webhooks", config: "version: v1"},
+		{name: "missing version", config: "webhooks: {}", wantErr: true},
+		{name: "unknown version", config: "version: v2", wantErr: true},
+		{name: "null principals", config: "version: v1\nwebhooks:\n  foo:\n", wantErr: true},
+		{name: "bad regex", config: "version: v1\nwebhooks:\n  foo:\n    serviceAccounts: ['^system:serviceaccount:(']", wantErr: true},
+		{name: "not a serviceaccount pattern", config: "version: v1\nwebhooks:\n  foo:\n    serviceAccounts: ['.*']", wantErr: true},
+		{name: "unknown field", config: "version: v1\nwebhooks:\n  foo:\n    user: [alice]", wantErr: true},
+		{name: "not yaml", config: "version: [", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Parse([]byte(test.config))
+			if (err != nil) != test.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	c, err := Parse([]byte(validConfig))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if err := c.Validate([]string{"clusterrole-validation", "scc-validation"}); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+	if err := c.Validate([]string{"scc-validation"}); err == nil {
+		t.Error("expected an error for an unknown webhook")
+	}
+}
+
+func TestAllows(t *testing.T) {
+	c, err := Parse([]byte(validConfig))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	tests := []struct {
+		name     string
+		hookName string
+		userInfo authenticationv1.UserInfo
+		want     bool
+	}{
+		{name: "user", hookName: "clusterrole-validation", userInfo: authenticationv1.UserInfo{Username: "alice"}, want: true},
+		{name: "group", hookName: "clusterrole-validation", userInfo: authenticationv1.UserInfo{Username: "bob", Groups: []string{"breakglass"}}, want: true},
+		{name: "serviceaccount", hookName: "clusterrole-validation", userInfo: authenticationv1.UserInfo{Username: "system:serviceaccount:openshift-foo:bar"}, want: true},
+		{name: "other serviceaccount", hookName: "clusterrole-validation", userInfo: authenticationv1.UserInfo{Username: "system:serviceaccount:openshift-bar:bar"}, want: false},
+		{name: "user containing a serviceaccount", hookName: "clusterrole-validation", userInfo: authenticationv1.UserInfo{Username: "evil-system:serviceaccount:openshift-foo:bar"}, want: false},
+		{name: "serviceaccount in a namespace with the same prefix", hookName: "scc-validation", userInfo: authenticationv1.UserInfo{Username: "system:serviceaccount:openshift-foo-evil:bar"}, want: false},
+		{name: "whole serviceaccount", hookName: "scc-validation", userInfo: authenticationv1.UserInfo{Username: "system:serviceaccount:openshift-foo:bar"}, want: true},
+		{name: "unlisted user", hookName: "clusterrole-validation", userInfo: authenticationv1.UserInfo{Username: "bob"}, want: false},
+		{name: "other webhook", hookName: "scc-validation", userInfo: authenticationv1.UserInfo{Username: "alice"}, want: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := c.Allows(test.hookName, test.userInfo); got != test.want {
+				t.Errorf("Allows() = %v, want %v", got, test.want)
+			}
+		})
+	}
+
+	var nilConfig *Config
+	if nilConfig.Allows("clusterrole-validation", authenticationv1.UserInfo{Username: "alice"}) {
+		t.Error("nil Config should not allow anyone")
+	}
+}
+
+func TestWatcherKeepsLastGoodConfig(t *testing.T) {
+	defer Set(nil)
+	path := filepath.Join(t.TempDir(), "allowlist.yaml")
+	alice := authenticationv1.UserInfo{Username: "alice"}
+	w := NewWatcher(path, time.Second, []string{"clusterrole-validation", "scc-validation"})
+
+	// A missing file is an empty configuration
+	if err := w.Load(); err != nil {
+		t.Fatalf("Load() of missing file error = %v", err)
+	}
+	if Current() == nil || Allowed("clusterrole-validation", alice) {
+		t.Fatal("expected an empty configuration to be active")
+	}
+
+	if err := os.WriteFile(path, []byte(validConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !Allowed("clusterrole-validation", alice) {
+		t.Fatal("expected alice to be allowed after loading the configuration")
+	}
+
+	if err := os.WriteFile(path, []byte("version: v2"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Load(); err == nil {
+		t.Fatal("expected an error loading an invalid configuration")
+	}
+	if !Allowed("clusterrole-validation", alice) {
+		t.Fatal("expected the last good configuration to remain active")
+	}
+	// The same invalid content is only reported once
+	if err := w.Load(); err != nil {
+		t.Fatalf("Load() of unchanged content error = %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte("version: v1"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if Allowed("clusterrole-validation", alice) {
+		t.Fatal("expected alice to no longer be allowed")
+	}
+}
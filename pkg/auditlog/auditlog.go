@@ -0,0 +1,124 @@
+package auditlog
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+// StdoutSink is the sink path which sends the audit log to stdout
+const StdoutSink = "-"
+
+var log = logf.Log.WithName("auditlog")
+
+// Record is a single admission decision made by a webhook
+type Record struct {
+	Time        time.Time `json:"time"`
+	UID         types.UID `json:"uid"`
+	Webhook     string    `json:"webhook"`
+	Group       string    `json:"group"`
+	Version     string    `json:"version"`
+	Kind        string    `json:"kind"`
+	Resource    string    `json:"resource"`
+	SubResource string    `json:"subResource,omitempty"`
+	Namespace   string    `json:"namespace,omitempty"`
+	Name        string    `json:"name,omitempty"`
+	Operation   string    `json:"operation"`
+	Username    string    `json:"username"`
+	Groups      []string  `json:"groups,omitempty"`
+	// Scopes are the scopes of the user's OAuth token, if it is scoped
+	Scopes     []string `json:"scopes,omitempty"`
+	Decision   string   `json:"decision"`
+	Code       int32    `json:"code"`
+	Reason     string   `json:"reason,omitempty"`
+	DurationMs float64  `json:"durationMs"`
+	// EnforcementMode is set when a denial was not enforced, in which case the
+	// request was allowed despite Decision
+	EnforcementMode string `json:"enforcementMode,omitempty"`
+	// BreakGlassJustification is set when a denial was allowed through by a
+	// break-glass override, to the override's justification
+	BreakGlassJustification string `json:"breakGlassJustification,omitempty"`
+}
+
+// NewRecord builds a Record from the request a webhook handled and the
+// response it sent back.
+func NewRecord(webhook string, request admissionctl.Request, resp admissionctl.Response, decision string, duration time.Duration) Record {
+	record := Record{
+		Time:        time.Now().UTC(),
+		UID:         request.UID,
+		Webhook:     webhook,
+		Group:       request.Kind.Group,
+		Version:     request.Kind.Version,
+		Kind:        request.Kind.Kind,
+		Resource:    request.Resource.Resource,
+		SubResource: request.SubResource,
+		Namespace:   request.Namespace,
+		Name:        request.Name,
+		Operation:   string(request.Operation),
+		Username:    request.UserInfo.Username,
+		Groups:      request.UserInfo.Groups,
+		Scopes:      utils.Scopes(request.UserInfo),
+		Decision:    decision,
+		DurationMs:  float64(duration.Microseconds()) / 1000,
+	}
+	if resp.Result != nil {
+		record.Code = resp.Result.Code
+		record.Reason = resp.Result.Message
+	}
+	return record
+}
+
+// Logger writes Records as newline-delimited JSON. It is safe for concurrent use.
+type Logger struct {
+	mu     sync.Mutex
+	enc    *json.Encoder
+	closer io.Closer
+}
+
+// New returns a Logger which writes to w
+func New(w io.Writer) *Logger {
+	return &Logger{
+		enc: json.NewEncoder(w),
+	}
+}
+
+// NewFromPath returns a Logger which writes to stdout when path is StdoutSink,
+// otherwise it appends to the file at path.
+func NewFromPath(path string) (*Logger, error) {
+	if path == StdoutSink {
+		return New(os.Stdout), nil
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	l := New(f)
+	l.closer = f
+	return l, nil
+}
+
+// Log writes a single Record. Failures are logged rather than returned since
+// they must never affect the admission decision.
+func (l *Logger) Log(record Record) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.enc.Encode(record); err != nil {
+		log.Error(err, "Failed to write audit record", "uid", record.UID, "webhook", record.Webhook)
+	}
+}
+
+// Close closes the underlying file, if there is one
+func (l *Logger) Close() error {
+	if l.closer == nil {
+		return nil
+	}
+	return l.closer.Close()
+}
@@ -0,0 +1,112 @@
+package auditlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+func testRequest() admissionctl.Request {
+	return admissionctl.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			UID:         "test-uid",
+			Kind:        metav1.GroupVersionKind{Group: "", Version: "v1", Kind: "Pod"},
+			Resource:    metav1.GroupVersionResource{Group: "", Version: "v1", Resource: "pods"},
+			SubResource: "exec",
+			Namespace:   "openshift-ingress",
+			Name:        "router",
+			Operation:   admissionv1.Create,
+			UserInfo: authenticationv1.UserInfo{
+				Username: "my-user",
+				Groups:   []string{"system:authenticated"},
+			},
+		},
+	}
+}
+
+func TestNewRecord(t *testing.T) {
+	resp := admissionctl.Denied("not allowed")
+	record := NewRecord("pod-validation", testRequest(), resp, "denied", 1500*time.Microsecond)
+
+	if record.UID != "test-uid" || record.Webhook != "pod-validation" {
+		t.Errorf("unexpected identifiers in record: %+v", record)
+	}
+	if record.Kind != "Pod" || record.Version != "v1" || record.Resource != "pods" || record.SubResource != "exec" {
+		t.Errorf("unexpected GVK/resource in record: %+v", record)
+	}
+	if record.Namespace != "openshift-ingress" || record.Name != "router" || record.Operation != "CREATE" {
+		t.Errorf("unexpected object in record: %+v", record)
+	}
+	if record.Username != "my-user" || len(record.Groups) != 1 {
+		t.Errorf("unexpected user in record: %+v", record)
+	}
+	if record.Decision != "denied" || record.Code != http.StatusForbidden || record.Reason != "not allowed" {
+		t.Errorf("unexpected decision in record: %+v", record)
+	}
+	if record.DurationMs != 1.5 {
+		t.Errorf("expected duration of 1.5ms, got %v", record.DurationMs)
+	}
+	if record.Scopes != nil {
+		t.Errorf("expected no scopes, got %v", record.Scopes)
+	}
+}
+
+func TestNewRecordScopes(t *testing.T) {
+	request := testRequest()
+	request.UserInfo.Extra = map[string]authenticationv1.ExtraValue{
+		utils.ScopesExtraKey: {"user:full"},
+	}
+	record := NewRecord("pod-validation", request, admissionctl.Allowed(""), "allowed", time.Millisecond)
+	if len(record.Scopes) != 1 || record.Scopes[0] != "user:full" {
+		t.Errorf("expected the token's scopes, got %v", record.Scopes)
+	}
+}
+
+func TestLoggerWritesOneLinePerRecord(t *testing.T) {
+	buf := new(bytes.Buffer)
+	l := New(buf)
+	l.Log(NewRecord("a", testRequest(), admissionctl.Allowed(""), "allowed", 0))
+	l.Log(NewRecord("b", testRequest(), admissionctl.Allowed(""), "allowed", 0))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d: %s", len(lines), buf.String())
+	}
+	for _, line := range lines {
+		record := Record{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Errorf("couldn't unmarshal audit line %q: %s", line, err)
+		}
+	}
+}
+
+func TestNewFromPath(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l, err := NewFromPath(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	l.Log(NewRecord("a", testRequest(), admissionctl.Allowed(""), "allowed", 0))
+	if err := l.Close(); err != nil {
+		t.Fatalf("unexpected error closing: %s", err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error reading: %s", err)
+	}
+	if !strings.Contains(string(b), `"uid":"test-uid"`) {
+		t.Errorf("expected the record to be written to the file, got %s", string(b))
+	}
+}
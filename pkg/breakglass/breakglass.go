@@ -0,0 +1,238 @@
+// Package breakglass holds time-bound overrides which let SRE through a
+// webhook's denial of a specific object or namespace during an incident,
+// without disabling the webhook or using a more privileged identity. The
+// overrides are read from a file, normally mounted from a ConfigMap, and can
+// be swapped at runtime without restarting the server.
+package breakglass
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/ghodss/yaml"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/configwatch"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/localmetrics"
+)
+
+const (
+	// Version is the only configuration version understood by this server
+	Version = "v1"
+	// MaxLifetime is how far in the future an override may expire, so that
+	// one cannot be left in place indefinitely
+	MaxLifetime = 7 * 24 * time.Hour
+
+	// JustificationAnnotation and ExpiresAnnotation are the audit annotation
+	// keys set when an override allows a request. The API server prefixes
+	// them with the webhook's name.
+	JustificationAnnotation = "break-glass-justification"
+	ExpiresAnnotation       = "break-glass-expires"
+)
+
+var (
+	log = logf.Log.WithName("breakglass")
+
+	// current is the last good configuration, nil until one has been loaded
+	current atomic.Pointer[Config]
+
+	// now is replaced in tests
+	now = time.Now
+)
+
+// Override lets requests for its target through the named webhook's denials
+// until it expires. The target is every object in Namespace, or the object
+// called Name in Namespace, or the cluster scoped object called Name when
+// Namespace is empty, optionally only of the given Kind.
+type Override struct {
+	Webhook   string `json:"webhook"`
+	Kind      string `json:"kind,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name,omitempty"`
+	// Justification explains why the override is needed, such as the
+	// incident it was created for. It is recorded with every use.
+	Justification string    `json:"justification"`
+	Expires       time.Time `json:"expires"`
+}
+
+// validate returns an error if o is malformed or expires more than
+// MaxLifetime after at
+func (o Override) validate(at time.Time) error {
+	switch {
+	case o.Webhook == "":
+		return fmt.Errorf("webhook is required")
+	case o.Namespace == "" && o.Name == "":
+		return fmt.Errorf("namespace or name is required")
+	case o.Justification == "":
+		return fmt.Errorf("justification is required")
+	case o.Expires.IsZero():
+		return fmt.Errorf("expires is required")
+	case o.Expires.After(at.Add(MaxLifetime)):
+		return fmt.Errorf("expires %s is more than %s away", o.Expires.Format(time.RFC3339), MaxLifetime)
+	}
+	return nil
+}
+
+// Covers returns true if o applies to request for the named webhook at time at
+func (o Override) Covers(hookName string, request admissionctl.Request, at time.Time) bool {
+	if o.Webhook != hookName || !at.Before(o.Expires) {
+		return false
+	}
+	if o.Kind != "" && o.Kind != request.Kind.Kind {
+		return false
+	}
+	if o.Name != "" && o.Name != request.Name {
+		return false
+	}
+	// Requests for a namespace have its name as their namespace, although
+	// namespaces are cluster scoped. A namespace is a target of the overrides
+	// for the objects in it.
+	if request.Kind.Group == "" && request.Kind.Kind == "Namespace" {
+		return o.Namespace == "" || (o.Name == "" && o.Namespace == request.Name)
+	}
+	return o.Namespace == request.Namespace
+}
+
+// Config is the versioned break-glass configuration
+type Config struct {
+	Version   string     `json:"version"`
+	Overrides []Override `json:"overrides,omitempty"`
+}
+
+// Parse decodes a YAML or JSON configuration. Malformed overrides are left
+// out of the Config rather than failing the whole configuration, and are
+// returned as errors alongside it, so that one mistake cannot stop every
+// other override from working during an incident.
+func Parse(b []byte) (*Config, []error, error) {
+	c := &Config{}
+	if err := yaml.Unmarshal(b, c); err != nil {
+		return nil, nil, err
+	}
+	if c.Version != Version {
+		return nil, nil, fmt.Errorf("unsupported break-glass version %q, expected %q", c.Version, Version)
+	}
+	var skipped []error
+	valid := make([]Override, 0, len(c.Overrides))
+	for i, o := range c.Overrides {
+		if err := o.validate(now()); err != nil {
+			skipped = append(skipped, fmt.Errorf("ignoring override %d for webhook %q: %w", i, o.Webhook, err))
+			continue
+		}
+		valid = append(valid, o)
+	}
+	c.Overrides = valid
+	return c, skipped, nil
+}
+
+// Match returns the unexpired override which covers request for the named
+// webhook
+func (c *Config) Match(hookName string, request admissionctl.Request) (Override, bool) {
+	if c == nil {
+		return Override{}, false
+	}
+	at := now()
+	for _, o := range c.Overrides {
+		if o.Covers(hookName, request, at) {
+			return o, true
+		}
+	}
+	return Override{}, false
+}
+
+// Current returns the active configuration, which is nil if none has been loaded
+func Current() *Config {
+	return current.Load()
+}
+
+// Set atomically replaces the active configuration
+func Set(c *Config) {
+	current.Store(c)
+}
+
+// Apply is consulted with every webhook's decision. If resp denies request
+// and an active override covers it, the request is allowed instead, with a
+// warning and audit annotations recording the override, and the use is
+// logged and counted. Any other response is returned unchanged.
+func Apply(hookName string, request admissionctl.Request, resp admissionctl.Response) (admissionctl.Response, *Override) {
+	if resp.Allowed || resp.Result == nil || resp.Result.Code != http.StatusForbidden {
+		return resp, nil
+	}
+	o, ok := Current().Match(hookName, request)
+	if !ok {
+		return resp, nil
+	}
+	log.Info("Break-glass override allowed a denied request", "webhookName", hookName,
+		"uid", request.UID,
+		"kind", request.Kind.Kind,
+		"namespace", request.Namespace,
+		"name", request.Name,
+		"user", request.UserInfo.Username,
+		"justification", o.Justification,
+		"expires", o.Expires)
+	localmetrics.IncrementBreakGlassOverride(hookName)
+
+	ret := admissionctl.Allowed("")
+	ret.UID = resp.UID
+	ret.AuditAnnotations = maps.Clone(resp.AuditAnnotations)
+	if ret.AuditAnnotations == nil {
+		ret.AuditAnnotations = map[string]string{}
+	}
+	ret.AuditAnnotations[JustificationAnnotation] = o.Justification
+	ret.AuditAnnotations[ExpiresAnnotation] = o.Expires.UTC().Format(time.RFC3339)
+	ret.Warnings = append(ret.Warnings, resp.Warnings...)
+	ret.Warnings = append(ret.Warnings, fmt.Sprintf("%s denial overridden until %s: %s", hookName, o.Expires.UTC().Format(time.RFC3339), o.Justification))
+	return ret, &o
+}
+
+// Watcher loads the configuration from a file and reloads it when the file's
+// content changes
+type Watcher struct {
+	watcher *configwatch.Watcher[Config]
+}
+
+// NewWatcher returns a Watcher for the file at path. A missing file is an
+// empty configuration since the ConfigMap is optional.
+func NewWatcher(path string, interval time.Duration) *Watcher {
+	parse := func(contents [][]byte) (*Config, error) {
+		if len(contents[0]) == 0 {
+			return &Config{Version: Version}, nil
+		}
+		c, skipped, err := Parse(contents[0])
+		if err != nil {
+			return nil, err
+		}
+		for _, err := range skipped {
+			log.Error(err, "Malformed break-glass override", "path", path)
+		}
+		return c, nil
+	}
+	return &Watcher{
+		watcher: configwatch.New("break-glass configuration", []string{path}, interval, parse).
+			AllowMissing().
+			OnLoad(func(c *Config, err error) {
+				localmetrics.IncrementBreakGlassReload(err == nil)
+				if err != nil {
+					return
+				}
+				Set(c)
+				log.Info("Loaded break-glass configuration", "path", path, "overrides", len(c.Overrides))
+			}),
+	}
+}
+
+// Load reads and activates the configuration file if it has changed. A
+// configuration which can't be parsed is rejected, leaving the last good one
+// active, and malformed overrides are logged and ignored.
+func (w *Watcher) Load() error {
+	return w.watcher.Load()
+}
+
+// Watch reloads the configuration every interval until ctx is done
+func (w *Watcher) Watch(ctx context.Context) {
+	w.watcher.Watch(ctx)
+}
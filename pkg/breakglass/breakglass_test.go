@@ -0,0 +1,242 @@
+package breakglass
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+var testNow = time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+const validConfig = `
+version: v1
+overrides:
+- webhook: namespace-validation
+  namespace: openshift-foo
+  justification: INC-123 restore the namespace
+  expires: "2026-10-16T18:00:00Z"
+- webhook: clusterrole-validation
+  kind: ClusterRole
+  name: cluster-admin
+  justification: INC-456
+  expires: "2026-10-17T00:00:00Z"
+`
+
+func fakeNow(t *testing.T, at time.Time) {
+	t.Helper()
+	now = func() time.Time { return at }
+	t.Cleanup(func() { now = time.Now })
+}
+
+func request(group, kind, namespace, name string) admissionctl.Request {
+	return admissionctl.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+		UID:       "test-uid",
+		Kind:      metav1.GroupVersionKind{Group: group, Version: "v1", Kind: kind},
+		Namespace: namespace,
+		Name:      name,
+	}}
+}
+
+func TestParse(t *testing.T) {
+	fakeNow(t, testNow)
+	tests := []struct {
+		name      string
+		config    string
+		overrides int
+		skipped   int
+		wantErr   bool
+	}{
+		{name: "valid", config: validConfig, overrides: 2},
+		{name: "no overrides", config: "version: v1"},
+		{name: "missing version", config: "overrides: []", wantErr: true},
+		{name: "unknown version", config: "version: v2", wantErr: true},
+		{name: "not yaml", config: "version: [", wantErr: true},
+		{
+			name:    "missing justification",
+			config:  "version: v1\noverrides:\n- webhook: foo\n  namespace: bar\n  expires: \"2026-10-16T18:00:00Z\"",
+			skipped: 1,
+		},
+		{
+			name:    "missing target",
+			config:  "version: v1\noverrides:\n- webhook: foo\n  justification: x\n  expires: \"2026-10-16T18:00:00Z\"",
+			skipped: 1,
+		},
+		{
+			name:    "missing expiry",
+			config:  "version: v1\noverrides:\n- webhook: foo\n  namespace: bar\n  justification: x",
+			skipped: 1,
+		},
+		{
+			name:    "expires too far away",
+			config:  "version: v1\noverrides:\n- webhook: foo\n  namespace: bar\n  justification: x\n  expires: \"2026-12-01T00:00:00Z\"",
+			skipped: 1,
+		},
+		{
+			name:      "one malformed override",
+			config:    validConfig + "- webhook: foo\n  namespace: bar\n",
+			overrides: 2,
+			skipped:   1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c, skipped, err := Parse([]byte(test.config))
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if len(c.Overrides) != test.overrides {
+				t.Errorf("Expected %d overrides, got %v", test.overrides, c.Overrides)
+			}
+			if len(skipped) != test.skipped {
+				t.Errorf("Expected %d skipped overrides, got %v", test.skipped, skipped)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	fakeNow(t, testNow)
+	c, _, err := Parse([]byte(validConfig))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	tests := []struct {
+		name     string
+		at       time.Time
+		hookName string
+		request  admissionctl.Request
+		want     bool
+	}{
+		{name: "object in namespace", hookName: "namespace-validation", request: request("", "ConfigMap", "openshift-foo", "cm"), want: true},
+		{name: "the namespace itself", hookName: "namespace-validation", request: request("", "Namespace", "openshift-foo", "openshift-foo"), want: true},
+		{name: "other namespace", hookName: "namespace-validation", request: request("", "Namespace", "openshift-bar", "openshift-bar")},
+		{name: "other webhook", hookName: "pod-validation", request: request("", "Pod", "openshift-foo", "pod")},
+		{name: "cluster scoped object", hookName: "clusterrole-validation", request: request("rbac.authorization.k8s.io", "ClusterRole", "", "cluster-admin"), want: true},
+		{name: "other name", hookName: "clusterrole-validation", request: request("rbac.authorization.k8s.io", "ClusterRole", "", "admin")},
+		{name: "other kind", hookName: "clusterrole-validation", request: request("rbac.authorization.k8s.io", "ClusterRoleBinding", "", "cluster-admin")},
+		{name: "namespaced object with the name", hookName: "clusterrole-validation", request: request("rbac.authorization.k8s.io", "ClusterRole", "openshift-foo", "cluster-admin")},
+		{name: "expired", at: testNow.Add(6 * time.Hour), hookName: "namespace-validation", request: request("", "ConfigMap", "openshift-foo", "cm")},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if !test.at.IsZero() {
+				fakeNow(t, test.at)
+			}
+			if _, got := c.Match(test.hookName, test.request); got != test.want {
+				t.Errorf("Match() = %v, want %v", got, test.want)
+			}
+		})
+	}
+
+	var nilConfig *Config
+	if _, ok := nilConfig.Match("namespace-validation", request("", "ConfigMap", "openshift-foo", "cm")); ok {
+		t.Error("nil Config should not match anything")
+	}
+}
+
+func TestApply(t *testing.T) {
+	fakeNow(t, testNow)
+	defer Set(nil)
+	c, _, err := Parse([]byte(validConfig))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	Set(c)
+	covered := request("", "ConfigMap", "openshift-foo", "cm")
+
+	allowed := utils.WebhookResponse(covered, true, "")
+	if resp, o := Apply("namespace-validation", covered, allowed); o != nil || !resp.Allowed {
+		t.Errorf("Expected an allowed response to be unchanged, got %v", resp)
+	}
+	errored := admissionctl.Errored(http.StatusBadRequest, os.ErrInvalid)
+	if resp, o := Apply("namespace-validation", covered, errored); o != nil || resp.Allowed {
+		t.Errorf("Expected an errored response to be unchanged, got %v", resp)
+	}
+	denied := utils.WebhookResponse(covered, false, "no", utils.WithWarnings("deprecated"), utils.WithRuleID("example"))
+	if resp, o := Apply("namespace-validation", request("", "ConfigMap", "openshift-bar", "cm"), denied); o != nil || resp.Allowed {
+		t.Errorf("Expected an uncovered denial to be unchanged, got %v", resp)
+	}
+
+	resp, o := Apply("namespace-validation", covered, denied)
+	if o == nil || !resp.Allowed {
+		t.Fatalf("Expected the denial to be overridden, got %v", resp)
+	}
+	if resp.UID != covered.UID {
+		t.Errorf("Expected UID %s, got %s", covered.UID, resp.UID)
+	}
+	expected := map[string]string{
+		utils.RuleIDAnnotation:  "example",
+		JustificationAnnotation: "INC-123 restore the namespace",
+		ExpiresAnnotation:       "2026-10-16T18:00:00Z",
+	}
+	for key, value := range expected {
+		if resp.AuditAnnotations[key] != value {
+			t.Errorf("Expected audit annotation %s=%s, got %v", key, value, resp.AuditAnnotations)
+		}
+	}
+	if _, ok := denied.AuditAnnotations[JustificationAnnotation]; ok {
+		t.Error("Expected the webhook's audit annotations not to be modified")
+	}
+	if len(resp.Warnings) != 2 || resp.Warnings[0] != "deprecated" || !strings.Contains(resp.Warnings[1], "INC-123") {
+		t.Errorf("Expected the webhook's warning and the override's, got %v", resp.Warnings)
+	}
+}
+
+func TestWatcherKeepsLastGoodConfig(t *testing.T) {
+	fakeNow(t, testNow)
+	defer Set(nil)
+	path := filepath.Join(t.TempDir(), "breakglass.yaml")
+	covered := request("", "ConfigMap", "openshift-foo", "cm")
+	w := NewWatcher(path, time.Second)
+
+	// A missing file is an empty configuration
+	if err := w.Load(); err != nil {
+		t.Fatalf("Load() of missing file error = %v", err)
+	}
+	if _, ok := Current().Match("namespace-validation", covered); Current() == nil || ok {
+		t.Fatal("expected an empty configuration to be active")
+	}
+
+	if err := os.WriteFile(path, []byte(validConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if _, ok := Current().Match("namespace-validation", covered); !ok {
+		t.Fatal("expected the override to be active after loading the configuration")
+	}
+
+	if err := os.WriteFile(path, []byte("version: v2"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Load(); err == nil {
+		t.Fatal("expected an error loading an invalid configuration")
+	}
+	if _, ok := Current().Match("namespace-validation", covered); !ok {
+		t.Fatal("expected the last good configuration to remain active")
+	}
+
+	// Malformed overrides are ignored without rejecting the configuration
+	if err := os.WriteFile(path, []byte("version: v1\noverrides:\n- webhook: namespace-validation\n  namespace: openshift-foo\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if _, ok := Current().Match("namespace-validation", covered); ok {
+		t.Fatal("expected the malformed override to be ignored")
+	}
+}
@@ -0,0 +1,80 @@
+// Package certwatcher serves a TLS certificate from disk and picks up
+// replacements, such as when service-ca rotates the serving certificate
+// Secret, without restarting the server.
+package certwatcher
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"time"
+
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/configwatch"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/localmetrics"
+)
+
+var log = logf.Log.WithName("certwatcher")
+
+// CertWatcher holds the current serving certificate and reloads it when the
+// certificate or key file changes
+type CertWatcher struct {
+	watcher *configwatch.Watcher[tls.Certificate]
+}
+
+// New returns a CertWatcher for the given certificate and key files. The
+// certificate is loaded immediately so that misconfiguration is reported at
+// startup.
+func New(certPath, keyPath string, interval time.Duration) (*CertWatcher, error) {
+	cw := &CertWatcher{
+		watcher: configwatch.New("serving certificate", []string{certPath, keyPath}, interval, parseKeyPair).
+			OnLoad(func(cert *tls.Certificate, err error) {
+				if err != nil {
+					return
+				}
+				localmetrics.SetServingCertificateExpiry(cert.Leaf.NotAfter)
+				log.Info("Loaded serving certificate", "path", certPath, "serial", cert.Leaf.SerialNumber.String(), "notAfter", cert.Leaf.NotAfter)
+			}),
+	}
+	if err := cw.Load(); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+// parseKeyPair parses the certificate and key, in that order
+func parseKeyPair(contents [][]byte) (*tls.Certificate, error) {
+	cert, err := tls.X509KeyPair(contents[0], contents[1])
+	if err != nil {
+		return nil, err
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return nil, err
+	}
+	cert.Leaf = leaf
+	return &cert, nil
+}
+
+// Load reads the certificate and key and makes them current if they have
+// changed. If they cannot be loaded, e.g. because only one of the pair has been
+// updated so far, the current certificate continues to be served.
+func (cw *CertWatcher) Load() error {
+	return cw.watcher.Load()
+}
+
+// GetCertificate is suitable for use as tls.Config.GetCertificate
+func (cw *CertWatcher) GetCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cert := cw.watcher.Current()
+	if cert == nil {
+		return nil, errors.New("no serving certificate has been loaded")
+	}
+	return cert, nil
+}
+
+// Watch reloads the certificate every interval until ctx is done
+func (cw *CertWatcher) Watch(ctx context.Context) {
+	cw.watcher.Watch(ctx)
+}
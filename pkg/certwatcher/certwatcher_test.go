@@ -0,0 +1,116 @@
+package certwatcher
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/localmetrics"
+)
+
+// writeKeyPair writes a self-signed certificate and its key to certPath and
+// keyPath and returns the certificate's not-after time
+func writeKeyPair(t *testing.T, certPath, keyPath string, serial int64) time.Time {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	notAfter := time.Now().Add(time.Duration(serial) * time.Hour).Truncate(time.Second)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "validation-webhook.openshift-validation-webhook.svc"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return notAfter
+}
+
+func servedSerial(t *testing.T, cw *CertWatcher) int64 {
+	t.Helper()
+	cert, err := cw.GetCertificate(nil)
+	if err != nil {
+		t.Fatalf("GetCertificate() error = %v", err)
+	}
+	return cert.Leaf.SerialNumber.Int64()
+}
+
+func expiryMetric(t *testing.T) float64 {
+	t.Helper()
+	m := &dto.Metric{}
+	if err := localmetrics.MetricServingCertificateExpiry.Write(m); err != nil {
+		t.Fatal(err)
+	}
+	return m.GetGauge().GetValue()
+}
+
+func TestCertWatcherRotation(t *testing.T) {
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, "tls.crt")
+	keyPath := filepath.Join(dir, "tls.key")
+
+	notAfter := writeKeyPair(t, certPath, keyPath, 1)
+	cw, err := New(certPath, keyPath, time.Second)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if got := servedSerial(t, cw); got != 1 {
+		t.Errorf("expected serial 1 to be served, got %d", got)
+	}
+	if got := expiryMetric(t); got != float64(notAfter.Unix()) {
+		t.Errorf("expected expiry metric %d, got %f", notAfter.Unix(), got)
+	}
+
+	notAfter = writeKeyPair(t, certPath, keyPath, 2)
+	if err := cw.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := servedSerial(t, cw); got != 2 {
+		t.Errorf("expected rotated serial 2 to be served, got %d", got)
+	}
+	if got := expiryMetric(t); got != float64(notAfter.Unix()) {
+		t.Errorf("expected expiry metric %d, got %f", notAfter.Unix(), got)
+	}
+
+	// A half-written rotation keeps the current certificate
+	if err := os.WriteFile(keyPath, []byte("not a key"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := cw.Load(); err == nil {
+		t.Error("expected an error loading a mismatched key pair")
+	}
+	if got := servedSerial(t, cw); got != 2 {
+		t.Errorf("expected serial 2 to still be served, got %d", got)
+	}
+}
+
+func TestNewMissingFiles(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := New(filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"), time.Second); err == nil {
+		t.Error("expected an error when the certificate does not exist")
+	}
+}
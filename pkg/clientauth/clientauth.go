@@ -0,0 +1,53 @@
+// Package clientauth configures the webhook server to require a client
+// certificate, so that only the kube-apiserver can submit AdmissionReviews.
+package clientauth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+)
+
+// Configure makes cfg require and verify a client certificate signed by a CA in
+// the PEM bundle at caBundlePath. If allowedNames is not empty the client
+// certificate's subject CN or one of its DNS SANs must also be in it.
+func Configure(cfg *tls.Config, caBundlePath string, allowedNames []string) error {
+	bundle, err := os.ReadFile(caBundlePath)
+	if err != nil {
+		return err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(bundle) {
+		return fmt.Errorf("no certificates found in client CA bundle %s", caBundlePath)
+	}
+	cfg.ClientCAs = pool
+	cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	if len(allowedNames) > 0 {
+		cfg.VerifyConnection = VerifyNames(allowedNames)
+	}
+	return nil
+}
+
+// VerifyNames returns a tls.Config.VerifyConnection callback which accepts a
+// verified client certificate only if its subject CN or one of its DNS SANs is
+// in allowedNames. It is run for resumed sessions too.
+func VerifyNames(allowedNames []string) func(tls.ConnectionState) error {
+	return func(cs tls.ConnectionState) error {
+		if len(cs.PeerCertificates) == 0 {
+			return errors.New("client did not present a certificate")
+		}
+		leaf := cs.PeerCertificates[0]
+		if slices.Contains(allowedNames, leaf.Subject.CommonName) {
+			return nil
+		}
+		for _, san := range leaf.DNSNames {
+			if slices.Contains(allowedNames, san) {
+				return nil
+			}
+		}
+		return fmt.Errorf("client certificate for %q is not one of the allowed names", leaf.Subject.CommonName)
+	}
+}
@@ -0,0 +1,153 @@
+package clientauth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// clientCert issues a client certificate with the given CN and DNS SANs
+func (ca *testCA) clientCert(t *testing.T, cn string, sans ...string) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     sans,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestConfigure(t *testing.T) {
+	ca := newTestCA(t)
+	otherCA := newTestCA(t)
+	bundlePath := filepath.Join(t.TempDir(), "ca-bundle.crt")
+	if err := os.WriteFile(bundlePath, ca.pem, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		allowedNames []string
+		clientCerts  []tls.Certificate
+		shouldAccept bool
+	}{
+		{
+			name:         "no client certificate",
+			shouldAccept: false,
+		},
+		{
+			name:         "certificate from another CA",
+			clientCerts:  []tls.Certificate{otherCA.clientCert(t, "kube-apiserver")},
+			shouldAccept: false,
+		},
+		{
+			name:         "any name when unrestricted",
+			clientCerts:  []tls.Certificate{ca.clientCert(t, "someone")},
+			shouldAccept: true,
+		},
+		{
+			name:         "allowed CN",
+			allowedNames: []string{"kube-apiserver"},
+			clientCerts:  []tls.Certificate{ca.clientCert(t, "kube-apiserver")},
+			shouldAccept: true,
+		},
+		{
+			name:         "allowed SAN",
+			allowedNames: []string{"kube-apiserver"},
+			clientCerts:  []tls.Certificate{ca.clientCert(t, "someone", "kube-apiserver")},
+			shouldAccept: true,
+		},
+		{
+			name:         "name not allowed",
+			allowedNames: []string{"kube-apiserver"},
+			clientCerts:  []tls.Certificate{ca.clientCert(t, "someone", "someone.example.com")},
+			shouldAccept: false,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			server.TLS = &tls.Config{}
+			if err := Configure(server.TLS, bundlePath, test.allowedNames); err != nil {
+				t.Fatalf("Configure() error = %v", err)
+			}
+			server.StartTLS()
+			defer server.Close()
+
+			client := server.Client()
+			client.Transport.(*http.Transport).TLSClientConfig.Certificates = test.clientCerts
+			resp, err := client.Get(server.URL)
+			if err == nil {
+				resp.Body.Close()
+			}
+			if accepted := err == nil; accepted != test.shouldAccept {
+				t.Errorf("expected accepted=%v, got error %v", test.shouldAccept, err)
+			}
+		})
+	}
+}
+
+func TestConfigureEmptyBundle(t *testing.T) {
+	bundlePath := filepath.Join(t.TempDir(), "ca-bundle.crt")
+	if err := os.WriteFile(bundlePath, []byte("not a certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := Configure(&tls.Config{}, bundlePath, nil); err == nil {
+		t.Error("expected an error for a bundle with no certificates")
+	}
+}
@@ -0,0 +1,133 @@
+// Package configwatch polls files, normally mounted from a ConfigMap or
+// Secret, and swaps in what they parse to whenever their content changes,
+// without restarting the server. Polling is used rather than inotify because
+// such volumes are updated by atomically swapping a symlink.
+package configwatch
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var log = logf.Log.WithName("configwatch")
+
+// ParseFunc parses the contents of the watched files, in the order of their
+// paths, into the value to swap in
+type ParseFunc[T any] func(contents [][]byte) (*T, error)
+
+// Watcher holds the value last parsed from a set of files and replaces it
+// when any of them changes. An invalid change is rejected, leaving the last
+// good value current.
+type Watcher[T any] struct {
+	// name describes the value in errors and logs, e.g. "allowlist configuration"
+	name     string
+	paths    []string
+	interval time.Duration
+	parse    ParseFunc[T]
+	// allowMissing reads files which don't exist as empty
+	allowMissing bool
+	// onLoad is called after each attempt to load changed files
+	onLoad func(value *T, err error)
+
+	current atomic.Pointer[T]
+
+	mu sync.Mutex
+	// last is the content of the files when they were last read
+	last   [][]byte
+	loaded bool
+}
+
+// New returns a Watcher which parses the files at paths with parse. Nothing is
+// read until Load is called.
+func New[T any](name string, paths []string, interval time.Duration, parse ParseFunc[T]) *Watcher[T] {
+	return &Watcher[T]{
+		name:     name,
+		paths:    paths,
+		interval: interval,
+		parse:    parse,
+	}
+}
+
+// AllowMissing reads files which don't exist as empty rather than failing,
+// e.g. when they are mounted from an optional ConfigMap
+func (w *Watcher[T]) AllowMissing() *Watcher[T] {
+	w.allowMissing = true
+	return w
+}
+
+// OnLoad calls f after each attempt to load changed files, with the new value
+// or the error which kept the last good one current, e.g. to count reloads or
+// to swap the value into a package's own state
+func (w *Watcher[T]) OnLoad(f func(value *T, err error)) *Watcher[T] {
+	w.onLoad = f
+	return w
+}
+
+// Current returns the last good value, or nil if none has been loaded
+func (w *Watcher[T]) Current() *T {
+	return w.current.Load()
+}
+
+// Load reads the files and, if any has changed, parses them and makes the
+// result current. Content which fails to parse is remembered so that it is
+// only reported once.
+func (w *Watcher[T]) Load() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	contents := make([][]byte, len(w.paths))
+	for i, path := range w.paths {
+		b, err := os.ReadFile(path)
+		if err != nil && !(w.allowMissing && errors.Is(err, os.ErrNotExist)) {
+			w.notify(nil, err)
+			return err
+		}
+		contents[i] = b
+	}
+	if w.loaded && slices.EqualFunc(contents, w.last, bytes.Equal) {
+		return nil
+	}
+	w.last = contents
+	w.loaded = true
+
+	value, err := w.parse(contents)
+	if err != nil {
+		err = fmt.Errorf("keeping the last good %s: %w", w.name, err)
+		w.notify(nil, err)
+		return err
+	}
+	w.current.Store(value)
+	w.notify(value, nil)
+	return nil
+}
+
+func (w *Watcher[T]) notify(value *T, err error) {
+	if w.onLoad != nil {
+		w.onLoad(value, err)
+	}
+}
+
+// Watch reloads the files every interval until ctx is done
+func (w *Watcher[T]) Watch(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := w.Load(); err != nil {
+				log.Error(err, "Failed to reload", "name", w.name, "paths", w.paths)
+			}
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package configwatch
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// joined is what the test parser makes of the files' contents
+type joined string
+
+func parseJoined(contents [][]byte) (*joined, error) {
+	parts := make([]string, 0, len(contents))
+	for _, b := range contents {
+		if string(b) == "invalid" {
+			return nil, errors.New("invalid content")
+		}
+		parts = append(parts, string(b))
+	}
+	j := joined(strings.Join(parts, ","))
+	return &j, nil
+}
+
+func TestWatcherKeepsLastGoodValue(t *testing.T) {
+	dir := t.TempDir()
+	first, second := filepath.Join(dir, "first"), filepath.Join(dir, "second")
+	loads, failures := 0, 0
+	w := New("test value", []string{first, second}, time.Second, parseJoined).
+		OnLoad(func(_ *joined, err error) {
+			if err != nil {
+				failures++
+			} else {
+				loads++
+			}
+		})
+
+	if err := w.Load(); err == nil {
+		t.Fatal("expected an error for missing files")
+	}
+	if w.Current() != nil {
+		t.Fatal("expected no value before a successful load")
+	}
+
+	for path, content := range map[string]string{first: "a", second: "b"} {
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := *w.Current(); got != "a,b" {
+		t.Errorf("expected a,b, got %q", got)
+	}
+	// Unchanged files aren't parsed again
+	if err := w.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if err := os.WriteFile(second, []byte("invalid"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Load(); err == nil {
+		t.Fatal("expected an error loading invalid content")
+	}
+	if got := *w.Current(); got != "a,b" {
+		t.Errorf("expected the last good value a,b, got %q", got)
+	}
+	// The same invalid content is only reported once
+	if err := w.Load(); err != nil {
+		t.Fatalf("Load() of unchanged content error = %v", err)
+	}
+
+	if loads != 1 || failures != 2 {
+		t.Errorf("expected 1 load and 2 failures, got %d and %d", loads, failures)
+	}
+}
+
+func TestWatcherAllowMissing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing")
+	w := New("test value", []string{path}, time.Second, parseJoined).AllowMissing()
+	if err := w.Load(); err != nil {
+		t.Fatalf("Load() of a missing file error = %v", err)
+	}
+	if got := w.Current(); got == nil || *got != "" {
+		t.Errorf("expected an empty value, got %v", got)
+	}
+}
@@ -0,0 +1,223 @@
+package dispatcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"sync/atomic"
+
+	"github.com/ghodss/yaml"
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/localmetrics"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks"
+)
+
+// ConcurrencyConfig limits how many requests are handled at once, so that a
+// burst of requests for one webhook cannot starve the others
+type ConcurrencyConfig struct {
+	// MaxInFlight is how many requests all webhooks together may handle at
+	// once. Zero means unlimited.
+	MaxInFlight int `json:"maxInFlight,omitempty"`
+	// ReservedInFlight is how many of MaxInFlight only critical webhooks may
+	// use
+	ReservedInFlight int `json:"reservedInFlight,omitempty"`
+	// Webhooks maps webhook names to their own limits
+	Webhooks map[string]ConcurrencyLimit `json:"webhooks,omitempty"`
+}
+
+// ConcurrencyLimit limits how many requests one webhook handles at once
+type ConcurrencyLimit struct {
+	// MaxInFlight is how many requests the webhook may handle at once. Zero
+	// means unlimited.
+	MaxInFlight int `json:"maxInFlight,omitempty"`
+	// MaxQueued is how many requests may wait for capacity. A request which
+	// arrives when the queue is full, or which is still waiting at its
+	// deadline, is shed. Requests for a webhook whose FailurePolicy is Ignore
+	// never wait.
+	MaxQueued int `json:"maxQueued,omitempty"`
+	// Critical webhooks may use the ReservedInFlight capacity
+	Critical bool `json:"critical,omitempty"`
+}
+
+// Validate ensures the limits are consistent and every webhook name is one of
+// known
+func (c ConcurrencyConfig) Validate(known []string) error {
+	if c.MaxInFlight < 0 || c.ReservedInFlight < 0 {
+		return fmt.Errorf("maxInFlight and reservedInFlight must not be negative")
+	}
+	if c.ReservedInFlight > 0 && c.ReservedInFlight >= c.MaxInFlight {
+		return fmt.Errorf("reservedInFlight (%d) must be less than maxInFlight (%d)", c.ReservedInFlight, c.MaxInFlight)
+	}
+	for name, limit := range c.Webhooks {
+		if !slices.Contains(known, name) {
+			return fmt.Errorf("concurrency limit set for unknown webhook %s", name)
+		}
+		if limit.MaxInFlight < 0 || limit.MaxQueued < 0 {
+			return fmt.Errorf("maxInFlight and maxQueued for webhook %s must not be negative", name)
+		}
+	}
+	return nil
+}
+
+// LoadConcurrencyConfig reads a YAML or JSON ConcurrencyConfig
+func LoadConcurrencyConfig(path string) (ConcurrencyConfig, error) {
+	config := ConcurrencyConfig{}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return config, err
+	}
+	if err := yaml.Unmarshal(b, &config); err != nil {
+		return config, fmt.Errorf("couldn't parse concurrency limits from %s: %w", path, err)
+	}
+	return config, nil
+}
+
+var (
+	// errNoCapacity is returned when there is no capacity free and the
+	// request may not wait for it
+	errNoCapacity = errors.New("no capacity is free")
+	// errQueueFull is returned when a request cannot wait for capacity
+	errQueueFull = errors.New("too many requests are already waiting")
+)
+
+// slots is a pool of capacity to handle requests. A nil slots is unlimited.
+type slots chan struct{}
+
+// release gives back a slot taken from s
+func (s slots) release() {
+	if s != nil {
+		<-s
+	}
+}
+
+// take takes a slot from pool or, if it is full, from fallback, which may be
+// nil. It returns the pool the slot was taken from. If wait is true it waits
+// until ctx is done for a slot to be given back, otherwise it returns
+// errNoCapacity.
+func take(ctx context.Context, wait bool, pool, fallback slots) (slots, error) {
+	if pool == nil {
+		return nil, nil
+	}
+	// Sending on a nil fallback never proceeds
+	select {
+	case pool <- struct{}{}:
+		return pool, nil
+	default:
+	}
+	select {
+	case fallback <- struct{}{}:
+		return fallback, nil
+	default:
+	}
+	if !wait {
+		return nil, errNoCapacity
+	}
+	select {
+	case pool <- struct{}{}:
+		return pool, nil
+	case fallback <- struct{}{}:
+		return fallback, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// concurrencyLimiter hands out capacity to handle requests
+type concurrencyLimiter struct {
+	// shared can be used by any webhook, reserved only by critical ones
+	shared   slots
+	reserved slots
+	hooks    map[string]*hookLimiter
+}
+
+// hookLimiter is one webhook's own capacity
+type hookLimiter struct {
+	slots     slots
+	queued    atomic.Int32
+	maxQueued int32
+	critical  bool
+}
+
+func newConcurrencyLimiter(config ConcurrencyConfig) *concurrencyLimiter {
+	l := &concurrencyLimiter{hooks: make(map[string]*hookLimiter)}
+	if config.MaxInFlight > 0 {
+		l.shared = make(slots, config.MaxInFlight-config.ReservedInFlight)
+		if config.ReservedInFlight > 0 {
+			l.reserved = make(slots, config.ReservedInFlight)
+		}
+	}
+	for name, limit := range config.Webhooks {
+		h := &hookLimiter{maxQueued: int32(limit.MaxQueued), critical: limit.Critical}
+		if limit.MaxInFlight > 0 {
+			h.slots = make(slots, limit.MaxInFlight)
+		}
+		l.hooks[name] = h
+	}
+	return l
+}
+
+// acquire takes capacity to handle a request for hook and returns a function
+// to give it back. If there is none free the request waits for it until ctx is
+// done, unless hook's FailurePolicy is Ignore or it already has MaxQueued
+// requests waiting.
+func (l *concurrencyLimiter) acquire(ctx context.Context, hook webhooks.Webhook) (release func(), err error) {
+	h, ok := l.hooks[hook.Name()]
+	if !ok {
+		h = &hookLimiter{}
+	}
+	release, err = l.take(ctx, h, false)
+	if !errors.Is(err, errNoCapacity) || hook.FailurePolicy() == admissionregv1.Ignore {
+		return release, err
+	}
+	if h.queued.Add(1) > h.maxQueued {
+		h.queued.Add(-1)
+		return nil, errQueueFull
+	}
+	defer h.queued.Add(-1)
+	return l.take(ctx, h, true)
+}
+
+// take takes a slot of the webhook's own capacity and one of the shared
+// capacity, or the reserved capacity if the webhook is critical
+func (l *concurrencyLimiter) take(ctx context.Context, h *hookLimiter, wait bool) (func(), error) {
+	own, err := take(ctx, wait, h.slots, nil)
+	if err != nil {
+		return nil, err
+	}
+	var reserved slots
+	if h.critical {
+		reserved = l.reserved
+	}
+	pool, err := take(ctx, wait, l.shared, reserved)
+	if err != nil {
+		own.release()
+		return nil, err
+	}
+	return func() {
+		pool.release()
+		own.release()
+	}, nil
+}
+
+// shedResponse is sent instead of hook's decision when there was no capacity
+// to handle the request. A webhook whose FailurePolicy is Ignore allows the
+// request, as the API server would have done had it timed out the call.
+func shedResponse(hook webhooks.Webhook, request admissionctl.Request, err error) admissionctl.Response {
+	localmetrics.IncrementWebhookShed(hook.Name())
+	err = fmt.Errorf("%s is overloaded: %w", hook.Name(), err)
+	log.Info("Shedding request", "webhookName", hook.Name(), "uid", request.UID, "reason", err.Error())
+	var resp admissionctl.Response
+	if hook.FailurePolicy() == admissionregv1.Ignore {
+		resp = admissionctl.Allowed(err.Error())
+		resp.Warnings = []string{err.Error()}
+	} else {
+		resp = admissionctl.Errored(http.StatusTooManyRequests, err)
+	}
+	resp.UID = request.UID
+	return resp
+}
@@ -0,0 +1,191 @@
+package dispatcher
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/localmetrics"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks"
+)
+
+// namedWebhook is a fakeWebhook served at /<name>
+type namedWebhook struct {
+	fakeWebhook
+	name string
+}
+
+func (n *namedWebhook) Name() string   { return n.name }
+func (n *namedWebhook) GetURI() string { return "/" + n.name }
+
+// blockingFailWebhook is a blockingWebhook whose FailurePolicy is Fail
+type blockingFailWebhook struct {
+	*blockingWebhook
+}
+
+func (b *blockingFailWebhook) FailurePolicy() admissionregv1.FailurePolicyType {
+	return admissionregv1.Fail
+}
+
+// handleInBackground sends a review to the dispatcher without waiting for the
+// response
+func handleInBackground(t *testing.T, wg *sync.WaitGroup, d *Dispatcher) {
+	t.Helper()
+	body := validAdmissionReviewBody(t)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		req := httptest.NewRequestWithContext(context.Background(), "POST", "/test-hook", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		d.HandleRequest(httptest.NewRecorder(), req)
+	}()
+}
+
+func waitForEntry(t *testing.T, bw *blockingWebhook) {
+	t.Helper()
+	select {
+	case <-bw.entered:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for the webhook to be called")
+	}
+}
+
+func TestConcurrencyConfigValidate(t *testing.T) {
+	known := []string{"test-validation"}
+	tests := []struct {
+		name    string
+		config  ConcurrencyConfig
+		wantErr bool
+	}{
+		{name: "empty", config: ConcurrencyConfig{}},
+		{name: "reserved within limit", config: ConcurrencyConfig{MaxInFlight: 10, ReservedInFlight: 2}},
+		{name: "reserved without limit", config: ConcurrencyConfig{ReservedInFlight: 2}, wantErr: true},
+		{name: "reserved is the whole limit", config: ConcurrencyConfig{MaxInFlight: 2, ReservedInFlight: 2}, wantErr: true},
+		{name: "negative limit", config: ConcurrencyConfig{Webhooks: map[string]ConcurrencyLimit{"test-validation": {MaxQueued: -1}}}, wantErr: true},
+		{name: "unknown webhook", config: ConcurrencyConfig{Webhooks: map[string]ConcurrencyLimit{"other-validation": {MaxInFlight: 1}}}, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.config.Validate(known); (err != nil) != test.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadConcurrencyConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "concurrency.yaml")
+	content := "maxInFlight: 20\nreservedInFlight: 5\nwebhooks:\n  pod-validation:\n    maxInFlight: 4\n    maxQueued: 8\n  regular-user-validation:\n    critical: true\n"
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	config, err := LoadConcurrencyConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConcurrencyConfig() error = %v", err)
+	}
+	if config.MaxInFlight != 20 || config.ReservedInFlight != 5 || config.Webhooks["pod-validation"].MaxQueued != 8 || !config.Webhooks["regular-user-validation"].Critical {
+		t.Errorf("unexpected config %+v", config)
+	}
+}
+
+func TestHandleRequest_ShedsIgnoreWebhook(t *testing.T) {
+	bw := &blockingWebhook{entered: make(chan struct{}), release: make(chan struct{})}
+	d := NewDispatcher(webhooks.RegisteredWebhooks{
+		"test-validation": func() webhooks.Webhook { return bw },
+	}, WithConcurrencyConfig(ConcurrencyConfig{
+		Webhooks: map[string]ConcurrencyLimit{"test-validation": {MaxInFlight: 1, MaxQueued: 5}},
+	}))
+	shed := localmetrics.MetricWebhookShedRequests.WithLabelValues("test-validation")
+	before := counterValue(t, shed)
+
+	var wg sync.WaitGroup
+	handleInBackground(t, &wg, d)
+	waitForEntry(t, bw)
+
+	start := time.Now()
+	review := sendReview(t, d)
+	if elapsed := time.Since(start); elapsed >= time.Second {
+		t.Errorf("expected the request to be shed without waiting, took %s", elapsed)
+	}
+	if !review.Response.Allowed || len(review.Response.Warnings) == 0 {
+		t.Errorf("expected the Ignore webhook to allow with a warning, got %+v", review.Response)
+	}
+	if review.Response.UID != "test-uid" {
+		t.Errorf("expected UID test-uid, got %q", review.Response.UID)
+	}
+	if got := counterValue(t, shed) - before; got != 1 {
+		t.Errorf("expected the shed metric to increase by 1, got %v", got)
+	}
+
+	close(bw.release)
+	wg.Wait()
+}
+
+func TestHandleRequest_QueuesFailWebhook(t *testing.T) {
+	bw := &blockingWebhook{entered: make(chan struct{}), release: make(chan struct{})}
+	d := NewDispatcher(webhooks.RegisteredWebhooks{
+		"test-validation": func() webhooks.Webhook { return &blockingFailWebhook{bw} },
+	}, WithConcurrencyConfig(ConcurrencyConfig{
+		Webhooks: map[string]ConcurrencyLimit{"test-validation": {MaxInFlight: 1, MaxQueued: 1}},
+	}))
+
+	var wg sync.WaitGroup
+	handleInBackground(t, &wg, d)
+	waitForEntry(t, bw)
+	handleInBackground(t, &wg, d)
+	for d.limiter.hooks["test-validation"].queued.Load() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// The queue is full
+	review := sendReview(t, d)
+	if review.Response.Allowed || review.Response.Result.Code != http.StatusTooManyRequests {
+		t.Errorf("expected the request to be rejected with %d, got %+v", http.StatusTooManyRequests, review.Response)
+	}
+
+	// The queued request is handled once the first one finishes
+	close(bw.release)
+	waitForEntry(t, bw)
+	wg.Wait()
+}
+
+func TestHandleRequest_ReservedCapacity(t *testing.T) {
+	bw := &blockingWebhook{entered: make(chan struct{}), release: make(chan struct{})}
+	d := NewDispatcher(webhooks.RegisteredWebhooks{
+		"test-validation":     func() webhooks.Webhook { return bw },
+		"critical-validation": func() webhooks.Webhook { return &namedWebhook{name: "critical-validation"} },
+	}, WithConcurrencyConfig(ConcurrencyConfig{
+		MaxInFlight:      2,
+		ReservedInFlight: 1,
+		Webhooks:         map[string]ConcurrencyLimit{"critical-validation": {Critical: true}},
+	}))
+	shed := localmetrics.MetricWebhookShedRequests.WithLabelValues("critical-validation")
+	before := counterValue(t, shed)
+
+	var wg sync.WaitGroup
+	handleInBackground(t, &wg, d)
+	waitForEntry(t, bw)
+
+	// The shared capacity is in use
+	if review := sendReview(t, d); len(review.Response.Warnings) == 0 {
+		t.Error("expected a non-critical webhook to be shed")
+	}
+	review := sendReviewTo(t, d, "/critical-validation")
+	if !review.Response.Allowed || len(review.Response.Warnings) != 0 {
+		t.Errorf("expected the critical webhook to use the reserved capacity, got %+v", review.Response)
+	}
+	if got := counterValue(t, shed) - before; got != 0 {
+		t.Errorf("expected the critical webhook not to be shed, got %v", got)
+	}
+
+	close(bw.release)
+	wg.Wait()
+}
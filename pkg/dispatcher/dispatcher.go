@@ -1,14 +1,28 @@
 package dispatcher
 
 import (
+	"context"
 	"fmt"
+	"maps"
 	"net/http"
 	"net/url"
+	"slices"
+	"strconv"
+	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	admissionv1 "k8s.io/api/admission/v1"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/auditlog"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/breakglass"
 	responsehelper "github.com/openshift/managed-cluster-validating-webhooks/pkg/helpers"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/localmetrics"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/recorder"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/tracing"
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks"
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
 )
@@ -19,23 +33,101 @@ const maxBodyBytes = 3 * 1024 * 1024 // 3 MiB, matches kube-apiserver MaxRequest
 
 // Dispatcher struct
 type Dispatcher struct {
-	hooks *map[string]webhooks.WebhookFactory // uri -> hookfactory
+	hooks       map[string]webhooks.Webhook // uri -> hook
+	auditLog    *auditlog.Logger
+	enforcement EnforcementModes
+	// timeoutFallback is the decision sent when a webhook times out
+	timeoutFallback TimeoutFallback
+	limiter         *concurrencyLimiter
+	recorder        *recorder.Recorder
 }
 
-// NewDispatcher new dispatcher
-func NewDispatcher(hooks webhooks.RegisteredWebhooks) *Dispatcher {
-	hookMap := make(map[string]webhooks.WebhookFactory)
-	for _, hook := range hooks {
-		hookMap[hook().GetURI()] = hook
+// Option configures optional Dispatcher behaviour
+type Option func(*Dispatcher)
+
+// WithAuditLogger writes a structured record of every admission decision to l
+func WithAuditLogger(l *auditlog.Logger) Option {
+	return func(d *Dispatcher) {
+		d.auditLog = l
 	}
-	return &Dispatcher{
-		hooks: &hookMap,
+}
+
+// WithRecorder writes the requests r selects, and the webhooks' decisions, to
+// disk so that they can be replayed
+func WithRecorder(r *recorder.Recorder) Option {
+	return func(d *Dispatcher) {
+		d.recorder = r
 	}
 }
 
+// WithEnforcementModes sets how each webhook's denials are enforced. Webhooks
+// not present in modes are enforced.
+func WithEnforcementModes(modes EnforcementModes) Option {
+	return func(d *Dispatcher) {
+		d.enforcement = modes
+	}
+}
+
+// WithTimeoutFallback sets the decision sent when a webhook does not reach one
+// before its deadline. The default is FailurePolicyFallback.
+func WithTimeoutFallback(fallback TimeoutFallback) Option {
+	return func(d *Dispatcher) {
+		d.timeoutFallback = fallback
+	}
+}
+
+// WithConcurrencyConfig limits how many requests each webhook, and all of
+// them together, handle at once. By default there is no limit.
+func WithConcurrencyConfig(config ConcurrencyConfig) Option {
+	return func(d *Dispatcher) {
+		d.limiter = newConcurrencyLimiter(config)
+	}
+}
+
+// NewDispatcher new dispatcher. Each webhook is built once and shared by all
+// requests, so webhooks must be safe for concurrent use. It panics if two
+// webhooks listen on the same URI.
+func NewDispatcher(hooks webhooks.RegisteredWebhooks, opts ...Option) *Dispatcher {
+	hookMap := make(map[string]webhooks.Webhook)
+	for _, factory := range hooks {
+		hook := factory()
+		if _, ok := hookMap[hook.GetURI()]; ok {
+			panic(fmt.Errorf("Duplicate webhook trying to listen on %s", hook.GetURI()))
+		}
+		hookMap[hook.GetURI()] = hook
+	}
+	d := &Dispatcher{
+		hooks:           hookMap,
+		timeoutFallback: FailurePolicyFallback,
+		limiter:         newConcurrencyLimiter(ConcurrencyConfig{}),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// URIs returns the URIs of the webhooks, sorted, on which HandleRequest
+// should be registered
+func (d *Dispatcher) URIs() []string {
+	return slices.Sorted(maps.Keys(d.hooks))
+}
+
+// AllowlistWebhooks returns the names of the webhooks which exempt the
+// principals the allowlist configures for them
+func (d *Dispatcher) AllowlistWebhooks() []string {
+	var names []string
+	for _, uri := range d.URIs() {
+		if hook, ok := d.hooks[uri].(webhooks.AllowlistWebhook); ok && hook.AllowlistEnabled() {
+			names = append(names, hook.Name())
+		}
+	}
+	return names
+}
+
 // HandleRequest http request
 // HTTP status code usage: When the request body is correctly parsed into a
-// request (utils.ParseHTTPRequest) then we should always send 200 OK and use
+// request (utils.ParseHTTPRequestVersion) then we should always send 200 OK and use
 // the response body (response.status.code) to indicate a problem. When instead
 // there's a problem with the HTTP request itself (404, an inability to parse a
 // request, or some internal problem) it is appropriate to use the HTTP status
@@ -52,31 +144,100 @@ func (d *Dispatcher) HandleRequest(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// is it one of ours?
-	if hook, ok := (*d.hooks)[url.Path]; ok {
+	if hook, ok := d.hooks[url.Path]; ok {
+		start := time.Now()
+		// The request's span is a child of the API server's, if it sent a
+		// traceparent header
+		ctx, span := tracing.Start(tracing.Extract(r.Context(), r), "admission "+hook.Name(), attribute.String("webhook", hook.Name()))
+		defer span.End()
+		var request admissionctl.Request
+		// Responses are sent in the AdmissionReview version of the request
+		gvk := admissionv1.SchemeGroupVersion.WithKind("AdmissionReview")
+		// A panic in the webhook must not take the server down, and the API
+		// server still needs a response it can make sense of
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				resp := d.panicResponse(hook, request, recovered)
+				responsehelper.SendVersionedResponse(w, gvk, resp)
+				d.recordDecision(ctx, hook.Name(), request, resp, localmetrics.OutcomeErrored, start, EnforceMode, nil)
+			}
+		}()
 		// it's one of ours, so let's attempt to parse the request
-		request, _, err := utils.ParseHTTPRequest(r)
+		_, decodeSpan := tracing.Start(ctx, "decode")
+		request, _, gvk, err = utils.ParseHTTPRequestVersion(r)
+		if err != nil {
+			tracing.RecordError(decodeSpan, err)
+		}
+		decodeSpan.End()
 		// Problem even parsing an AdmissionReview, so use HTTP status code
 		if err != nil {
 			w.WriteHeader(http.StatusBadRequest)
 			log.Error(err, "Error parsing HTTP Request Body")
-			responsehelper.SendResponse(w, admissionctl.Errored(http.StatusBadRequest, err))
+			resp := admissionctl.Errored(http.StatusBadRequest, err)
+			responsehelper.SendVersionedResponse(w, gvk, resp)
+			d.recordDecision(ctx, hook.Name(), request, resp, localmetrics.OutcomeInvalid, start, EnforceMode, nil)
 			return
 		}
+		span.SetAttributes(
+			attribute.String("admission.uid", string(request.UID)),
+			attribute.String("admission.version", gvk.Version),
+			attribute.String("admission.operation", string(request.Operation)),
+			attribute.String("admission.group", request.Kind.Group),
+			attribute.String("admission.kind", request.Kind.Kind),
+			attribute.String("admission.namespace", request.Namespace),
+			attribute.String("admission.name", request.Name),
+		)
 		// Valid AdmissionReview, but we can't do anything with it because we do not
 		// think the request inside is valid.
-		if !hook().Validate(request) {
+		_, validateSpan := tracing.Start(ctx, "validate")
+		valid := hook.Validate(request)
+		validateSpan.End()
+		if !valid {
 			err = fmt.Errorf("not a valid webhook request")
 			log.Error(err, "Error validaing HTTP Request Body")
-			responsehelper.SendResponse(w,
-				admissionctl.Errored(http.StatusBadRequest, err))
+			resp := admissionctl.Errored(http.StatusBadRequest, err)
+			resp.UID = request.UID
+			responsehelper.SendVersionedResponse(w, gvk, resp)
+			d.recordDecision(ctx, hook.Name(), request, resp, localmetrics.OutcomeInvalid, start, EnforceMode, nil)
 			return
 		}
 
-		// Dispatch
-		responsehelper.SendResponse(w, hook().Authorized(request))
+		// The deadline covers both waiting for capacity and the webhook itself
+		ctx, cancel := context.WithTimeout(ctx, hookTimeout(hook))
+		defer cancel()
+		release, err := d.limiter.acquire(ctx, hook)
+		if err != nil {
+			resp := shedResponse(hook, request, err)
+			responsehelper.SendVersionedResponse(w, gvk, resp)
+			d.recordDecision(ctx, hook.Name(), request, resp, localmetrics.OutcomeShed, start, EnforceMode, nil)
+			return
+		}
+
+		// Dispatch. Spans the webhook starts, such as around its API calls, are
+		// children of the authorize span.
+		authorizeCtx, authorizeSpan := tracing.Start(ctx, "authorize")
+		resp, timedOut := d.authorize(authorizeCtx, hook, request, release)
+		authorizeSpan.End()
+		// The API server rejects a response which is not for its request
+		if resp.UID == "" {
+			resp.UID = request.UID
+		}
+		resp = annotateDecision(hook.Name(), resp)
+		mode := d.enforcement.Mode(hook.Name())
+		// A break-glass override takes precedence over the enforcement mode
+		sent, override := breakglass.Apply(hook.Name(), request, resp)
+		if override == nil {
+			sent = applyEnforcementMode(mode, hook.Name(), resp)
+		}
+		responsehelper.SendVersionedResponse(w, gvk, sent)
+		decision := outcome(resp)
+		if timedOut {
+			decision = localmetrics.OutcomeTimeout
+		}
+		d.recordDecision(ctx, hook.Name(), request, resp, decision, start, mode, override)
 		return
 	}
-	log.Info("Request is not for a registered webhook.", "known_hooks", *d.hooks, "parsed_url", url, "lookup", (*d.hooks)[url.Path])
+	log.Info("Request is not for a registered webhook.", "known_hooks", d.URIs(), "parsed_url", url)
 	// Not a registered hook
 	// Note: This segment is not likely to be reached because there will not be
 	// any URI registered (handler set up) for an URI that would trigger this.
@@ -85,3 +246,78 @@ func (d *Dispatcher) HandleRequest(w http.ResponseWriter, r *http.Request) {
 		admissionctl.Errored(http.StatusBadRequest,
 			fmt.Errorf("request is not for a registered webhook")))
 }
+
+// outcome classifies a webhook's response into one of the localmetrics outcomes.
+// admissionctl.Denied (and so utils.WebhookResponse) always uses 403 Forbidden,
+// any other non-allowed response is the result of admissionctl.Errored.
+func outcome(resp admissionctl.Response) string {
+	// A panic is an error even when the fallback allows the request
+	if resp.Result != nil && resp.Result.Code == http.StatusInternalServerError {
+		return localmetrics.OutcomeErrored
+	}
+	if resp.Allowed {
+		return localmetrics.OutcomeAllowed
+	}
+	if resp.Result != nil && resp.Result.Code == http.StatusForbidden {
+		return localmetrics.OutcomeDenied
+	}
+	return localmetrics.OutcomeErrored
+}
+
+// annotateDecision adds the name of the webhook and the code of its decision
+// to the audit annotations of resp, merged with any the webhook set itself.
+// A webhook's own annotation is kept if it uses the same key.
+func annotateDecision(hookName string, resp admissionctl.Response) admissionctl.Response {
+	code := int32(http.StatusOK)
+	if resp.Result != nil && resp.Result.Code != 0 {
+		code = resp.Result.Code
+	}
+	annotations := map[string]string{
+		utils.WebhookAnnotation:      hookName,
+		utils.DecisionCodeAnnotation: strconv.Itoa(int(code)),
+	}
+	// Copy so that a map shared by the webhook between responses is not
+	// modified
+	maps.Copy(annotations, resp.AuditAnnotations)
+	resp.AuditAnnotations = annotations
+	return resp
+}
+
+// recordDecision records the decision metrics, and audit record and recording
+// if enabled, for a request handled by hookName, and adds the decision to the
+// request's span. resp and outcome are the webhook's own decision, before its
+// EnforcementMode or a break-glass override was applied. Requests sent by
+// SelfTest are only traced.
+func (d *Dispatcher) recordDecision(ctx context.Context, hookName string, request admissionctl.Request, resp admissionctl.Response, outcome string, start time.Time, mode EnforcementMode, override *breakglass.Override) {
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(attribute.String("admission.outcome", outcome), attribute.Bool("admission.allowed", resp.Allowed))
+	if outcome != localmetrics.OutcomeAllowed && outcome != localmetrics.OutcomeDenied && resp.Result != nil {
+		span.SetStatus(codes.Error, resp.Result.Message)
+	}
+	if isSelfTest(ctx) {
+		return
+	}
+	duration := time.Since(start)
+	localmetrics.ObserveWebhookDecision(
+		hookName,
+		string(request.Operation),
+		request.Kind.Group,
+		request.Kind.Kind,
+		outcome,
+		localmetrics.UserClass(request.UserInfo.Username, request.UserInfo.Groups),
+		duration,
+	)
+	if d.auditLog != nil {
+		record := auditlog.NewRecord(hookName, request, resp, outcome, duration)
+		switch {
+		case override != nil:
+			record.BreakGlassJustification = override.Justification
+		case outcome == localmetrics.OutcomeDenied && mode != EnforceMode:
+			record.EnforcementMode = string(mode)
+		}
+		d.auditLog.Log(record)
+	}
+	if d.recorder != nil {
+		d.recorder.Record(hookName, request, resp, outcome)
+	}
+}
@@ -6,18 +6,33 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	admissionv1 "k8s.io/api/admission/v1"
 	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/auditlog"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/breakglass"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/localmetrics"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/recorder"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/tracing"
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/namespace"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
 )
 
 type fakeWebhook struct{}
@@ -127,6 +142,28 @@ func TestHandleRequest_ValidRequest(t *testing.T) {
 	}
 }
 
+func TestHandleRequest_V1beta1Request(t *testing.T) {
+	d := newTestDispatcher()
+
+	body := []byte(`{"kind":"AdmissionReview","apiVersion":"admission.k8s.io/v1beta1","request":{"uid":"test-uid","kind":{"group":"","version":"v1","kind":"Namespace"},"resource":{"group":"","version":"v1","resource":"namespaces"},"operation":"CREATE"}}`)
+	req := httptest.NewRequestWithContext(context.Background(), "POST", "/test-hook", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	w := httptest.NewRecorder()
+
+	d.HandleRequest(w, req)
+
+	var review admissionv1.AdmissionReview
+	if err := json.Unmarshal(w.Body.Bytes(), &review); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if review.APIVersion != "admission.k8s.io/v1beta1" {
+		t.Errorf("expected a v1beta1 response, got %q", review.APIVersion)
+	}
+	if !review.Response.Allowed || review.Response.UID != "test-uid" {
+		t.Errorf("expected test-uid to be allowed, got %+v", review.Response)
+	}
+}
+
 func TestHandleRequest_UnknownURI(t *testing.T) {
 	d := newTestDispatcher()
 
@@ -177,3 +214,384 @@ func TestHandleRequest_ConcurrentRequests(t *testing.T) {
 	close(bw.release)
 	wg.Wait()
 }
+
+func counterValue(t *testing.T, c prometheus.Counter) float64 {
+	t.Helper()
+	m := &dto.Metric{}
+	if err := c.Write(m); err != nil {
+		t.Fatalf("failed to read counter: %v", err)
+	}
+	return m.GetCounter().GetValue()
+}
+
+type denyingWebhook struct {
+	fakeWebhook
+}
+
+func (d *denyingWebhook) Authorized(request admissionctl.Request) admissionctl.Response {
+	return admissionctl.Denied("no")
+}
+
+func TestHandleRequest_RecordsDecisionMetrics(t *testing.T) {
+	tests := []struct {
+		name    string
+		hook    webhooks.Webhook
+		body    []byte
+		outcome string
+	}{
+		{
+			name:    "allowed",
+			hook:    &fakeWebhook{},
+			body:    validAdmissionReviewBody(t),
+			outcome: localmetrics.OutcomeAllowed,
+		},
+		{
+			name:    "denied",
+			hook:    &denyingWebhook{},
+			body:    validAdmissionReviewBody(t),
+			outcome: localmetrics.OutcomeDenied,
+		},
+		{
+			name:    "invalid",
+			hook:    &fakeWebhook{},
+			body:    []byte("{}"),
+			outcome: localmetrics.OutcomeInvalid,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hook := test.hook
+			d := NewDispatcher(webhooks.RegisteredWebhooks{
+				"test-validation": func() webhooks.Webhook { return hook },
+			})
+			operation, kind := "CREATE", "Namespace"
+			if test.outcome == localmetrics.OutcomeInvalid {
+				operation, kind = "", ""
+			}
+			counter := localmetrics.MetricWebhookDecisions.WithLabelValues(
+				"test-validation", operation, "", kind, test.outcome, localmetrics.UserClassCustomer)
+			before := counterValue(t, counter)
+
+			req := httptest.NewRequestWithContext(context.Background(), "POST", "/test-hook", bytes.NewReader(test.body))
+			req.Header.Set("Content-Type", "application/json")
+			d.HandleRequest(httptest.NewRecorder(), req)
+
+			if after := counterValue(t, counter); after != before+1 {
+				t.Errorf("expected %s decision counter to increase by 1, went from %v to %v", test.outcome, before, after)
+			}
+		})
+	}
+}
+
+func TestHandleRequest_WritesAuditRecord(t *testing.T) {
+	buf := new(bytes.Buffer)
+	d := NewDispatcher(webhooks.RegisteredWebhooks{
+		"test-validation": func() webhooks.Webhook { return &denyingWebhook{} },
+	}, WithAuditLogger(auditlog.New(buf)))
+
+	req := httptest.NewRequestWithContext(context.Background(), "POST", "/test-hook", bytes.NewReader(validAdmissionReviewBody(t)))
+	req.Header.Set("Content-Type", "application/json")
+	d.HandleRequest(httptest.NewRecorder(), req)
+
+	record := auditlog.Record{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("failed to unmarshal audit record %q: %v", buf.String(), err)
+	}
+	if record.UID != "test-uid" || record.Webhook != "test-validation" || record.Kind != "Namespace" {
+		t.Errorf("unexpected request details in audit record: %+v", record)
+	}
+	if record.Decision != localmetrics.OutcomeDenied || record.Code != http.StatusForbidden || record.Reason != "no" {
+		t.Errorf("unexpected decision in audit record: %+v", record)
+	}
+}
+
+func TestHandleRequest_WritesRecording(t *testing.T) {
+	buf := new(bytes.Buffer)
+	d := NewDispatcher(webhooks.RegisteredWebhooks{
+		"test-validation": func() webhooks.Webhook { return &denyingWebhook{} },
+	}, WithEnforcementModes(EnforcementModes{"test-validation": WarnMode}), WithRecorder(recorder.New(buf, recorder.Options{SampleRatio: 1})))
+
+	req := httptest.NewRequestWithContext(context.Background(), "POST", "/test-hook", bytes.NewReader(validAdmissionReviewBody(t)))
+	req.Header.Set("Content-Type", "application/json")
+	d.HandleRequest(httptest.NewRecorder(), req)
+
+	recordings, err := recorder.Read(buf)
+	if err != nil {
+		t.Fatalf("failed to read recordings %q: %v", buf.String(), err)
+	}
+	if len(recordings) != 1 {
+		t.Fatalf("expected 1 recording, got %d", len(recordings))
+	}
+	// The webhook's own decision is recorded, not the enforcement mode's
+	if r := recordings[0]; r.Request.UID != "test-uid" || r.Response.Allowed || r.Outcome != localmetrics.OutcomeDenied {
+		t.Errorf("unexpected recording: %+v", r)
+	}
+}
+
+func TestNewDispatcher_BuildsEachHookOnce(t *testing.T) {
+	calls := 0
+	hooks := webhooks.RegisteredWebhooks{
+		"test-validation": func() webhooks.Webhook {
+			calls++
+			return &fakeWebhook{}
+		},
+	}
+	d := NewDispatcher(hooks)
+	for range 3 {
+		req := httptest.NewRequestWithContext(context.Background(), "POST", "/test-hook", bytes.NewReader(validAdmissionReviewBody(t)))
+		req.Header.Set("Content-Type", "application/json")
+		d.HandleRequest(httptest.NewRecorder(), req)
+	}
+	if uris := d.URIs(); !slices.Equal(uris, []string{"/test-hook"}) {
+		t.Errorf("expected the webhook's URI, got %v", uris)
+	}
+	if calls != 1 {
+		t.Errorf("expected the webhook factory to be called once, got %d", calls)
+	}
+}
+
+type allowlistWebhook struct {
+	fakeWebhook
+}
+
+func (a *allowlistWebhook) GetURI() string         { return "/allowlist-hook" }
+func (a *allowlistWebhook) Name() string           { return "allowlist-validation" }
+func (a *allowlistWebhook) AllowlistEnabled() bool { return true }
+
+func TestAllowlistWebhooks(t *testing.T) {
+	d := NewDispatcher(webhooks.RegisteredWebhooks{
+		"test-validation":      func() webhooks.Webhook { return &fakeWebhook{} },
+		"allowlist-validation": func() webhooks.Webhook { return &allowlistWebhook{} },
+	})
+	if names := d.AllowlistWebhooks(); !slices.Equal(names, []string{"allowlist-validation"}) {
+		t.Errorf("expected only the webhook which consults the allowlist, got %v", names)
+	}
+}
+
+func TestNewDispatcher_DuplicateURI(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic for two webhooks on the same URI")
+		}
+	}()
+	NewDispatcher(webhooks.RegisteredWebhooks{
+		"test-validation":  func() webhooks.Webhook { return &fakeWebhook{} },
+		"other-validation": func() webhooks.Webhook { return &fakeWebhook{} },
+	})
+}
+
+// perRequestWebhook builds a new webhook from factory for every method call, as
+// the dispatcher did before it cached webhook instances
+type perRequestWebhook struct {
+	webhooks.Webhook
+	factory webhooks.WebhookFactory
+}
+
+func (p *perRequestWebhook) Validate(request admissionctl.Request) bool {
+	return p.factory().Validate(request)
+}
+func (p *perRequestWebhook) Authorized(request admissionctl.Request) admissionctl.Response {
+	return p.factory().Authorized(request)
+}
+func (p *perRequestWebhook) Name() string { return p.factory().Name() }
+
+func namespaceReviewBody(b *testing.B) []byte {
+	b.Helper()
+	ar := admissionv1.AdmissionReview{
+		Request: &admissionv1.AdmissionRequest{
+			UID:       types.UID("test-uid"),
+			Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Namespace"},
+			Resource:  metav1.GroupVersionResource{Version: "v1", Resource: "namespaces"},
+			Name:      "my-project",
+			Operation: admissionv1.Create,
+			UserInfo:  authenticationv1.UserInfo{Username: "test-user", Groups: []string{"system:authenticated"}},
+			Object:    runtime.RawExtension{Raw: []byte(`{"apiVersion":"v1","kind":"Namespace","metadata":{"name":"my-project"}}`)},
+		},
+	}
+	body, err := json.Marshal(ar)
+	if err != nil {
+		b.Fatalf("failed to marshal AdmissionReview: %v", err)
+	}
+	return body
+}
+
+func benchmarkHandleRequest(b *testing.B, d *Dispatcher) {
+	body := namespaceReviewBody(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for b.Loop() {
+		req := httptest.NewRequestWithContext(context.Background(), "POST", "/namespace-validation", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		d.HandleRequest(w, req)
+		if w.Code != http.StatusOK {
+			b.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+		}
+	}
+}
+
+// BenchmarkHandleRequest measures a request to a webhook built once by the
+// dispatcher. Compare with BenchmarkHandleRequest_FactoryPerCall for the cost
+// of building the webhook, and its runtime.Scheme, on every request.
+func BenchmarkHandleRequest(b *testing.B) {
+	d := NewDispatcher(webhooks.RegisteredWebhooks{
+		namespace.WebhookName: func() webhooks.Webhook { return namespace.NewWebhook() },
+	})
+	benchmarkHandleRequest(b, d)
+}
+
+func BenchmarkHandleRequest_FactoryPerCall(b *testing.B) {
+	factory := func() webhooks.Webhook { return namespace.NewWebhook() }
+	d := NewDispatcher(webhooks.RegisteredWebhooks{
+		namespace.WebhookName: func() webhooks.Webhook {
+			return &perRequestWebhook{Webhook: factory(), factory: factory}
+		},
+	})
+	benchmarkHandleRequest(b, d)
+}
+
+func TestHandleRequest_Tracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(previous)
+	if _, err := tracing.Setup(context.Background(), tracing.Config{}); err != nil {
+		t.Fatal(err)
+	}
+	d := newTestDispatcher()
+
+	// The API server's trace
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequestWithContext(context.Background(), "POST", "/test-hook", bytes.NewReader(validAdmissionReviewBody(t)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	d.HandleRequest(httptest.NewRecorder(), req)
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	root, ok := spans["admission test-validation"]
+	if !ok {
+		t.Fatalf("expected a span for the request, got %v", spans)
+	}
+	if root.SpanContext().TraceID().String() != traceID || root.Parent().SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("expected the request span to continue the API server's trace, got trace %s parent %s", root.SpanContext().TraceID(), root.Parent().SpanID())
+	}
+	for _, name := range []string{"decode", "validate", "authorize"} {
+		span, ok := spans[name]
+		if !ok {
+			t.Errorf("expected a %s span", name)
+			continue
+		}
+		if span.Parent().SpanID() != root.SpanContext().SpanID() {
+			t.Errorf("expected the %s span to be a child of the request span", name)
+		}
+	}
+	var outcome string
+	for _, attr := range root.Attributes() {
+		if attr.Key == "admission.outcome" {
+			outcome = attr.Value.AsString()
+		}
+	}
+	if outcome != localmetrics.OutcomeAllowed {
+		t.Errorf("expected the outcome on the request span, got %q", outcome)
+	}
+}
+
+type annotatingWebhook struct {
+	fakeWebhook
+}
+
+func (a *annotatingWebhook) Authorized(request admissionctl.Request) admissionctl.Response {
+	return utils.WebhookResponse(request, false, "no", utils.WithRuleID("example"), utils.WithWarnings("deprecated"))
+}
+
+func TestHandleRequest_AnnotatesDecision(t *testing.T) {
+	d := NewDispatcher(webhooks.RegisteredWebhooks{
+		"test-validation": func() webhooks.Webhook { return &annotatingWebhook{} },
+	})
+
+	req := httptest.NewRequestWithContext(context.Background(), "POST", "/test-hook", bytes.NewReader(validAdmissionReviewBody(t)))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	d.HandleRequest(w, req)
+
+	var review admissionv1.AdmissionReview
+	if err := json.Unmarshal(w.Body.Bytes(), &review); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	expected := map[string]string{
+		"owner":                      "srep-managed-webhook",
+		utils.WebhookAnnotation:      "test-validation",
+		utils.DecisionCodeAnnotation: "403",
+		utils.RuleIDAnnotation:       "example",
+	}
+	for key, value := range expected {
+		if review.Response.AuditAnnotations[key] != value {
+			t.Errorf("expected audit annotation %s=%s, got %v", key, value, review.Response.AuditAnnotations)
+		}
+	}
+	if len(review.Response.Warnings) != 1 || review.Response.Warnings[0] != "deprecated" {
+		t.Errorf("expected the webhook's warning, got %v", review.Response.Warnings)
+	}
+}
+
+func TestHandleRequest_BreakGlassOverride(t *testing.T) {
+	defer breakglass.Set(nil)
+	c, _, err := breakglass.Parse([]byte(`
+version: v1
+overrides:
+- webhook: test-validation
+  name: openshift-foo
+  justification: INC-123
+  expires: "` + time.Now().Add(time.Hour).UTC().Format(time.RFC3339) + `"
+`))
+	if err != nil || len(c.Overrides) != 1 {
+		t.Fatalf("failed to parse break-glass configuration: %v", err)
+	}
+	breakglass.Set(c)
+
+	buf := new(bytes.Buffer)
+	d := NewDispatcher(webhooks.RegisteredWebhooks{
+		"test-validation": func() webhooks.Webhook { return &denyingWebhook{} },
+	}, WithAuditLogger(auditlog.New(buf)))
+	counter := localmetrics.MetricBreakGlassOverrides.WithLabelValues("test-validation")
+	before := counterValue(t, counter)
+
+	ar := admissionv1.AdmissionReview{}
+	if err := json.Unmarshal(validAdmissionReviewBody(t), &ar); err != nil {
+		t.Fatal(err)
+	}
+	ar.Request.Name = "openshift-foo"
+	body, err := json.Marshal(ar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequestWithContext(context.Background(), "POST", "/test-hook", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	d.HandleRequest(w, req)
+
+	var review admissionv1.AdmissionReview
+	if err := json.Unmarshal(w.Body.Bytes(), &review); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if !review.Response.Allowed {
+		t.Errorf("expected the override to allow the request, got %+v", review.Response)
+	}
+	if review.Response.AuditAnnotations[breakglass.JustificationAnnotation] != "INC-123" {
+		t.Errorf("expected the justification audit annotation, got %v", review.Response.AuditAnnotations)
+	}
+	record := auditlog.Record{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("failed to unmarshal audit record %q: %v", buf.String(), err)
+	}
+	// The webhook's own decision is audited along with the override
+	if record.Decision != localmetrics.OutcomeDenied || record.BreakGlassJustification != "INC-123" {
+		t.Errorf("unexpected audit record: %+v", record)
+	}
+	if got := counterValue(t, counter) - before; got != 1 {
+		t.Errorf("expected the override to be counted once, got %v", got)
+	}
+}
@@ -0,0 +1,121 @@
+package dispatcher
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/localmetrics"
+)
+
+// EnforcementMode controls what the dispatcher does with a webhook's denial
+type EnforcementMode string
+
+const (
+	// EnforceMode sends the webhook's denial to the API server unchanged
+	EnforceMode EnforcementMode = "enforce"
+	// WarnMode allows the request, returning the denial reason as an admission
+	// warning and an audit annotation
+	WarnMode EnforcementMode = "warn"
+	// AuditMode allows the request, recording the denial reason only as an
+	// audit annotation
+	AuditMode EnforcementMode = "audit"
+
+	// enforcementModeAnnotation and wouldDenyAnnotation are the audit annotation
+	// keys used when a denial is not enforced. The API server prefixes them
+	// with the webhook's name.
+	enforcementModeAnnotation = "enforcement-mode"
+	wouldDenyAnnotation       = "would-deny-reason"
+)
+
+// EnforcementModes maps webhook names to their EnforcementMode. Webhooks
+// which are not present are enforced.
+type EnforcementModes map[string]EnforcementMode
+
+// Mode returns the EnforcementMode for the named webhook
+func (m EnforcementModes) Mode(hookName string) EnforcementMode {
+	if mode, ok := m[hookName]; ok {
+		return mode
+	}
+	return EnforceMode
+}
+
+// Validate ensures every mode is known and every webhook name is one of known
+func (m EnforcementModes) Validate(known []string) error {
+	for name, mode := range m {
+		switch mode {
+		case EnforceMode, WarnMode, AuditMode:
+		default:
+			return fmt.Errorf("unknown enforcement mode %q for webhook %s, must be one of %s, %s or %s", mode, name, EnforceMode, WarnMode, AuditMode)
+		}
+		if !slices.Contains(known, name) {
+			return fmt.Errorf("enforcement mode set for unknown webhook %s", name)
+		}
+	}
+	return nil
+}
+
+// ParseEnforcementModes parses a comma-separated list of webhook=mode pairs,
+// for example "regular-user-validation=warn,scc-validation=audit"
+func ParseEnforcementModes(s string) (EnforcementModes, error) {
+	modes := EnforcementModes{}
+	if strings.TrimSpace(s) == "" {
+		return modes, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		name, mode, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("couldn't parse enforcement mode %q, expected webhook=mode", pair)
+		}
+		modes[name] = EnforcementMode(mode)
+	}
+	return modes, nil
+}
+
+// LoadEnforcementModes reads a YAML or JSON file mapping webhook names to modes
+func LoadEnforcementModes(path string) (EnforcementModes, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	modes := EnforcementModes{}
+	if err := yaml.Unmarshal(b, &modes); err != nil {
+		return nil, fmt.Errorf("couldn't parse enforcement modes from %s: %w", path, err)
+	}
+	return modes, nil
+}
+
+// applyEnforcementMode turns a denial into an allowed response when the
+// webhook is not in EnforceMode. Any other response is returned unchanged.
+func applyEnforcementMode(mode EnforcementMode, hookName string, resp admissionctl.Response) admissionctl.Response {
+	if mode == EnforceMode || outcome(resp) != localmetrics.OutcomeDenied {
+		return resp
+	}
+	reason := ""
+	if resp.Result != nil {
+		reason = resp.Result.Message
+	}
+	log.Info("Not enforcing denial", "webhookName", hookName, "mode", mode, "reason", reason, "uid", resp.UID)
+	localmetrics.IncrementUnenforcedDenial(hookName, string(mode))
+
+	ret := admissionctl.Allowed("")
+	ret.UID = resp.UID
+	// Keep the webhook's own audit annotations, such as the rule which denied
+	// the request
+	ret.AuditAnnotations = maps.Clone(resp.AuditAnnotations)
+	if ret.AuditAnnotations == nil {
+		ret.AuditAnnotations = map[string]string{}
+	}
+	ret.AuditAnnotations[enforcementModeAnnotation] = string(mode)
+	ret.AuditAnnotations[wouldDenyAnnotation] = reason
+	if mode == WarnMode {
+		ret.Warnings = append(ret.Warnings, resp.Warnings...)
+		ret.Warnings = append(ret.Warnings, fmt.Sprintf("%s would deny this request: %s", hookName, reason))
+	}
+	return ret
+}
@@ -0,0 +1,139 @@
+package dispatcher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+func TestParseEnforcementModes(t *testing.T) {
+	modes, err := ParseEnforcementModes("a=warn, b=audit,c=enforce")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if modes.Mode("a") != WarnMode || modes.Mode("b") != AuditMode || modes.Mode("c") != EnforceMode {
+		t.Errorf("unexpected modes parsed: %v", modes)
+	}
+	if modes.Mode("unlisted") != EnforceMode {
+		t.Errorf("expected unlisted webhooks to be enforced")
+	}
+
+	if _, err := ParseEnforcementModes("a"); err == nil {
+		t.Errorf("expected an error parsing a pair without a mode")
+	}
+	if modes, err := ParseEnforcementModes(""); err != nil || len(modes) != 0 {
+		t.Errorf("expected no modes and no error for an empty string, got %v, %v", modes, err)
+	}
+}
+
+func TestLoadEnforcementModes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "modes.yaml")
+	if err := os.WriteFile(path, []byte("regular-user-validation: warn\nscc-validation: audit\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	modes, err := LoadEnforcementModes(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if modes.Mode("regular-user-validation") != WarnMode || modes.Mode("scc-validation") != AuditMode {
+		t.Errorf("unexpected modes loaded: %v", modes)
+	}
+}
+
+func TestEnforcementModesValidate(t *testing.T) {
+	known := []string{"a", "b"}
+	if err := (EnforcementModes{"a": WarnMode}).Validate(known); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if err := (EnforcementModes{"a": "block"}).Validate(known); err == nil {
+		t.Errorf("expected an error for an unknown mode")
+	}
+	if err := (EnforcementModes{"z": WarnMode}).Validate(known); err == nil {
+		t.Errorf("expected an error for an unknown webhook")
+	}
+}
+
+func TestApplyEnforcementMode(t *testing.T) {
+	denied := admissionctl.Denied("not allowed")
+	denied.UID = "test-uid"
+
+	tests := []struct {
+		mode            EnforcementMode
+		resp            admissionctl.Response
+		expectedAllowed bool
+		expectWarning   bool
+		expectAnnotated bool
+	}{
+		{mode: EnforceMode, resp: denied, expectedAllowed: false},
+		{mode: WarnMode, resp: denied, expectedAllowed: true, expectWarning: true, expectAnnotated: true},
+		{mode: AuditMode, resp: denied, expectedAllowed: true, expectAnnotated: true},
+		{mode: WarnMode, resp: admissionctl.Errored(500, os.ErrClosed), expectedAllowed: false},
+		{mode: AuditMode, resp: admissionctl.Allowed(""), expectedAllowed: true},
+	}
+	for _, test := range tests {
+		ret := applyEnforcementMode(test.mode, "test-validation", test.resp)
+		if ret.Allowed != test.expectedAllowed {
+			t.Errorf("%s: expected allowed to be %v, got %v", test.mode, test.expectedAllowed, ret.Allowed)
+		}
+		if test.expectWarning != (len(ret.Warnings) == 1 && strings.Contains(ret.Warnings[0], "not allowed")) {
+			t.Errorf("%s: unexpected warnings %v", test.mode, ret.Warnings)
+		}
+		if test.expectAnnotated {
+			if ret.AuditAnnotations[wouldDenyAnnotation] != "not allowed" || ret.AuditAnnotations[enforcementModeAnnotation] != string(test.mode) {
+				t.Errorf("%s: unexpected audit annotations %v", test.mode, ret.AuditAnnotations)
+			}
+			if ret.UID != "test-uid" {
+				t.Errorf("%s: expected the UID to be preserved, got %s", test.mode, ret.UID)
+			}
+		}
+	}
+}
+
+func TestHandleRequest_WarnMode(t *testing.T) {
+	d := NewDispatcher(webhooks.RegisteredWebhooks{
+		"test-validation": func() webhooks.Webhook { return &denyingWebhook{} },
+	}, WithEnforcementModes(EnforcementModes{"test-validation": WarnMode}))
+
+	req := httptest.NewRequestWithContext(context.Background(), "POST", "/test-hook", bytes.NewReader(validAdmissionReviewBody(t)))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	d.HandleRequest(w, req)
+
+	var review admissionv1.AdmissionReview
+	if err := json.Unmarshal(w.Body.Bytes(), &review); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if !review.Response.Allowed {
+		t.Error("expected request to be allowed in warn mode")
+	}
+	if len(review.Response.Warnings) != 1 {
+		t.Errorf("expected one warning, got %v", review.Response.Warnings)
+	}
+	if review.Response.AuditAnnotations["owner"] != "srep-managed-webhook" || review.Response.AuditAnnotations[enforcementModeAnnotation] != "warn" {
+		t.Errorf("expected both owner and enforcement mode audit annotations, got %v", review.Response.AuditAnnotations)
+	}
+}
+
+func TestApplyEnforcementMode_KeepsWebhookAnnotations(t *testing.T) {
+	denied := admissionctl.Denied("not allowed")
+	denied.AuditAnnotations = map[string]string{utils.RuleIDAnnotation: "example"}
+
+	ret := applyEnforcementMode(AuditMode, "test-validation", denied)
+	if ret.AuditAnnotations[utils.RuleIDAnnotation] != "example" || ret.AuditAnnotations[enforcementModeAnnotation] != "audit" {
+		t.Errorf("expected the webhook's and the enforcement audit annotations, got %v", ret.AuditAnnotations)
+	}
+	if _, ok := denied.AuditAnnotations[enforcementModeAnnotation]; ok {
+		t.Errorf("expected the webhook's annotations not to be modified, got %v", denied.AuditAnnotations)
+	}
+}
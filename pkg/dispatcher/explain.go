@@ -0,0 +1,109 @@
+package dispatcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/breakglass"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/explain"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/localmetrics"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+// Explanation is the response of the explain endpoint: how a webhook decided
+// on an AdmissionReview and what the API server would have been sent
+type Explanation struct {
+	Webhook string `json:"webhook"`
+	// Allowed is the decision the API server would have been sent, after the
+	// webhook's enforcement mode or a break-glass override was applied
+	Allowed bool `json:"allowed"`
+	// Response is the webhook's own decision
+	Response admissionv1.AdmissionResponse `json:"response"`
+	// EnforcementMode is the webhook's enforcement mode
+	EnforcementMode EnforcementMode `json:"enforcementMode"`
+	// BreakGlassJustification is the justification of the break-glass
+	// override which would have allowed the request, if any
+	BreakGlassJustification string `json:"breakGlassJustification,omitempty"`
+	// Trace is every check evaluated, in order. Webhooks record their own
+	// checks when they implement webhooks.ContextWebhook, as all of ours do.
+	Trace []explain.Check `json:"trace"`
+}
+
+// Explain handles POSTs of an AdmissionReview to explain.PathPrefix followed
+// by a webhook's name. The webhook decides on the request as it would for the
+// API server, and the response is an Explanation of the decision. Nothing is
+// recorded in the decision metrics, audit log or recording, and the request
+// does not wait for or use the webhook's concurrency capacity.
+func (d *Dispatcher) Explain(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "an AdmissionReview must be POSTed", http.StatusMethodNotAllowed)
+		return
+	}
+	name := strings.TrimPrefix(r.URL.Path, explain.PathPrefix)
+	hook := d.hookNamed(name)
+	if hook == nil {
+		http.Error(w, fmt.Sprintf("webhook %q is not registered", name), http.StatusNotFound)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+	request, _, _, err := utils.ParseHTTPRequestVersion(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("couldn't parse the AdmissionReview: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	trace := explain.New()
+	explanation := Explanation{
+		Webhook:         hook.Name(),
+		EnforcementMode: d.enforcement.Mode(hook.Name()),
+	}
+	if trace.Record(hook.Validate(request), "request is valid") {
+		ctx, cancel := context.WithTimeout(explain.NewContext(r.Context(), trace), hookTimeout(hook))
+		defer cancel()
+		resp, _ := d.authorize(ctx, hook, request, func() {})
+		if resp.UID == "" {
+			resp.UID = request.UID
+		}
+		resp = annotateDecision(hook.Name(), resp)
+		explanation.Response = resp.AdmissionResponse
+		explanation.Allowed = resp.Allowed
+		if outcome(resp) == localmetrics.OutcomeDenied {
+			// As in HandleRequest, without logging or counting the override or
+			// unenforced denial
+			if o, ok := breakglass.Current().Match(hook.Name(), request); trace.Record(ok, "break-glass override covers the request") {
+				explanation.BreakGlassJustification = o.Justification
+				explanation.Allowed = true
+			} else if !trace.Recordf(explanation.EnforcementMode == EnforceMode, "enforcement mode is %s", EnforceMode) {
+				explanation.Allowed = true
+			}
+		}
+	} else {
+		resp := admissionctl.Errored(http.StatusBadRequest, fmt.Errorf("not a valid webhook request"))
+		resp.UID = request.UID
+		explanation.Response = resp.AdmissionResponse
+	}
+	explanation.Trace = trace.Checks()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(explanation); err != nil {
+		log.Error(err, "Couldn't send the explanation", "webhookName", hook.Name())
+	}
+}
+
+// hookNamed returns the webhook called name, or nil if there is none
+func (d *Dispatcher) hookNamed(name string) webhooks.Webhook {
+	for _, hook := range d.hooks {
+		if hook.Name() == name {
+			return hook
+		}
+	}
+	return nil
+}
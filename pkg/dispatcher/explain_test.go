@@ -0,0 +1,158 @@
+package dispatcher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/explain"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/localmetrics"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/namespace"
+)
+
+func namespaceExplainBody(t *testing.T, username string) []byte {
+	t.Helper()
+	ar := admissionv1.AdmissionReview{
+		Request: &admissionv1.AdmissionRequest{
+			UID:       "explain-uid",
+			Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Namespace"},
+			Resource:  metav1.GroupVersionResource{Version: "v1", Resource: "namespaces"},
+			Name:      "com",
+			Operation: admissionv1.Create,
+			UserInfo:  authenticationv1.UserInfo{Username: username, Groups: []string{"dedicated-admins"}},
+			Object:    runtime.RawExtension{Raw: []byte(`{"apiVersion":"v1","kind":"Namespace","metadata":{"name":"com"}}`)},
+		},
+	}
+	b, err := json.Marshal(ar)
+	if err != nil {
+		t.Fatalf("failed to marshal AdmissionReview: %v", err)
+	}
+	return b
+}
+
+func sendExplain(t *testing.T, d *Dispatcher, method, path string, body []byte) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequestWithContext(context.Background(), method, path, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	d.Explain(w, req)
+	return w
+}
+
+func TestExplain(t *testing.T) {
+	tests := []struct {
+		name     string
+		modes    EnforcementModes
+		username string
+		allowed  bool
+		// checks must appear in the trace in this order
+		checks []explain.Check
+	}{
+		{
+			name:     "denied",
+			username: "customer",
+			checks: []explain.Check{
+				{Check: "request is valid", Matched: true},
+				{Check: `admins: user in ["kube:admin" "system:admin" "backplane-cluster-admin"]`},
+				{Check: `admins: group in ["cluster-admins" "system:serviceaccounts:openshift-backplane-srep"]`},
+				{Check: "namespace matches (^com$|^io$|^in$)", Matched: true},
+				{Check: "break-glass override covers the request"},
+				{Check: "enforcement mode is enforce", Matched: true},
+			},
+		},
+		{
+			name:     "unenforced",
+			modes:    EnforcementModes{namespace.WebhookName: AuditMode},
+			username: "customer",
+			allowed:  true,
+			checks: []explain.Check{
+				{Check: "namespace matches (^com$|^io$|^in$)", Matched: true},
+				{Check: "enforcement mode is enforce"},
+			},
+		},
+		{
+			name:     "admin",
+			username: "backplane-cluster-admin",
+			allowed:  true,
+			checks: []explain.Check{
+				{Check: `admins: user in ["kube:admin" "system:admin" "backplane-cluster-admin"]`, Matched: true},
+			},
+		},
+		{
+			name:     "invalid",
+			username: "",
+			checks: []explain.Check{
+				{Check: "request is valid"},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d := NewDispatcher(webhooks.RegisteredWebhooks{
+				namespace.WebhookName: func() webhooks.Webhook { return namespace.NewWebhook() },
+			}, WithEnforcementModes(test.modes))
+			counter := localmetrics.MetricWebhookDecisions.WithLabelValues(
+				namespace.WebhookName, "CREATE", "", "Namespace", localmetrics.OutcomeDenied, localmetrics.UserClassCustomer)
+			before := counterValue(t, counter)
+
+			w := sendExplain(t, d, http.MethodPost, explain.PathPrefix+namespace.WebhookName, namespaceExplainBody(t, test.username))
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body)
+			}
+			var explanation Explanation
+			if err := json.Unmarshal(w.Body.Bytes(), &explanation); err != nil {
+				t.Fatalf("failed to unmarshal explanation: %v", err)
+			}
+			if explanation.Allowed != test.allowed {
+				t.Errorf("expected allowed %v, got %+v", test.allowed, explanation)
+			}
+			if explanation.Response.UID != "explain-uid" {
+				t.Errorf("expected the request's UID, got %q", explanation.Response.UID)
+			}
+			next := 0
+			for _, check := range explanation.Trace {
+				if next < len(test.checks) && check == test.checks[next] {
+					next++
+				}
+			}
+			if next < len(test.checks) {
+				t.Errorf("expected %s in the trace, got %v", test.checks[next], explanation.Trace)
+			}
+			if after := counterValue(t, counter); after != before {
+				t.Errorf("expected explained requests not to be counted, went from %v to %v", before, after)
+			}
+		})
+	}
+}
+
+func TestExplain_BadRequests(t *testing.T) {
+	d := newTestDispatcher()
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   []byte
+		code   int
+	}{
+		{name: "unknown webhook", method: http.MethodPost, path: explain.PathPrefix + "unknown-validation", body: validAdmissionReviewBody(t), code: http.StatusNotFound},
+		{name: "not a POST", method: http.MethodGet, path: explain.PathPrefix + "test-validation", code: http.StatusMethodNotAllowed},
+		{name: "not an AdmissionReview", method: http.MethodPost, path: explain.PathPrefix + "test-validation", body: []byte("{"), code: http.StatusBadRequest},
+		{name: "valid", method: http.MethodPost, path: explain.PathPrefix + "test-validation", body: validAdmissionReviewBody(t), code: http.StatusOK},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if w := sendExplain(t, d, test.method, test.path, test.body); w.Code != test.code {
+				t.Errorf("expected status %d, got %d: %s", test.code, w.Code, w.Body)
+			}
+		})
+	}
+}
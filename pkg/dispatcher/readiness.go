@@ -0,0 +1,158 @@
+package dispatcher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/localmetrics"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks"
+)
+
+// selfTestUser is the username in self-test requests
+const selfTestUser = "system:serviceaccount:openshift-validation-webhook:self-test"
+
+type selfTestKey struct{}
+
+// isSelfTest returns true if ctx belongs to a request sent by SelfTest
+func isSelfTest(ctx context.Context) bool {
+	selfTest, _ := ctx.Value(selfTestKey{}).(bool)
+	return selfTest
+}
+
+// SelfTest sends a canned AdmissionReview for each webhook through
+// HandleRequest. It checks the whole request path, not the decision: each
+// webhook must answer with a well-formed AdmissionReview for the same UID
+// which either allows or denies the request, so a request the webhook
+// considers invalid fails as an internal error does. Self-test requests are
+// not recorded in the decision metrics or audit log.
+func (d *Dispatcher) SelfTest(ctx context.Context) error {
+	ctx = context.WithValue(ctx, selfTestKey{}, true)
+	var errs []error
+	for _, uri := range d.URIs() {
+		if err := d.selfTest(ctx, d.hooks[uri]); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", d.hooks[uri].Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (d *Dispatcher) selfTest(ctx context.Context, hook webhooks.Webhook) error {
+	uid := types.UID("self-test-" + hook.Name())
+	body, err := json.Marshal(selfTestReview(hook, uid))
+	if err != nil {
+		return err
+	}
+	req := httptest.NewRequestWithContext(ctx, http.MethodPost, hook.GetURI(), bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	d.HandleRequest(w, req)
+
+	if w.Code != http.StatusOK {
+		return fmt.Errorf("unexpected HTTP status %d", w.Code)
+	}
+	review := admissionv1.AdmissionReview{}
+	if err := json.Unmarshal(w.Body.Bytes(), &review); err != nil {
+		return fmt.Errorf("malformed AdmissionReview in response: %w", err)
+	}
+	if review.Response == nil || review.Response.UID != uid {
+		return fmt.Errorf("response is not for request %s", uid)
+	}
+	resp := admissionctl.Response{AdmissionResponse: *review.Response}
+	if outcome(resp) == localmetrics.OutcomeErrored {
+		if resp.Result == nil {
+			return fmt.Errorf("request neither allowed nor denied")
+		}
+		return fmt.Errorf("errored with code %d: %s", resp.Result.Code, resp.Result.Message)
+	}
+	return nil
+}
+
+// selfTestReview builds an AdmissionReview for the first resource hook's rules
+// match, and the kind the hook asks for, on an object which exists only for the
+// test
+func selfTestReview(hook webhooks.Webhook, uid types.UID) admissionv1.AdmissionReview {
+	request := &admissionv1.AdmissionRequest{
+		UID:       uid,
+		Name:      "self-test",
+		Operation: admissionv1.Create,
+		UserInfo: authenticationv1.UserInfo{
+			Username: selfTestUser,
+			Groups:   []string{"system:serviceaccounts", "system:authenticated"},
+		},
+	}
+	if rules := hook.Rules(); len(rules) > 0 {
+		rule := rules[0]
+		request.Resource = metav1.GroupVersionResource{
+			Group:    first(rule.APIGroups),
+			Version:  first(rule.APIVersions),
+			Resource: first(rule.Resources),
+		}
+		if op := first(rule.Operations); op != "" && op != admissionregv1.OperationAll {
+			request.Operation = admissionv1.Operation(op)
+		}
+	}
+	if selfTestHook, ok := hook.(webhooks.SelfTestWebhook); ok {
+		kind := selfTestHook.SelfTestKind()
+		request.Kind = metav1.GroupVersionKind{
+			Group:   kind.Group,
+			Version: request.Resource.Version,
+			Kind:    kind.Kind,
+		}
+		request.RequestKind = &request.Kind
+	}
+	request.Object = runtime.RawExtension{
+		Raw: []byte(`{"metadata":{"name":"self-test"}}`),
+	}
+	switch request.Operation {
+	case admissionv1.Update:
+		request.OldObject = request.Object
+	case admissionv1.Delete:
+		request.OldObject, request.Object = request.Object, runtime.RawExtension{}
+	}
+	return admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: admissionv1.SchemeGroupVersion.String(),
+			Kind:       "AdmissionReview",
+		},
+		Request: request,
+	}
+}
+
+// first returns the first item of a rule's list, or the zero value if the list
+// is empty or a wildcard
+func first[T ~string](items []T) T {
+	var zero T
+	if len(items) == 0 || items[0] == "*" {
+		return zero
+	}
+	return items[0]
+}
+
+// CheckClusterAccess returns an error describing each webhook which needs to
+// reach the API server but cannot
+func (d *Dispatcher) CheckClusterAccess(ctx context.Context) error {
+	var errs []error
+	for _, uri := range d.URIs() {
+		hook, ok := d.hooks[uri].(webhooks.ClusterAccessWebhook)
+		if !ok {
+			continue
+		}
+		if err := hook.CheckClusterAccess(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", hook.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
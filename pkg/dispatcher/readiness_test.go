@@ -0,0 +1,100 @@
+package dispatcher
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/localmetrics"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks"
+)
+
+type clusterAccessWebhook struct {
+	fakeWebhook
+	err error
+}
+
+func (c *clusterAccessWebhook) CheckClusterAccess(_ context.Context) error { return c.err }
+
+func TestSelfTest(t *testing.T) {
+	d := newTestDispatcher()
+	decisions := localmetrics.MetricWebhookDecisions.WithLabelValues("test-validation", "CREATE", "", "", localmetrics.OutcomeAllowed, localmetrics.UserClassServiceAccount)
+	before := counterValue(t, decisions)
+	if err := d.SelfTest(context.Background()); err != nil {
+		t.Errorf("expected the self-test to pass, got %v", err)
+	}
+	if got := counterValue(t, decisions) - before; got != 0 {
+		t.Errorf("expected self-test requests to not be recorded, got %v", got)
+	}
+
+	d = NewDispatcher(webhooks.RegisteredWebhooks{
+		"test-validation": func() webhooks.Webhook { return &panickingWebhook{} },
+	})
+	err := d.SelfTest(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "test-validation") {
+		t.Errorf("expected the panicking webhook to fail the self-test, got %v", err)
+	}
+}
+
+// kindWebhook only accepts requests for Nodes, as most webhooks only accept
+// the kinds they protect
+type kindWebhook struct {
+	fakeWebhook
+	selfTestKind metav1.GroupKind
+	authorized   bool
+}
+
+func (k *kindWebhook) Validate(request admissionctl.Request) bool {
+	return request.Kind.Kind == "Node"
+}
+func (k *kindWebhook) Authorized(_ admissionctl.Request) admissionctl.Response {
+	k.authorized = true
+	return admissionctl.Denied("no")
+}
+func (k *kindWebhook) SelfTestKind() metav1.GroupKind { return k.selfTestKind }
+
+func TestSelfTestKind(t *testing.T) {
+	hook := &kindWebhook{selfTestKind: metav1.GroupKind{Kind: "Node"}}
+	d := NewDispatcher(webhooks.RegisteredWebhooks{
+		"test-validation": func() webhooks.Webhook { return hook },
+	})
+	if err := d.SelfTest(context.Background()); err != nil {
+		t.Errorf("expected a denial to pass the self-test, got %v", err)
+	}
+	if !hook.authorized {
+		t.Error("expected the self-test request for the webhook's kind to reach Authorized")
+	}
+
+	hook = &kindWebhook{selfTestKind: metav1.GroupKind{Kind: "Pod"}}
+	d = NewDispatcher(webhooks.RegisteredWebhooks{
+		"test-validation": func() webhooks.Webhook { return hook },
+	})
+	err := d.SelfTest(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "not a valid webhook request") {
+		t.Errorf("expected a request the webhook considers invalid to fail the self-test, got %v", err)
+	}
+	if hook.authorized {
+		t.Error("expected an invalid request to not reach Authorized")
+	}
+}
+
+func TestCheckClusterAccess(t *testing.T) {
+	hook := &clusterAccessWebhook{}
+	d := NewDispatcher(webhooks.RegisteredWebhooks{
+		"test-validation": func() webhooks.Webhook { return hook },
+	})
+	if err := d.CheckClusterAccess(context.Background()); err != nil {
+		t.Errorf("expected cluster access, got %v", err)
+	}
+	hook.err = errors.New("connection refused")
+	if err := d.CheckClusterAccess(context.Background()); err == nil {
+		t.Error("expected an error when the webhook cannot reach the API server")
+	}
+	if err := newTestDispatcher().CheckClusterAccess(context.Background()); err != nil {
+		t.Errorf("expected webhooks without cluster access needs to be skipped, got %v", err)
+	}
+}
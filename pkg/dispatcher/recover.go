@@ -0,0 +1,38 @@
+package dispatcher
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/localmetrics"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks"
+)
+
+// panicResponse logs and counts a panic recovered from hook while handling
+// request, and returns the timeout fallback decision for the request, as the
+// API server would have failed open or closed had the webhook crashed. The
+// response keeps a 500 code even when it allows, so that the panic is still
+// counted as an error and fails the self-test. The panic's value is logged
+// but not returned, as it may describe internals of the webhook.
+func (d *Dispatcher) panicResponse(hook webhooks.Webhook, request admissionctl.Request, recovered any) admissionctl.Response {
+	localmetrics.IncrementWebhookPanic(hook.Name())
+	log.Error(fmt.Errorf("panic: %v", recovered), "Recovered from a panic while handling an admission request",
+		"webhookName", hook.Name(),
+		"uid", request.UID,
+		"stack", string(debug.Stack()),
+	)
+	err := fmt.Errorf("webhook %s encountered an internal error", hook.Name())
+	var resp admissionctl.Response
+	if d.timeoutFallback.allows(hook) {
+		resp = admissionctl.Allowed(err.Error())
+		resp.Result.Code = http.StatusInternalServerError
+		resp.Warnings = []string{err.Error()}
+	} else {
+		resp = admissionctl.Errored(http.StatusInternalServerError, err)
+	}
+	resp.UID = request.UID
+	return resp
+}
@@ -0,0 +1,71 @@
+package dispatcher
+
+import (
+	"net/http"
+	"testing"
+
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/localmetrics"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks"
+)
+
+type panickingWebhook struct {
+	fakeWebhook
+	inValidate bool
+}
+
+func (p *panickingWebhook) Validate(_ admissionctl.Request) bool {
+	if p.inValidate {
+		var m map[string]string
+		m["boom"] = "boom"
+	}
+	return true
+}
+
+func (p *panickingWebhook) Authorized(_ admissionctl.Request) admissionctl.Response {
+	var resp *admissionctl.Response
+	return *resp
+}
+
+func TestHandleRequest_RecoversPanics(t *testing.T) {
+	tests := []struct {
+		name       string
+		inValidate bool
+		fallback   TimeoutFallback
+		// wantAllowed is whether the request is allowed: the test webhook's
+		// FailurePolicy is Ignore
+		wantAllowed bool
+	}{
+		{name: "panic in Authorized", fallback: FailurePolicyFallback, wantAllowed: true},
+		{name: "panic in Authorized with deny fallback", fallback: DenyFallback},
+		{name: "panic in Validate", inValidate: true, fallback: FailurePolicyFallback, wantAllowed: true},
+		{name: "panic in Validate with deny fallback", inValidate: true, fallback: DenyFallback},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d := NewDispatcher(webhooks.RegisteredWebhooks{
+				"test-validation": func() webhooks.Webhook { return &panickingWebhook{inValidate: test.inValidate} },
+			}, WithTimeoutFallback(test.fallback))
+			panics := localmetrics.MetricWebhookPanics.WithLabelValues("test-validation")
+			before := counterValue(t, panics)
+
+			review := sendReview(t, d)
+			if review.Response.Allowed != test.wantAllowed {
+				t.Errorf("expected allowed %v, got %v", test.wantAllowed, review.Response.Allowed)
+			}
+			if test.wantAllowed && len(review.Response.Warnings) == 0 {
+				t.Error("expected a warning when the request is allowed")
+			}
+			if review.Response.UID != "test-uid" {
+				t.Errorf("expected UID test-uid, got %q", review.Response.UID)
+			}
+			if review.Response.Result.Code != http.StatusInternalServerError {
+				t.Errorf("expected code %d, got %d", http.StatusInternalServerError, review.Response.Result.Code)
+			}
+			if got := counterValue(t, panics) - before; got != 1 {
+				t.Errorf("expected the panic metric to increase by 1, got %v", got)
+			}
+		})
+	}
+}
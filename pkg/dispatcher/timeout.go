@@ -0,0 +1,113 @@
+package dispatcher
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/localmetrics"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks"
+)
+
+// TimeoutFallback is the decision sent when a webhook does not reach one of
+// its own before its deadline, or panics
+type TimeoutFallback string
+
+const (
+	// FailurePolicyFallback allows the request if the webhook's FailurePolicy
+	// is Ignore and rejects it otherwise, as the API server would have done
+	// had it timed out the call itself
+	FailurePolicyFallback TimeoutFallback = "failure-policy"
+	// AllowFallback always allows the request
+	AllowFallback TimeoutFallback = "allow"
+	// DenyFallback always rejects the request
+	DenyFallback TimeoutFallback = "deny"
+
+	// timeoutMargin is how long before the API server's own timeout the
+	// dispatcher gives up on a webhook, leaving time to send the response
+	timeoutMargin = 250 * time.Millisecond
+	// defaultTimeout is the API server's timeout for a webhook which does not
+	// set TimeoutSeconds
+	defaultTimeout = 10 * time.Second
+)
+
+// ParseTimeoutFallback parses a TimeoutFallback, where empty means
+// FailurePolicyFallback
+func ParseTimeoutFallback(s string) (TimeoutFallback, error) {
+	switch fallback := TimeoutFallback(s); fallback {
+	case "":
+		return FailurePolicyFallback, nil
+	case FailurePolicyFallback, AllowFallback, DenyFallback:
+		return fallback, nil
+	default:
+		return "", fmt.Errorf("unknown timeout fallback %q, must be one of %s, %s or %s", s, FailurePolicyFallback, AllowFallback, DenyFallback)
+	}
+}
+
+// allows returns true if the fallback decision for hook is to allow
+func (f TimeoutFallback) allows(hook webhooks.Webhook) bool {
+	switch f {
+	case AllowFallback:
+		return true
+	case DenyFallback:
+		return false
+	default:
+		return hook.FailurePolicy() == admissionregv1.Ignore
+	}
+}
+
+// hookTimeout is how long the dispatcher waits for hook to reach a decision:
+// slightly less than the API server will wait for the dispatcher
+func hookTimeout(hook webhooks.Webhook) time.Duration {
+	timeout := time.Duration(hook.TimeoutSeconds()) * time.Second
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	return timeout - timeoutMargin
+}
+
+// authorize asks hook for its decision, giving up when ctx, which should carry
+// the hook's deadline, is done. If the hook gives up, timedOut is true and resp
+// is the fallback decision. Only a ContextWebhook is told to stop; any other
+// hook is left to finish in the background and its decision discarded. release
+// is called once the hook has returned, so that a hook which is still running
+// keeps using its concurrency capacity.
+func (d *Dispatcher) authorize(ctx context.Context, hook webhooks.Webhook, request admissionctl.Request, release func()) (resp admissionctl.Response, timedOut bool) {
+	result := make(chan admissionctl.Response, 1)
+	go func() {
+		defer release()
+		// This runs outside of HandleRequest's recover
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				result <- d.panicResponse(hook, request, recovered)
+			}
+		}()
+		if ctxHook, ok := hook.(webhooks.ContextWebhook); ok {
+			result <- ctxHook.AuthorizedWithContext(ctx, request)
+		} else {
+			result <- hook.Authorized(request)
+		}
+	}()
+
+	select {
+	case resp = <-result:
+		return resp, false
+	case <-ctx.Done():
+	}
+
+	localmetrics.IncrementWebhookTimeout(hook.Name())
+	err := fmt.Errorf("%s did not reach a decision within %s: %w", hook.Name(), hookTimeout(hook), ctx.Err())
+	log.Error(err, "Webhook timed out, sending the fallback decision", "webhookName", hook.Name(), "uid", request.UID, "fallback", d.timeoutFallback)
+	if d.timeoutFallback.allows(hook) {
+		resp = admissionctl.Allowed(err.Error())
+		resp.Warnings = []string{err.Error()}
+	} else {
+		resp = admissionctl.Errored(http.StatusGatewayTimeout, err)
+	}
+	resp.UID = request.UID
+	return resp, true
+}
@@ -0,0 +1,152 @@
+package dispatcher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/localmetrics"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks"
+)
+
+// slowContextWebhook only returns once its context is done
+type slowContextWebhook struct {
+	fakeWebhook
+	failurePolicy admissionregv1.FailurePolicyType
+	cancelled     chan struct{}
+}
+
+func (s *slowContextWebhook) AuthorizedWithContext(ctx context.Context, _ admissionctl.Request) admissionctl.Response {
+	<-ctx.Done()
+	close(s.cancelled)
+	return admissionctl.Allowed("too late")
+}
+func (s *slowContextWebhook) FailurePolicy() admissionregv1.FailurePolicyType { return s.failurePolicy }
+func (s *slowContextWebhook) TimeoutSeconds() int32                           { return 1 }
+
+// slowWebhook ignores the deadline entirely
+type slowWebhook struct {
+	fakeWebhook
+	release chan struct{}
+}
+
+func (s *slowWebhook) Authorized(_ admissionctl.Request) admissionctl.Response {
+	<-s.release
+	return admissionctl.Allowed("too late")
+}
+func (s *slowWebhook) TimeoutSeconds() int32 { return 1 }
+
+func TestParseTimeoutFallback(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    TimeoutFallback
+		wantErr bool
+	}{
+		{input: "", want: FailurePolicyFallback},
+		{input: "failure-policy", want: FailurePolicyFallback},
+		{input: "allow", want: AllowFallback},
+		{input: "deny", want: DenyFallback},
+		{input: "ignore", wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := ParseTimeoutFallback(test.input)
+		if (err != nil) != test.wantErr {
+			t.Errorf("ParseTimeoutFallback(%q) error = %v, wantErr %v", test.input, err, test.wantErr)
+		}
+		if got != test.want {
+			t.Errorf("ParseTimeoutFallback(%q) = %q, want %q", test.input, got, test.want)
+		}
+	}
+}
+
+func sendReview(t *testing.T, d *Dispatcher) admissionv1.AdmissionReview {
+	t.Helper()
+	return sendReviewTo(t, d, "/test-hook")
+}
+
+func sendReviewTo(t *testing.T, d *Dispatcher, uri string) admissionv1.AdmissionReview {
+	t.Helper()
+	req := httptest.NewRequestWithContext(context.Background(), "POST", uri, bytes.NewReader(validAdmissionReviewBody(t)))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	d.HandleRequest(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	var review admissionv1.AdmissionReview
+	if err := json.Unmarshal(w.Body.Bytes(), &review); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	return review
+}
+
+func TestHandleRequest_TimeoutFallback(t *testing.T) {
+	tests := []struct {
+		name          string
+		fallback      TimeoutFallback
+		failurePolicy admissionregv1.FailurePolicyType
+		wantAllowed   bool
+	}{
+		{name: "failure policy ignore", fallback: FailurePolicyFallback, failurePolicy: admissionregv1.Ignore, wantAllowed: true},
+		{name: "failure policy fail", fallback: FailurePolicyFallback, failurePolicy: admissionregv1.Fail, wantAllowed: false},
+		{name: "allow", fallback: AllowFallback, failurePolicy: admissionregv1.Fail, wantAllowed: true},
+		{name: "deny", fallback: DenyFallback, failurePolicy: admissionregv1.Ignore, wantAllowed: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hook := &slowContextWebhook{failurePolicy: test.failurePolicy, cancelled: make(chan struct{})}
+			d := NewDispatcher(webhooks.RegisteredWebhooks{
+				"test-validation": func() webhooks.Webhook { return hook },
+			}, WithTimeoutFallback(test.fallback))
+			timeouts := localmetrics.MetricWebhookTimeouts.WithLabelValues("test-validation")
+			before := counterValue(t, timeouts)
+
+			start := time.Now()
+			review := sendReview(t, d)
+			if elapsed := time.Since(start); elapsed >= time.Second {
+				t.Errorf("expected a response before the 1s TimeoutSeconds, took %s", elapsed)
+			}
+			if review.Response.Allowed != test.wantAllowed {
+				t.Errorf("expected allowed=%v, got %v", test.wantAllowed, review.Response.Allowed)
+			}
+			if review.Response.UID != "test-uid" {
+				t.Errorf("expected UID test-uid, got %q", review.Response.UID)
+			}
+			if !test.wantAllowed && review.Response.Result.Code != http.StatusGatewayTimeout {
+				t.Errorf("expected code %d, got %d", http.StatusGatewayTimeout, review.Response.Result.Code)
+			}
+			if got := counterValue(t, timeouts) - before; got != 1 {
+				t.Errorf("expected the timeout metric to increase by 1, got %v", got)
+			}
+			select {
+			case <-hook.cancelled:
+			case <-time.After(time.Second):
+				t.Error("expected the webhook's context to be cancelled")
+			}
+		})
+	}
+}
+
+func TestHandleRequest_TimeoutWithoutContext(t *testing.T) {
+	hook := &slowWebhook{release: make(chan struct{})}
+	defer close(hook.release)
+	d := NewDispatcher(webhooks.RegisteredWebhooks{
+		"test-validation": func() webhooks.Webhook { return hook },
+	})
+
+	review := sendReview(t, d)
+	if !review.Response.Allowed {
+		t.Error("expected the Ignore webhook's fallback to allow the request")
+	}
+	if len(review.Response.Warnings) == 0 {
+		t.Error("expected a warning explaining the fallback decision")
+	}
+}
@@ -0,0 +1,103 @@
+// Package explain records the checks a webhook evaluates on its way to a
+// decision, for the /explain endpoint. A Trace is carried to the webhook in
+// the context passed to AuthorizedWithContext. Webhooks record into whatever
+// FromContext returns, which is nil, and so does nothing, for ordinary
+// admission requests.
+package explain
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// Check is one check a webhook evaluated
+type Check struct {
+	// Check describes what was checked, e.g. `admins: group in ["cluster-admins"]`
+	Check string `json:"check"`
+	// Matched is true if the request matched the check
+	Matched bool `json:"matched"`
+}
+
+// String describes the check and its result, e.g.
+// `admins: group in ["cluster-admins"] → no`
+func (c Check) String() string {
+	if c.Matched {
+		return c.Check + " → yes"
+	}
+	return c.Check + " → no"
+}
+
+// Trace is the ordered list of checks evaluated for one request. A nil Trace
+// records nothing, so webhooks need not check whether they are explaining.
+type Trace struct {
+	// prefix is added to each check recorded through this Trace
+	prefix string
+	log    *checkLog
+}
+
+// checkLog is shared by a Trace and the Traces returned by its Prefixed
+type checkLog struct {
+	mu     sync.Mutex
+	checks []Check
+}
+
+// New returns an empty Trace
+func New() *Trace {
+	return &Trace{log: &checkLog{}}
+}
+
+// Prefixed returns a Trace which records into t with prefix added to each
+// check, e.g. the name of the principals a webhook is matching
+func (t *Trace) Prefixed(prefix string) *Trace {
+	if t == nil {
+		return nil
+	}
+	return &Trace{prefix: t.prefix + prefix, log: t.log}
+}
+
+// Record appends check and whether it matched, and returns matched so that
+// it can wrap a condition
+func (t *Trace) Record(matched bool, check string) bool {
+	if t == nil {
+		return matched
+	}
+	t.log.mu.Lock()
+	defer t.log.mu.Unlock()
+	t.log.checks = append(t.log.checks, Check{Check: t.prefix + check, Matched: matched})
+	return matched
+}
+
+// Recordf is Record with a formatted check, which is only formatted if t is
+// not nil
+func (t *Trace) Recordf(matched bool, format string, args ...interface{}) bool {
+	if t == nil {
+		return matched
+	}
+	return t.Record(matched, fmt.Sprintf(format, args...))
+}
+
+// Checks returns the checks recorded so far, in order
+func (t *Trace) Checks() []Check {
+	if t == nil {
+		return nil
+	}
+	t.log.mu.Lock()
+	defer t.log.mu.Unlock()
+	return slices.Clone(t.log.checks)
+}
+
+type traceKey struct{}
+
+// NewContext returns a copy of ctx carrying t
+func NewContext(ctx context.Context, t *Trace) context.Context {
+	return context.WithValue(ctx, traceKey{}, t)
+}
+
+// FromContext returns the Trace carried by ctx, or nil if the request is not
+// being explained
+func FromContext(ctx context.Context) *Trace {
+	t, _ := ctx.Value(traceKey{}).(*Trace)
+	return t
+}
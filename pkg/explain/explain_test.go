@@ -0,0 +1,118 @@
+package explain
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTrace(t *testing.T) {
+	trace := New()
+	if !trace.Record(true, "first") {
+		t.Error("Record should return matched")
+	}
+	trace.Prefixed("admins: ").Recordf(false, "user in %q", []string{"kube:admin"})
+	want := []string{"first → yes", `admins: user in ["kube:admin"] → no`}
+	checks := trace.Checks()
+	if len(checks) != len(want) {
+		t.Fatalf("Expected %d checks, got %v", len(want), checks)
+	}
+	for i, check := range checks {
+		if check.String() != want[i] {
+			t.Errorf("Expected %q, got %q", want[i], check)
+		}
+	}
+}
+
+func TestNilTrace(t *testing.T) {
+	trace := FromContext(context.Background())
+	if trace != nil {
+		t.Fatal("Expected no Trace in an empty context")
+	}
+	if !trace.Prefixed("admins: ").Recordf(true, "user in %q", "x") || trace.Record(false, "x") {
+		t.Error("A nil Trace should return matched")
+	}
+	if checks := trace.Checks(); checks != nil {
+		t.Errorf("Expected no checks, got %v", checks)
+	}
+
+	trace = New()
+	if FromContext(NewContext(context.Background(), trace)) != trace {
+		t.Error("Expected the Trace in the context")
+	}
+}
+
+func TestCheckBindAddress(t *testing.T) {
+	tests := []struct {
+		addr          string
+		tokenRequired bool
+		wantErr       bool
+	}{
+		{addr: "127.0.0.1:8082"},
+		{addr: "[::1]:8082"},
+		{addr: "localhost:8082"},
+		{addr: ":8082", wantErr: true},
+		{addr: "0.0.0.0:8082", wantErr: true},
+		{addr: "10.0.0.1:8082", wantErr: true},
+		{addr: ":8082", tokenRequired: true},
+		{addr: "8082", wantErr: true},
+	}
+	for _, test := range tests {
+		if err := CheckBindAddress(test.addr, test.tokenRequired); (err != nil) != test.wantErr {
+			t.Errorf("CheckBindAddress(%q, %v) error = %v, wantErr %v", test.addr, test.tokenRequired, err, test.wantErr)
+		}
+	}
+}
+
+func TestLoadToken(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "token")
+	if err := os.WriteFile(path, []byte("secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if token, err := LoadToken(path); err != nil || token != "secret" {
+		t.Errorf("LoadToken() = %q, %v, want secret", token, err)
+	}
+	empty := filepath.Join(dir, "empty")
+	if err := os.WriteFile(empty, []byte(" \n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadToken(empty); err == nil {
+		t.Error("Expected an error for an empty token")
+	}
+	if _, err := LoadToken(filepath.Join(dir, "missing")); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
+
+func TestRequireToken(t *testing.T) {
+	handler := RequireToken("secret", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	tests := []struct {
+		name          string
+		authorization string
+		code          int
+	}{
+		{name: "no token", code: http.StatusUnauthorized},
+		{name: "wrong token", authorization: "Bearer wrong", code: http.StatusUnauthorized},
+		{name: "not a bearer token", authorization: "Basic secret", code: http.StatusUnauthorized},
+		{name: "token", authorization: "Bearer secret", code: http.StatusOK},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, PathPrefix+"namespace-validation", nil)
+			if test.authorization != "" {
+				req.Header.Set("Authorization", test.authorization)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			if w.Code != test.code {
+				t.Errorf("Expected status %d, got %d", test.code, w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package explain
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// PathPrefix is followed by the name of the webhook to explain
+const PathPrefix = "/explain/"
+
+// CheckBindAddress returns an error unless addr only listens on a loopback
+// interface, since the endpoint reveals how every webhook decides. A token is
+// required to listen anywhere else.
+func CheckBindAddress(addr string, tokenRequired bool) error {
+	if tokenRequired {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+		return fmt.Errorf("explain endpoint address %q is not a loopback address and no token is required", addr)
+	}
+	return nil
+}
+
+// LoadToken reads a bearer token from path, ignoring surrounding whitespace
+func LoadToken(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	token := strings.TrimSpace(string(b))
+	if token == "" {
+		return "", fmt.Errorf("token file %s is empty", path)
+	}
+	return token, nil
+}
+
+// RequireToken only passes requests with token as their bearer token on to
+// next, and rejects the rest with 401 Unauthorized
+func RequireToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
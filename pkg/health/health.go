@@ -0,0 +1,136 @@
+// Package health serves the /healthz and /readyz endpoints which the kubelet
+// probes.
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// HealthzPath reports whether the process is alive
+	HealthzPath = "/healthz"
+	// ReadyzPath reports whether the server should receive admission requests
+	ReadyzPath = "/readyz"
+
+	// checkTimeout bounds each readiness check. The readiness probe's
+	// timeoutSeconds in build/resources.go must exceed it times the number of
+	// checks, so that a probe always gets an answer before the kubelet gives up
+	// on it.
+	checkTimeout = 2 * time.Second
+)
+
+var log = logf.Log.WithName("health")
+
+// Check returns an error if the server is not ready
+type Check func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Health tracks the server's readiness
+type Health struct {
+	mu     sync.RWMutex
+	checks []namedCheck
+	// shuttingDown is set once the server has started to shut down
+	shuttingDown atomic.Bool
+}
+
+// New returns a Health with no readiness checks
+func New() *Health {
+	return &Health{}
+}
+
+// AddReadinessCheck adds a check which must pass for the server to be ready.
+// Checks run in the order they were added, each time /readyz is requested.
+func (h *Health) AddReadinessCheck(name string, check Check) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks = append(h.checks, namedCheck{name: name, check: check})
+}
+
+// AddStartupCheck adds a check which must pass once for the server to become
+// ready. It runs each time /readyz is requested until it first passes, and is
+// skipped after that, so that a brief failure, such as of the API server, does
+// not take every replica out of the Service at once.
+func (h *Health) AddStartupCheck(name string, check Check) {
+	var passed atomic.Bool
+	h.AddReadinessCheck(name, func(ctx context.Context) error {
+		if passed.Load() {
+			return nil
+		}
+		if err := check(ctx); err != nil {
+			return err
+		}
+		passed.Store(true)
+		return nil
+	})
+}
+
+// SetShuttingDown makes the server permanently not ready, so that it is taken
+// out of the Service's endpoints before it stops accepting connections
+func (h *Health) SetShuttingDown() {
+	h.shuttingDown.Store(true)
+}
+
+// Ready runs every readiness check and returns the failures
+func (h *Health) Ready(ctx context.Context) []error {
+	if h.shuttingDown.Load() {
+		return []error{fmt.Errorf("shutting down")}
+	}
+	h.mu.RLock()
+	checks := h.checks
+	h.mu.RUnlock()
+
+	var failures []error
+	for _, c := range checks {
+		checkCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+		err := c.check(checkCtx)
+		cancel()
+		if err != nil {
+			failures = append(failures, fmt.Errorf("%s: %w", c.name, err))
+		}
+	}
+	return failures
+}
+
+// HandleHealthz answers liveness probes. It succeeds as long as the process can
+// serve HTTP.
+func (h *Health) HandleHealthz(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	fmt.Fprintln(w, "ok")
+}
+
+// HandleReadyz answers readiness probes with 503 Service Unavailable and the
+// failing checks if the server is not ready
+func (h *Health) HandleReadyz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	if failures := h.Ready(r.Context()); len(failures) > 0 {
+		messages := make([]string, len(failures))
+		for i, failure := range failures {
+			messages[i] = failure.Error()
+		}
+		log.Info("Not ready", "failures", messages)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprintln(w, strings.Join(messages, "\n"))
+		return
+	}
+	fmt.Fprintln(w, "ok")
+}
+
+// Handler returns a mux serving /healthz and /readyz
+func (h *Health) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(HealthzPath, h.HandleHealthz)
+	mux.HandleFunc(ReadyzPath, h.HandleReadyz)
+	return mux
+}
@@ -0,0 +1,91 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func probe(t *testing.T, h *Health, path string) (int, string) {
+	t.Helper()
+	req := httptest.NewRequestWithContext(context.Background(), "GET", path, nil)
+	w := httptest.NewRecorder()
+	h.Handler().ServeHTTP(w, req)
+	return w.Code, w.Body.String()
+}
+
+func TestReadyz(t *testing.T) {
+	h := New()
+	if code, _ := probe(t, h, ReadyzPath); code != http.StatusOK {
+		t.Errorf("expected a server without checks to be ready, got %d", code)
+	}
+
+	var tlsErr error = errors.New("no serving certificate has been loaded")
+	h.AddReadinessCheck("tls", func(context.Context) error { return tlsErr })
+	h.AddReadinessCheck("self-test", func(context.Context) error { return nil })
+
+	code, body := probe(t, h, ReadyzPath)
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("expected %d while a check fails, got %d", http.StatusServiceUnavailable, code)
+	}
+	if !strings.Contains(body, "tls: no serving certificate") {
+		t.Errorf("expected the failing check in the body, got %q", body)
+	}
+	if strings.Contains(body, "self-test") {
+		t.Errorf("expected only failing checks in the body, got %q", body)
+	}
+
+	tlsErr = nil
+	if code, _ := probe(t, h, ReadyzPath); code != http.StatusOK {
+		t.Errorf("expected ready once every check passes, got %d", code)
+	}
+
+	h.SetShuttingDown()
+	if code, _ := probe(t, h, ReadyzPath); code != http.StatusServiceUnavailable {
+		t.Errorf("expected not ready while shutting down, got %d", code)
+	}
+	if code, _ := probe(t, h, HealthzPath); code != http.StatusOK {
+		t.Errorf("expected healthz to succeed while shutting down, got %d", code)
+	}
+}
+
+func TestReadyzCheckDeadline(t *testing.T) {
+	h := New()
+	h.AddReadinessCheck("cluster-access", func(ctx context.Context) error {
+		if _, ok := ctx.Deadline(); !ok {
+			return errors.New("no deadline")
+		}
+		return nil
+	})
+	if code, body := probe(t, h, ReadyzPath); code != http.StatusOK {
+		t.Errorf("expected each check to be given a deadline, got %d: %s", code, body)
+	}
+}
+
+func TestReadyzStartupCheck(t *testing.T) {
+	h := New()
+	calls := 0
+	var clusterErr error = errors.New("connection refused")
+	h.AddStartupCheck("cluster-access", func(context.Context) error {
+		calls++
+		return clusterErr
+	})
+	if code, _ := probe(t, h, ReadyzPath); code != http.StatusServiceUnavailable {
+		t.Errorf("expected %d until the startup check passes, got %d", http.StatusServiceUnavailable, code)
+	}
+
+	clusterErr = nil
+	if code, _ := probe(t, h, ReadyzPath); code != http.StatusOK {
+		t.Errorf("expected ready once the startup check passes, got %d", code)
+	}
+	clusterErr = errors.New("connection refused")
+	if code, _ := probe(t, h, ReadyzPath); code != http.StatusOK {
+		t.Errorf("expected a startup check to not run again once it passed, got %d", code)
+	}
+	if calls != 2 {
+		t.Errorf("expected the startup check to run twice, got %d", calls)
+	}
+}
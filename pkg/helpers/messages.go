@@ -0,0 +1,310 @@
+package helpers
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"text/template"
+)
+
+const (
+	// DefaultSupportURL is where denial messages send customers with questions
+	// unless the server is configured with a product-specific support URL
+	DefaultSupportURL = "https://access.redhat.com/support"
+
+	// catalogURL is the generated documentation of every denial, in which
+	// each code has an anchor of its own
+	catalogURL = "https://github.com/openshift/managed-cluster-validating-webhooks/blob/master/docs/denials.md"
+)
+
+// Code identifies a denial in the message catalog, e.g. MCVW-NS-001. Codes
+// are stable so that support can search for them and clients can act on them:
+// once released, a code keeps its meaning and is never reused for another
+// denial. Retire a code instead of changing what it means.
+type Code string
+
+// Args are the values a Message's template is rendered with
+type Args map[string]any
+
+// Message is a catalog entry
+type Message struct {
+	Code Code
+	// Template is a text/template of the reason for the denial, rendered
+	// with the Args the webhook provides
+	Template string
+	// Doc explains the denial, and what the customer can do instead, in the
+	// generated documentation
+	Doc string
+
+	tmpl *template.Template
+}
+
+// Codes shared by several webhooks
+const (
+	CodeUnauthenticated Code = "MCVW-GEN-001"
+	CodeManagedResource Code = "MCVW-GEN-002"
+)
+
+// Codes for a single webhook, prefixed with an abbreviation of its name
+const (
+	CodeClusterLoggingRetentionUnset   Code = "MCVW-CL-001"
+	CodeClusterLoggingRetentionInvalid Code = "MCVW-CL-002"
+	CodeClusterRoleDelete              Code = "MCVW-CR-001"
+	CodeClusterRoleBindingDelete       Code = "MCVW-CRB-001"
+	CodeHostedClusterDelete            Code = "MCVW-HC-001"
+	CodeHostedControlPlaneDelete       Code = "MCVW-HCP-001"
+	CodeHCPNamespaceDelete             Code = "MCVW-HCPNS-001"
+	CodeImageMirrors                   Code = "MCVW-ICP-001"
+	CodeIngressConfig                  Code = "MCVW-INGCFG-001"
+	CodeIngressControllerToleration    Code = "MCVW-INGCTL-001"
+	CodeManifestWorkDelete             Code = "MCVW-MW-001"
+	CodeNetworkOperatorMigration       Code = "MCVW-NETOP-001"
+	CodeNetworkPolicyIngress           Code = "MCVW-NP-001"
+	CodeNodeDelete                     Code = "MCVW-NODE-001"
+	CodeNodeManaged                    Code = "MCVW-NODE-002"
+	CodeNamespacePrivileged            Code = "MCVW-NS-001"
+	CodeNamespaceHarmful               Code = "MCVW-NS-002"
+	CodeNamespaceProtectedLabels       Code = "MCVW-NS-003"
+	CodePodTolerations                 Code = "MCVW-POD-001"
+	CodeServiceAccountDelete           Code = "MCVW-SA-001"
+	CodeSCCDelete                      Code = "MCVW-SCC-001"
+	CodeSCCModify                      Code = "MCVW-SCC-002"
+	CodeSDNMigration                   Code = "MCVW-SDN-001"
+	CodeTechPreviewNoUpgrade           Code = "MCVW-TP-001"
+)
+
+// harmful is the explanation the Red Hat managed resource denials share
+const harmful = " This is in an effort to prevent harmful actions that may cause unintended consequences or affect the stability of the cluster."
+
+var catalog = newCatalog([]Message{
+	{
+		Code:     CodeUnauthenticated,
+		Template: "Unauthenticated requests are not allowed.",
+		Doc:      "The request was not made by an authenticated user, so the webhook can't check whether the user may make it.",
+	},
+	{
+		Code:     CodeManagedResource,
+		Template: "Prevented from accessing Red Hat managed resources." + harmful,
+		Doc:      "The object is managed by Red Hat, and only Red Hat SRE and the cluster's own components may change it.",
+	},
+	{
+		Code:     CodeClusterLoggingRetentionUnset,
+		Template: "The entered retention policy is not allowed. {{.Name}} must not be unset. Hint: {{.Hint}}",
+		Doc:      "Every ClusterLogging log store retention policy must set a maximum age, so that logs do not fill the storage Red Hat manages.",
+	},
+	{
+		Code:     CodeClusterLoggingRetentionInvalid,
+		Template: "The entered RetentionPolicy {{.Name}} is not allowed. {{.Hint}}",
+		Doc:      "A ClusterLogging log store retention policy's maximum age is outside the range which is supported on Managed OpenShift.",
+	},
+	{
+		Code:     CodeClusterRoleDelete,
+		Template: "Deleting ClusterRole {{.Name}} is not allowed.",
+		Doc:      "The ClusterRole is part of the Managed OpenShift RBAC configuration and can't be deleted.",
+	},
+	{
+		Code:     CodeClusterRoleBindingDelete,
+		Template: "Deleting ClusterRoleBinding {{.Name}} is not allowed.",
+		Doc:      "The ClusterRoleBinding is part of the Managed OpenShift RBAC configuration and can't be deleted.",
+	},
+	{
+		Code:     CodeHostedClusterDelete,
+		Template: "Only authorized service accounts {{.ExemptUsers}} can delete HostedCluster resources.",
+		Doc:      "HostedClusters on management clusters are only deleted through OCM.",
+	},
+	{
+		Code:     CodeHostedControlPlaneDelete,
+		Template: "Only authorized service accounts {{.ExemptUsers}} and those matching {{.ExemptUserPatterns}} can delete HostedControlPlane resources.",
+		Doc:      "HostedControlPlanes are only deleted by HyperShift and the SRE tooling which manage them.",
+	},
+	{
+		Code:     CodeHCPNamespaceDelete,
+		Template: "Only authorized users/service accounts can delete this namespace {{.Namespace}}.",
+		Doc:      "The namespaces holding hosted control planes are only deleted by HyperShift and the SRE tooling which manage them.",
+	},
+	{
+		Code:     CodeImageMirrors,
+		Template: "Managed OpenShift customers may not create ImageContentSourcePolicy, ImageDigestMirrorSet, or ImageTagMirrorSet resources that configure mirrors that would conflict with system registries (e.g. quay.io, registry.redhat.io, registry.access.redhat.com, etc).",
+		Doc:      "Mirrors for the registries the cluster's own images come from could stop the cluster from being upgraded or repaired. Mirrors for other registries are allowed.",
+	},
+	{
+		Code:     CodeIngressConfig,
+		Template: "Only privileged service accounts may access the cluster ingress configuration.",
+		Doc:      "The cluster's ingress configuration is managed by Red Hat.",
+	},
+	{
+		Code:     CodeIngressControllerToleration,
+		Template: "Not allowed to provision ingress controller pods with toleration for master nodes.",
+		Doc:      "IngressController pods may not run on master nodes, which are reserved for the control plane. Use worker or infra nodes instead.",
+	},
+	{
+		Code:     CodeManifestWorkDelete,
+		Template: "Only authorized service accounts can delete ManifestWork resources. Allowed service accounts: {{.ExemptUsers}}",
+		Doc:      "ManifestWorks on service clusters are only deleted by OCM and the controllers which garbage collect them.",
+	},
+	{
+		Code:     CodeNetworkOperatorMigration,
+		Template: "Modification of critical migration fields (spec.migration.networkType and related migration configuration) is not allowed, even for cluster-admin users. These fields are managed by the Cluster Network Operator and manual changes can disrupt CNI migrations.",
+		Doc:      "CNI migrations are started through OCM, which sets the migration fields of the cluster Network operator configuration.",
+	},
+	{
+		Code:     CodeNetworkPolicyIngress,
+		Template: "Prevented from creating network policy that may impact default ingress, which is managed by Red Hat." + harmful,
+		Doc:      "NetworkPolicies in the openshift-ingress namespace could cut the cluster's default router off from its clients.",
+	},
+	{
+		Code:     CodeNodeDelete,
+		Template: "Prevented from deleting nodes." + harmful,
+		Doc:      "Nodes are managed by their MachineSets. Scale the MachinePool or MachineSet down instead.",
+	},
+	{
+		Code:     CodeNodeManaged,
+		Template: "Prevented from modifying Red Hat managed {{.Role}} nodes." + harmful,
+		Doc:      "Infra, control plane and master nodes are managed by Red Hat.",
+	},
+	{
+		Code:     CodeNamespacePrivileged,
+		Template: "Prevented from accessing Red Hat managed namespaces. Customer workloads should be placed in customer namespaces, and should not match an entry in this list of regular expressions: {{.Namespaces}}",
+		Doc:      "The namespace is managed by Red Hat. Create a namespace of your own instead, with a name which doesn't match any of the managed namespaces.",
+	},
+	{
+		Code:     CodeNamespaceHarmful,
+		Template: "Prevented from creating a potentially harmful namespace. Customer namespaces should not match this regular expression, as this would impact DNS resolution: {{.Regex}}",
+		Doc:      "Namespaces with names like top level domains would capture DNS lookups of hosts in those domains.",
+	},
+	{
+		Code:     CodeNamespaceProtectedLabels,
+		Template: "Managed OpenShift customers may not {{.Action}} the protected labels {{.Labels}} on Namespaces.",
+		Doc:      "Some namespace labels are managed by Red Hat and may not be added, removed or changed by customers.",
+	},
+	{
+		Code:     CodePodTolerations,
+		Template: "Not allowed to schedule a pod with {{.Effect}} taint on {{.Role}} node.",
+		Doc:      "Infra and master nodes are reserved for Red Hat managed workloads. Schedule the pod on a worker node instead.",
+	},
+	{
+		Code:     CodeServiceAccountDelete,
+		Template: "Deleting protected service account under namespace {{.Namespace}} is not allowed.",
+		Doc:      "The service accounts in Red Hat managed namespaces are used by the cluster's own components.",
+	},
+	{
+		Code:     CodeSCCDelete,
+		Template: "Deleting default SCCs {{.SCCs}} is not allowed.",
+		Doc:      "The default SecurityContextConstraints are used by the cluster's own components. Create an SCC of your own instead.",
+	},
+	{
+		Code:     CodeSCCModify,
+		Template: "Modifying default SCCs {{.SCCs}} is not allowed.",
+		Doc:      "The default SecurityContextConstraints are used by the cluster's own components. Create an SCC of your own instead.",
+	},
+	{
+		Code:     CodeSDNMigration,
+		Template: "Changing the network type is not allowed.",
+		Doc:      "Migrations from OpenShift SDN to OVN-Kubernetes are started through OCM.",
+	},
+	{
+		Code:     CodeTechPreviewNoUpgrade,
+		Template: "The TechPreviewNoUpgrade Feature Gate is not allowed.",
+		Doc:      "Clusters with the TechPreviewNoUpgrade feature set can't be upgraded, so it isn't supported on Managed OpenShift.",
+	},
+})
+
+// supportURL is where denial messages send customers with questions
+var supportURL atomic.Pointer[string]
+
+// newCatalog indexes messages by code. A duplicate code or invalid template
+// is a programming error, so it panics rather than leaving a denial without
+// a message.
+func newCatalog(messages []Message) map[Code]*Message {
+	c := make(map[Code]*Message, len(messages))
+	for i := range messages {
+		m := &messages[i]
+		if _, ok := c[m.Code]; ok {
+			panic(fmt.Sprintf("duplicate message code %s", m.Code))
+		}
+		m.tmpl = template.Must(template.New(string(m.Code)).Option("missingkey=error").Parse(m.Template))
+		c[m.Code] = m
+	}
+	return c
+}
+
+// Lookup returns the catalog entry for code
+func Lookup(code Code) (Message, bool) {
+	m, ok := catalog[code]
+	if !ok {
+		return Message{}, false
+	}
+	return *m, true
+}
+
+// Catalog returns every entry in the catalog in order of code
+func Catalog() []Message {
+	messages := make([]Message, 0, len(catalog))
+	for _, m := range catalog {
+		messages = append(messages, *m)
+	}
+	slices.SortFunc(messages, func(a, b Message) int { return strings.Compare(string(a.Code), string(b.Code)) })
+	return messages
+}
+
+// SupportURL returns where denial messages send customers with questions
+func SupportURL() string {
+	if url := supportURL.Load(); url != nil {
+		return *url
+	}
+	return DefaultSupportURL
+}
+
+// SetSupportURL replaces the support URL for the product the server is
+// deployed for. An empty url restores DefaultSupportURL.
+func SetSupportURL(url string) {
+	if url == "" {
+		supportURL.Store(nil)
+		return
+	}
+	supportURL.Store(&url)
+}
+
+// DocURL links to the documentation of code
+func (c Code) DocURL() string {
+	return catalogURL + "#" + strings.ToLower(string(c))
+}
+
+// Text renders the reason for the denial from code's template. A code which
+// isn't in the catalog, or args which don't fit its template, are programming
+// errors, which are logged and leave the template unrendered rather than
+// losing the denial.
+func (c Code) Text(args Args) string {
+	m, ok := catalog[c]
+	if !ok {
+		log.Error(fmt.Errorf("unknown message code %s", c), "Denial without a catalog message")
+		return string(c)
+	}
+	text, err := c.Render(args)
+	if err != nil {
+		log.Error(err, "Couldn't render denial message", "code", c)
+		return m.Template
+	}
+	return text
+}
+
+// Render renders the reason for the denial from code's template, or returns
+// an error if the code isn't in the catalog or args don't fit its template
+func (c Code) Render(args Args) (string, error) {
+	m, ok := catalog[c]
+	if !ok {
+		return "", fmt.Errorf("unknown message code %s", c)
+	}
+	var b strings.Builder
+	if err := m.tmpl.Execute(&b, args); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// Format adds the code, a link to its documentation and the support URL to
+// text, so that customers and support can find out more about the denial
+func (c Code) Format(text string) string {
+	return fmt.Sprintf("[%s] %s See %s for details. If you have any questions about this, please reach out to Red Hat support at %s", c, text, c.DocURL(), SupportURL())
+}
@@ -0,0 +1,105 @@
+package helpers
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var validCode = regexp.MustCompile(`^MCVW-[A-Z]+-[0-9]{3}$`)
+
+func TestCatalog(t *testing.T) {
+	messages := Catalog()
+	if len(messages) != len(catalog) {
+		t.Fatalf("Expected %d messages, got %d", len(catalog), len(messages))
+	}
+	for i, m := range messages {
+		if !validCode.MatchString(string(m.Code)) {
+			t.Errorf("Code %q should look like MCVW-NS-001", m.Code)
+		}
+		if m.Template == "" || m.Doc == "" {
+			t.Errorf("Code %s needs a template and documentation", m.Code)
+		}
+		if i > 0 && messages[i-1].Code >= m.Code {
+			t.Errorf("Expected codes in order, got %s before %s", messages[i-1].Code, m.Code)
+		}
+	}
+}
+
+func TestNewCatalogDuplicate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a duplicate code to panic")
+		}
+	}()
+	newCatalog([]Message{{Code: "MCVW-X-001", Template: "a"}, {Code: "MCVW-X-001", Template: "b"}})
+}
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		name string
+		code Code
+		args Args
+		want string
+	}{
+		{
+			name: "without args",
+			code: CodeSDNMigration,
+			want: "Changing the network type is not allowed.",
+		},
+		{
+			name: "with args",
+			code: CodeClusterRoleDelete,
+			args: Args{"Name": "cluster-admin"},
+			want: "Deleting ClusterRole cluster-admin is not allowed.",
+		},
+		{
+			name: "missing args",
+			code: CodeClusterRoleDelete,
+			want: "Deleting ClusterRole {{.Name}} is not allowed.",
+		},
+		{
+			name: "unknown code",
+			code: "MCVW-XX-001",
+			want: "MCVW-XX-001",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.code.Text(test.args); got != test.want {
+				t.Errorf("Expected %q, got %q", test.want, got)
+			}
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	if text, err := CodeClusterRoleDelete.Render(Args{"Name": "cluster-admin"}); err != nil || text != "Deleting ClusterRole cluster-admin is not allowed." {
+		t.Errorf("Render() = %q, %v", text, err)
+	}
+	if _, err := CodeClusterRoleDelete.Render(nil); err == nil {
+		t.Error("Expected an error for missing args")
+	}
+	if _, err := Code("MCVW-XX-001").Render(nil); err == nil {
+		t.Error("Expected an error for an unknown code")
+	}
+}
+
+func TestFormat(t *testing.T) {
+	defer SetSupportURL("")
+	message := CodeNamespacePrivileged.Format("Prevented.")
+	for _, want := range []string{
+		"[MCVW-NS-001] Prevented.",
+		"docs/denials.md#mcvw-ns-001",
+		DefaultSupportURL,
+	} {
+		if !strings.Contains(message, want) {
+			t.Errorf("Expected %q to contain %q", message, want)
+		}
+	}
+
+	SetSupportURL("https://example.com/support")
+	if message := CodeNamespacePrivileged.Format("Prevented."); !strings.HasSuffix(message, "https://example.com/support") {
+		t.Errorf("Expected the configured support URL, got %q", message)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	admissionapi "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
@@ -14,23 +15,56 @@ var log = logf.Log.WithName("response_helper")
 
 // SendResponse Send the AdmissionReview.
 func SendResponse(w io.Writer, resp admissionctl.Response) {
+	SendVersionedResponse(w, admissionapi.SchemeGroupVersion.WithKind("AdmissionReview"), resp)
+}
+
+// SendVersionedResponse sends the AdmissionReview as gvk, which should be the
+// version of the AdmissionReview in the request. v1 and v1beta1 responses
+// differ only in their apiVersion.
+func SendVersionedResponse(w io.Writer, gvk schema.GroupVersionKind, resp admissionctl.Response) {
 
 	// Apply ownership annotation to allow for granular alerts for
-	// manipulation of SREP owned webhooks.
-	resp.AuditAnnotations = map[string]string{
-		"owner": "srep-managed-webhook",
+	// manipulation of SREP owned webhooks. Copy any annotations set by the
+	// webhook rather than replacing them, and without modifying the caller's map.
+	auditAnnotations := map[string]string{}
+	for k, v := range resp.AuditAnnotations {
+		auditAnnotations[k] = v
+	}
+	auditAnnotations["owner"] = "srep-managed-webhook"
+	resp.AuditAnnotations = auditAnnotations
+
+	body, err := encodeResponse(gvk, resp)
+	if err != nil {
+		// Fall back to a response which contains only strings. It is encoded
+		// before anything is written so that a failed attempt leaves nothing
+		// partial behind.
+		log.Error(err, "Failed to encode Response", "response", resp)
+		fallback := admissionctl.Errored(http.StatusInternalServerError, err)
+		fallback.UID = resp.UID
+		fallback.AuditAnnotations = map[string]string{"owner": "srep-managed-webhook"}
+		body, err = encodeResponse(gvk, fallback)
+		if err != nil {
+			log.Error(err, "Failed to encode fallback Response", "response", fallback)
+			return
+		}
+	}
+	if _, err := w.Write(body); err != nil {
+		log.Error(err, "Failed to write Response")
 	}
+}
 
-	encoder := json.NewEncoder(w)
+// marshal is swapped out by tests to exercise encoding failures
+var marshal = json.Marshal
+
+// encodeResponse renders resp as a newline-terminated AdmissionReview of gvk
+func encodeResponse(gvk schema.GroupVersionKind, resp admissionctl.Response) ([]byte, error) {
 	responseAdmissionReview := admissionapi.AdmissionReview{
 		Response: &resp.AdmissionResponse,
 	}
-	responseAdmissionReview.APIVersion = admissionapi.SchemeGroupVersion.String()
-	responseAdmissionReview.Kind = "AdmissionReview"
-	err := encoder.Encode(responseAdmissionReview)
-	// TODO (lisa): handle this in a non-recursive way (why would the second one succeed)?
+	responseAdmissionReview.SetGroupVersionKind(gvk)
+	body, err := marshal(responseAdmissionReview)
 	if err != nil {
-		log.Error(err, "Failed to encode Response", "response", resp)
-		SendResponse(w, admissionctl.Errored(http.StatusInternalServerError, err))
+		return nil, err
 	}
+	return append(body, '\n'), nil
 }
@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	admissionapi "k8s.io/api/admission/v1"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
 	"k8s.io/apimachinery/pkg/types"
 	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
@@ -35,7 +36,43 @@ func makeResponseObj(uid string, allowed bool, e error) *admissionctl.Response {
 }
 
 func TestBadResponse(t *testing.T) {
-	t.Skip("Not quite sure how to test json encoding error")
+	defer func() { marshal = json.Marshal }()
+	calls := 0
+	marshal = func(v any) ([]byte, error) {
+		calls++
+		if calls == 1 {
+			return nil, fmt.Errorf("cannot encode")
+		}
+		return json.Marshal(v)
+	}
+
+	buf := makeBuffer()
+	SendResponse(buf, *makeResponseObj("test-uid", true, nil))
+	if calls != 2 {
+		t.Fatalf("expected one fallback encode attempt, got %d attempts", calls)
+	}
+
+	review := &admissionapi.AdmissionReview{}
+	if err := json.Unmarshal(buf.Bytes(), review); err != nil {
+		t.Fatalf("expected a single well-formed AdmissionReview, got %q: %v", buf.String(), err)
+	}
+	if review.Response.Allowed {
+		t.Error("expected the fallback response to not be allowed")
+	}
+	if review.Response.UID != "test-uid" {
+		t.Errorf("expected UID test-uid, got %q", review.Response.UID)
+	}
+	if review.Response.Result.Code != http.StatusInternalServerError {
+		t.Errorf("expected code %d, got %d", http.StatusInternalServerError, review.Response.Result.Code)
+	}
+
+	// If even the fallback can't be encoded nothing is written
+	marshal = func(v any) ([]byte, error) { return nil, fmt.Errorf("cannot encode") }
+	buf = makeBuffer()
+	SendResponse(buf, *makeResponseObj("test-uid", true, nil))
+	if buf.Len() != 0 {
+		t.Errorf("expected nothing to be written, got %q", buf.String())
+	}
 }
 
 func TestResponse(t *testing.T) {
@@ -93,3 +130,32 @@ func TestResponse(t *testing.T) {
 	}
 
 }
+
+func TestResponseKeepsAuditAnnotations(t *testing.T) {
+	buf := makeBuffer()
+	resp := admissionctl.Allowed("")
+	resp.AuditAnnotations = map[string]string{"rule": "example"}
+	SendResponse(buf, resp)
+
+	decodedResult := &admissionapi.AdmissionReview{}
+	if err := json.Unmarshal(buf.Bytes(), decodedResult); err != nil {
+		t.Fatalf("Couldn't unmarshal the JSON blob: %s", err.Error())
+	}
+	if decodedResult.Response.AuditAnnotations["rule"] != "example" || decodedResult.Response.AuditAnnotations["owner"] != "srep-managed-webhook" {
+		t.Errorf("Expected both the webhook's and the owner audit annotations, got %v", decodedResult.Response.AuditAnnotations)
+	}
+	if _, ok := resp.AuditAnnotations["owner"]; ok {
+		t.Errorf("Expected the caller's audit annotations not to be modified")
+	}
+}
+
+func TestVersionedResponse(t *testing.T) {
+	buf := makeBuffer()
+	resp := *makeResponseObj("test-uid", true, nil)
+	SendVersionedResponse(buf, admissionv1beta1.SchemeGroupVersion.WithKind("AdmissionReview"), resp)
+
+	expected := formatOutput(`{"kind":"AdmissionReview","apiVersion":"admission.k8s.io/v1beta1","response":{"uid":"test-uid","allowed":true,"auditAnnotations":{"owner":"srep-managed-webhook"}}}`)
+	if buf.String() != expected {
+		t.Errorf("Expected to have `%s` but got `%s`", expected, buf.String())
+	}
+}
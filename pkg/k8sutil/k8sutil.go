@@ -10,6 +10,8 @@ import (
 	"k8s.io/client-go/tools/clientcmd"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/tracing"
 )
 
 type RunModeType string
@@ -54,6 +56,8 @@ func KubeClient(s *runtime.Scheme) (client.Client, error) {
 	if err != nil {
 		return nil, err
 	}
+	// Trace every API call the client makes
+	config.Wrap(tracing.WrapTransport)
 
 	c, err := client.New(config, client.Options{
 		Scheme: s,
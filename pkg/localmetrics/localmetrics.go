@@ -1,7 +1,39 @@
 package localmetrics
 
 import (
+	"time"
+
 	"github.com/prometheus/client_golang/prometheus"
+	authenticationv1 "k8s.io/api/authentication/v1"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+const (
+	// OutcomeAllowed is recorded when a webhook admits a request
+	OutcomeAllowed = "allowed"
+	// OutcomeDenied is recorded when a webhook rejects a request
+	OutcomeDenied = "denied"
+	// OutcomeErrored is recorded when a webhook fails to come to a decision
+	OutcomeErrored = "errored"
+	// OutcomeInvalid is recorded when the AdmissionReview could not be parsed
+	// or the webhook did not consider the request valid
+	OutcomeInvalid = "invalid"
+	// OutcomeTimeout is recorded when a webhook did not reach a decision before
+	// its deadline and the fallback decision was sent instead
+	OutcomeTimeout = "timeout"
+	// OutcomeShed is recorded when there was no capacity to handle a request
+	// and it was answered without asking the webhook
+	OutcomeShed = "shed"
+
+	// UserClassCustomer is any user which is not otherwise classified
+	UserClassCustomer = "customer"
+	// UserClassSRE is a Red Hat SRE acting through backplane
+	UserClassSRE = "sre"
+	// UserClassSystem is a system: or kube: user which is not a serviceaccount
+	UserClassSystem = "system"
+	// UserClassServiceAccount is any serviceaccount not belonging to SRE
+	UserClassServiceAccount = "serviceaccount"
 )
 
 var (
@@ -10,11 +42,155 @@ var (
 		Help: "Report how many times the managed node webhook has blocked requests",
 	}, []string{"user"})
 
+	MetricWebhookDecisions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "managed_webhook_admission_decisions_total",
+		Help: "Report how many admission requests each webhook has handled, by outcome",
+	}, []string{"webhook", "operation", "group", "kind", "outcome", "user_class"})
+
+	MetricWebhookDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "managed_webhook_admission_duration_seconds",
+		Help:    "Report how long each webhook took to handle an admission request, by outcome",
+		Buckets: []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5},
+	}, []string{"webhook", "operation", "group", "kind", "outcome", "user_class"})
+
+	MetricWebhookUnenforcedDenials = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "managed_webhook_unenforced_denials_total",
+		Help: "Report how many denials were allowed through because the webhook is in warn or audit mode",
+	}, []string{"webhook", "mode"})
+
+	MetricAllowlistReloads = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "managed_webhook_allowlist_reloads_total",
+		Help: "Report how many times the allowlist configuration was reloaded, by whether it was accepted",
+	}, []string{"result"})
+
+	MetricBreakGlassReloads = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "managed_webhook_break_glass_reloads_total",
+		Help: "Report how many times the break-glass override configuration was reloaded, by whether it was accepted",
+	}, []string{"result"})
+
+	MetricBreakGlassOverrides = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "managed_webhook_break_glass_overrides_total",
+		Help: "Report how many denials were allowed through by a break-glass override",
+	}, []string{"webhook"})
+
+	MetricWebhookTimeouts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "managed_webhook_timeouts_total",
+		Help: "Report how many times each webhook did not reach a decision before its deadline",
+	}, []string{"webhook"})
+
+	MetricWebhookPanics = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "managed_webhook_panics_total",
+		Help: "Report how many times each webhook panicked while handling a request",
+	}, []string{"webhook"})
+
+	MetricWebhookShedRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "managed_webhook_shed_requests_total",
+		Help: "Report how many requests each webhook answered without a decision because it was at its concurrency limit",
+	}, []string{"webhook"})
+
+	MetricServingCertificateExpiry = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "managed_webhook_serving_certificate_expiry_timestamp_seconds",
+		Help: "Report the not-after time of the serving certificate currently in use, as a Unix timestamp",
+	})
+
 	MetricsList = []prometheus.Collector{
 		MetricNodeWebhookBlockedReqeust,
+		MetricWebhookDecisions,
+		MetricWebhookDuration,
+		MetricWebhookUnenforcedDenials,
+		MetricAllowlistReloads,
+		MetricServingCertificateExpiry,
+		MetricWebhookTimeouts,
+		MetricWebhookPanics,
+		MetricWebhookShedRequests,
+		MetricBreakGlassReloads,
+		MetricBreakGlassOverrides,
 	}
 )
 
 func IncrementNodeWebhookBlockedRequest(user string) {
 	MetricNodeWebhookBlockedReqeust.With(prometheus.Labels{"user": user}).Inc()
 }
+
+// ObserveWebhookDecision records the outcome and latency of a single admission
+// request handled by the named webhook.
+func ObserveWebhookDecision(webhook, operation, group, kind, outcome, userClass string, duration time.Duration) {
+	labels := prometheus.Labels{
+		"webhook":    webhook,
+		"operation":  operation,
+		"group":      group,
+		"kind":       kind,
+		"outcome":    outcome,
+		"user_class": userClass,
+	}
+	MetricWebhookDecisions.With(labels).Inc()
+	MetricWebhookDuration.With(labels).Observe(duration.Seconds())
+}
+
+// IncrementUnenforcedDenial records a denial which was not enforced because the
+// webhook is not in enforce mode
+func IncrementUnenforcedDenial(webhook, mode string) {
+	MetricWebhookUnenforcedDenials.With(prometheus.Labels{"webhook": webhook, "mode": mode}).Inc()
+}
+
+// IncrementWebhookTimeout records a webhook not reaching a decision in time
+func IncrementWebhookTimeout(webhook string) {
+	MetricWebhookTimeouts.With(prometheus.Labels{"webhook": webhook}).Inc()
+}
+
+// IncrementWebhookPanic records a panic recovered from a webhook
+func IncrementWebhookPanic(webhook string) {
+	MetricWebhookPanics.With(prometheus.Labels{"webhook": webhook}).Inc()
+}
+
+// IncrementWebhookShed records a request shed because the webhook was at its
+// concurrency limit
+func IncrementWebhookShed(webhook string) {
+	MetricWebhookShedRequests.With(prometheus.Labels{"webhook": webhook}).Inc()
+}
+
+// IncrementAllowlistReload records an attempt to load the allowlist configuration
+func IncrementAllowlistReload(success bool) {
+	result := "success"
+	if !success {
+		result = "failure"
+	}
+	MetricAllowlistReloads.With(prometheus.Labels{"result": result}).Inc()
+}
+
+// IncrementBreakGlassReload records an attempt to load the break-glass
+// override configuration
+func IncrementBreakGlassReload(success bool) {
+	result := "success"
+	if !success {
+		result = "failure"
+	}
+	MetricBreakGlassReloads.With(prometheus.Labels{"result": result}).Inc()
+}
+
+// IncrementBreakGlassOverride records a denial by the webhook which a
+// break-glass override allowed through
+func IncrementBreakGlassOverride(webhook string) {
+	MetricBreakGlassOverrides.With(prometheus.Labels{"webhook": webhook}).Inc()
+}
+
+// SetServingCertificateExpiry records the not-after time of the serving
+// certificate which has just been loaded
+func SetServingCertificateExpiry(notAfter time.Time) {
+	MetricServingCertificateExpiry.Set(float64(notAfter.Unix()))
+}
+
+// UserClass reduces a username and its groups to one of a bounded set of
+// classes so that it is safe to use as a metric label.
+func UserClass(username string, groups []string) string {
+	userInfo := authenticationv1.UserInfo{Username: username, Groups: groups}
+	switch {
+	case utils.SREUsers.Matches(userInfo):
+		return UserClassSRE
+	case utils.ServiceAccounts.Matches(userInfo):
+		return UserClassServiceAccount
+	case utils.SystemUsers.Matches(userInfo):
+		return UserClassSystem
+	}
+	return UserClassCustomer
+}
@@ -0,0 +1,49 @@
+package localmetrics
+
+import "testing"
+
+func TestUserClass(t *testing.T) {
+	tests := []struct {
+		username string
+		groups   []string
+		expected string
+	}{
+		{
+			username: "my-user",
+			groups:   []string{"dedicated-admins", "system:authenticated"},
+			expected: UserClassCustomer,
+		},
+		{
+			username: "backplane-cluster-admin",
+			expected: UserClassSRE,
+		},
+		{
+			username: "system:serviceaccount:openshift-backplane-srep:1234",
+			groups:   []string{"system:serviceaccounts:openshift-backplane-srep"},
+			expected: UserClassSRE,
+		},
+		{
+			username: "system:serviceaccount:openshift-backplane-cee:1234",
+			groups:   []string{"system:serviceaccounts:openshift-backplane-cee"},
+			expected: UserClassSRE,
+		},
+		{
+			username: "system:serviceaccount:my-namespace:default",
+			groups:   []string{"system:serviceaccounts:my-namespace"},
+			expected: UserClassServiceAccount,
+		},
+		{
+			username: "system:admin",
+			expected: UserClassSystem,
+		},
+		{
+			username: "kube:admin",
+			expected: UserClassSystem,
+		},
+	}
+	for _, test := range tests {
+		if got := UserClass(test.username, test.groups); got != test.expected {
+			t.Errorf("expected user %s with groups %v to be classified as %s, got %s", test.username, test.groups, test.expected, got)
+		}
+	}
+}
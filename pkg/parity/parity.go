@@ -0,0 +1,248 @@
+// Package parity checks that webhooks and the ValidatingAdmissionPolicies
+// meant to replace them make the same decisions, by running a corpus of
+// AdmissionRequests through both in-process. It needs no cluster, so it can
+// run under go test before a webhook is retired.
+package parity
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	admissionv1 "k8s.io/api/admission/v1"
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/recorder"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/policy"
+)
+
+// Fixture is a request to send to a webhook and to its policy
+type Fixture struct {
+	// Name identifies the fixture in reports
+	Name    string                       `json:"name"`
+	Webhook string                       `json:"webhook"`
+	Request admissionv1.AdmissionRequest `json:"request"`
+}
+
+// Policy is a ValidatingAdmissionPolicy and the validation actions of its
+// binding
+type Policy struct {
+	Spec    admissionregv1.ValidatingAdmissionPolicySpec
+	Actions []admissionregv1.ValidationAction
+}
+
+// Result is the decision of a webhook and of its policy on a Fixture
+type Result struct {
+	Fixture Fixture
+	Webhook admissionv1.AdmissionResponse
+	Policy  policy.Decision
+	// Err is set if the Fixture could not be checked, for example because its
+	// webhook has no policy
+	Err error
+}
+
+// Diverged returns true if the webhook and the policy disagree on whether
+// the request is allowed. A webhook response which neither allows nor denies
+// the request has already had the webhook's failure policy applied.
+func (r Result) Diverged() bool {
+	return r.Err == nil && r.Webhook.Allowed != r.Policy.Allowed
+}
+
+// String describes both decisions and the request they were made on
+func (r Result) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: webhook %s", r.Fixture.Name, r.Fixture.Webhook)
+	if r.Err != nil {
+		fmt.Fprintf(&b, ": %v", r.Err)
+	} else {
+		webhookMessage := ""
+		if r.Webhook.Result != nil {
+			webhookMessage = r.Webhook.Result.Message
+		}
+		fmt.Fprintf(&b, " %s (%q), policy %s (%q)", verdict(r.Webhook.Allowed), webhookMessage, verdict(r.Policy.Allowed), r.Policy.Message)
+		if r.Policy.Err != nil {
+			fmt.Fprintf(&b, " after error: %v", r.Policy.Err)
+		}
+	}
+	request, err := json.MarshalIndent(r.Fixture.Request, "", "  ")
+	if err != nil {
+		request = []byte(err.Error())
+	}
+	fmt.Fprintf(&b, "\nrequest: %s", request)
+	return b.String()
+}
+
+func verdict(allowed bool) string {
+	if allowed {
+		return "allowed"
+	}
+	return "denied"
+}
+
+// LoadFixtures reads the fixtures in dir. A .yaml file is a list of Fixtures
+// and a .json file is written by the webhook server's -record flag, so that
+// recorded requests can be added to the corpus as they are.
+func LoadFixtures(dir string) ([]Fixture, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var fixtures []Fixture
+	for _, file := range files {
+		path := filepath.Join(dir, file.Name())
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		switch filepath.Ext(path) {
+		case ".yaml":
+			var list []Fixture
+			if err := yaml.Unmarshal(b, &list); err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			for i, fixture := range list {
+				fixture.Name = fmt.Sprintf("%s[%d] %s", file.Name(), i, fixture.Name)
+				fixtures = append(fixtures, fixture)
+			}
+		case ".json":
+			recordings, err := recorder.Read(bytes.NewReader(b))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			for i, recording := range recordings {
+				fixtures = append(fixtures, Fixture{
+					Name:    fmt.Sprintf("%s:%d", file.Name(), i+1),
+					Webhook: recording.Webhook,
+					Request: recording.Request,
+				})
+			}
+		}
+	}
+	return fixtures, nil
+}
+
+// Policies returns the policies for the webhooks in hooks. A webhook which
+// implements webhooks.AdmissionPolicyWebhook gets the policy it renders. Any
+// other webhook gets the ValidatingAdmissionPolicy in dir named after it,
+// e.g. namespace-validation.yaml, if there is one, bound with the Deny action.
+func Policies(hooks webhooks.RegisteredWebhooks, dir string) (map[string]Policy, error) {
+	policies := map[string]Policy{}
+	for name, factory := range hooks {
+		if spec, actions, ok := webhooks.AdmissionPolicySpec(factory()); ok {
+			policies[name] = Policy{Spec: spec, Actions: actions}
+		}
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range files {
+		name := strings.TrimSuffix(filepath.Base(path), ".yaml")
+		if _, ok := hooks[name]; !ok {
+			return nil, fmt.Errorf("%s: webhook %s is not registered", path, name)
+		}
+		if _, ok := policies[name]; ok {
+			return nil, fmt.Errorf("%s: webhook %s already renders its own policy", path, name)
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		vap := admissionregv1.ValidatingAdmissionPolicy{}
+		if err := yaml.Unmarshal(b, &vap); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		policies[name] = Policy{Spec: vap.Spec, Actions: []admissionregv1.ValidationAction{admissionregv1.Deny}}
+	}
+	return policies, nil
+}
+
+// Check asks each Fixture's webhook and its policy for their decision. The
+// webhook only sees requests which match its rules and object selector, as it
+// would behind the API server, and its decision is taken before any
+// enforcement mode is applied.
+func Check(ctx context.Context, fixtures []Fixture, hooks webhooks.RegisteredWebhooks, policies map[string]Policy) []Result {
+	built := map[string]webhooks.Webhook{}
+	evaluators := map[string]*policy.AdmissionPolicyEvaluator{}
+	evaluatorErrs := map[string]error{}
+	for name, p := range policies {
+		if factory, ok := hooks[name]; ok {
+			built[name] = factory()
+		}
+		evaluators[name], evaluatorErrs[name] = policy.NewAdmissionPolicyEvaluator(p.Spec, p.Actions)
+	}
+
+	results := make([]Result, 0, len(fixtures))
+	for _, fixture := range fixtures {
+		result := Result{Fixture: fixture}
+		hook, registered := built[fixture.Webhook]
+		evaluator, hasPolicy := evaluators[fixture.Webhook]
+		switch {
+		case !hasPolicy:
+			result.Err = fmt.Errorf("webhook %s has no policy", fixture.Webhook)
+		case !registered:
+			result.Err = fmt.Errorf("webhook %s is not registered", fixture.Webhook)
+		case evaluatorErrs[fixture.Webhook] != nil:
+			result.Err = fmt.Errorf("invalid policy: %w", evaluatorErrs[fixture.Webhook])
+		default:
+			request := admissionctl.Request{AdmissionRequest: fixture.Request}
+			result.Webhook, result.Err = authorize(ctx, hook, request)
+			result.Policy = evaluator.Evaluate(ctx, request)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// authorize returns the webhook's decision on request, in the same way the
+// dispatcher would, or allows it if the API server wouldn't send it. An
+// errored response is subject to the webhook's failure policy, as an
+// expression which can't be evaluated is to the policy's.
+func authorize(ctx context.Context, hook webhooks.Webhook, request admissionctl.Request) (resp admissionv1.AdmissionResponse, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("webhook panicked: %v", recovered)
+		}
+	}()
+	matched, err := policy.Matches(hook.Rules(), hook.ObjectSelector(), request)
+	if err != nil {
+		return resp, err
+	}
+	var ret admissionctl.Response
+	switch ctxHook, ok := hook.(webhooks.ContextWebhook); {
+	case !matched:
+		ret = admissionctl.Allowed("not sent to the webhook")
+	case !hook.Validate(request):
+		ret = admissionctl.Errored(http.StatusBadRequest, fmt.Errorf("not a valid webhook request"))
+	case ok:
+		ret = ctxHook.AuthorizedWithContext(ctx, request)
+	default:
+		ret = hook.Authorized(request)
+	}
+	if errored(ret) && hook.FailurePolicy() == admissionregv1.Ignore {
+		message := ""
+		if ret.Result != nil {
+			message = ret.Result.Message
+		}
+		ret = admissionctl.Allowed(fmt.Sprintf("ignored an error from the webhook: %s", message))
+	}
+	return ret.AdmissionResponse, nil
+}
+
+// errored returns true if resp neither allows nor denies the request, the
+// way the dispatcher classifies responses for its metrics: a denial is a 403
+// and any other code is an error, as is a 500 which allows
+func errored(resp admissionctl.Response) bool {
+	if resp.Result != nil && resp.Result.Code == http.StatusInternalServerError {
+		return true
+	}
+	return !resp.Allowed && (resp.Result == nil || resp.Result.Code != http.StatusForbidden)
+}
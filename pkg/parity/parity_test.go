@@ -0,0 +1,192 @@
+package parity
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/policy"
+)
+
+// TestParity checks every webhook with a policy makes the same decisions as
+// its policy on the fixtures in testdata/fixtures. Add fixtures, or requests
+// recorded with -record, there before retiring a webhook.
+func TestParity(t *testing.T) {
+	fixtures, err := LoadFixtures("testdata/fixtures")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	policies, err := Policies(webhooks.Webhooks, "testdata/policies")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	checked := map[string]int{}
+	for _, result := range Check(context.Background(), fixtures, webhooks.Webhooks, policies) {
+		switch {
+		case result.Err != nil:
+			t.Errorf("Couldn't check %s", result)
+		case result.Diverged():
+			t.Errorf("Diverged on %s", result)
+		}
+		checked[result.Fixture.Webhook]++
+	}
+	for name := range policies {
+		if checked[name] == 0 {
+			t.Errorf("Webhook %s has a policy but no fixtures", name)
+		}
+	}
+}
+
+const testPolicy = `
+name: test-validation
+doc: Only admins may delete widgets
+rules:
+- operations: ["DELETE"]
+  apiGroups: ["example.com"]
+  apiVersions: ["*"]
+  resources: ["widgets"]
+kinds:
+- group: example.com
+  kind: Widget
+exempt:
+  users: ["admin"]
+message: Only admins may delete widgets
+targets:
+  classic: true
+`
+
+func widgetFixture(name, username string, operation admissionv1.Operation) Fixture {
+	return Fixture{
+		Name:    name,
+		Webhook: "test-validation",
+		Request: admissionv1.AdmissionRequest{
+			UID:       "test-uid",
+			Kind:      metav1.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Widget"},
+			Resource:  metav1.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"},
+			Namespace: "widgets",
+			Name:      "widget-a",
+			Operation: operation,
+			UserInfo:  authenticationv1.UserInfo{Username: username},
+		},
+	}
+}
+
+func TestCheck(t *testing.T) {
+	p, err := policy.Parse([]byte(testPolicy))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	hooks := webhooks.RegisteredWebhooks{
+		"test-validation": func() webhooks.Webhook { return policy.NewWebhook(p) },
+	}
+	policies, err := Policies(hooks, "testdata/none")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	// A policy which forgets the exempt users
+	diverging := policies["test-validation"]
+	diverging.Spec.MatchConditions = nil
+
+	fixtures := []Fixture{
+		widgetFixture("admin deletes", "admin", admissionv1.Delete),
+		widgetFixture("someone deletes", "someone", admissionv1.Delete),
+		widgetFixture("admin updates", "admin", admissionv1.Update),
+		{Name: "unknown webhook", Webhook: "unknown-validation"},
+	}
+	tests := []struct {
+		name     string
+		policies map[string]Policy
+		diverged []string
+	}{
+		{
+			name:     "rendered policy",
+			policies: policies,
+		},
+		{
+			name:     "diverging policy",
+			policies: map[string]Policy{"test-validation": diverging},
+			diverged: []string{"admin deletes"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var diverged []string
+			for _, result := range Check(context.Background(), fixtures, hooks, test.policies) {
+				if result.Fixture.Webhook == "unknown-validation" {
+					if result.Err == nil || !strings.Contains(result.Err.Error(), "has no policy") {
+						t.Errorf("Expected an error for a webhook without a policy, got %v", result.Err)
+					}
+					continue
+				}
+				if result.Err != nil {
+					t.Fatalf("Unexpected error: %s", result.Err)
+				}
+				if result.Diverged() {
+					diverged = append(diverged, result.Fixture.Name)
+					if !strings.Contains(result.String(), `"username": "admin"`) {
+						t.Errorf("Expected the divergence to show the request, got %s", result)
+					}
+				}
+			}
+			if strings.Join(diverged, ",") != strings.Join(test.diverged, ",") {
+				t.Errorf("Expected %v to diverge, got %v", test.diverged, diverged)
+			}
+		})
+	}
+}
+
+func TestAuthorizeFailurePolicy(t *testing.T) {
+	tests := []struct {
+		name          string
+		failurePolicy string
+		kind          string
+		allowed       bool
+	}{
+		{"ignored error", "Ignore", "Gadget", true},
+		{"failed error", "Fail", "Gadget", false},
+		{"denial is not an error", "Ignore", "Widget", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p, err := policy.Parse([]byte(testPolicy + "failurePolicy: " + test.failurePolicy + "\n"))
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			fixture := widgetFixture(test.name, "someone", admissionv1.Delete)
+			// Validate rejects a request for any kind but Widget with a 400
+			fixture.Request.Kind.Kind = test.kind
+			resp, err := authorize(context.Background(), policy.NewWebhook(p), admissionctl.Request{AdmissionRequest: fixture.Request})
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if resp.Allowed != test.allowed {
+				t.Errorf("Expected allowed to be %v, got %v: %v", test.allowed, resp.Allowed, resp.Result)
+			}
+		})
+	}
+}
+
+func TestLoadFixtures(t *testing.T) {
+	fixtures, err := LoadFixtures("testdata/fixtures")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	var recorded *Fixture
+	for i := range fixtures {
+		if strings.HasPrefix(fixtures[i].Name, "recorded.json:") {
+			recorded = &fixtures[i]
+		}
+	}
+	if recorded == nil {
+		t.Fatalf("Expected the recorded requests to be loaded")
+	}
+	if recorded.Webhook != "techpreviewnoupgrade-validation" || recorded.Request.Kind.Kind != "FeatureGate" {
+		t.Errorf("Unexpected recorded fixture %v", recorded)
+	}
+}
@@ -0,0 +1,137 @@
+// Package recorder writes the AdmissionReviews the webhooks handle to disk, so
+// that they can be replayed through the webhooks later to reproduce or check a
+// decision.
+package recorder
+
+import (
+	"encoding/json"
+	"io"
+	"math/rand/v2"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+var log = logf.Log.WithName("recorder")
+
+// Recording is a request a webhook handled and the response it sent back
+type Recording struct {
+	Time     time.Time                     `json:"time"`
+	Webhook  string                        `json:"webhook"`
+	Outcome  string                        `json:"outcome"`
+	Request  admissionv1.AdmissionRequest  `json:"request"`
+	Response admissionv1.AdmissionResponse `json:"response"`
+}
+
+// Options selects which requests are recorded
+type Options struct {
+	// SampleRatio is the fraction of the selected requests which are recorded
+	SampleRatio float64
+	// Webhooks to record requests for. Empty means every webhook.
+	Webhooks []string
+	// Outcomes to record, as recorded in metrics. Empty means every outcome.
+	Outcomes []string
+	// MaxRecordings stops the Recorder once it has written this many
+	// Recordings, so that it cannot fill the disk. Zero means unlimited.
+	MaxRecordings int
+}
+
+// Recorder writes Recordings as newline-delimited JSON, with sensitive fields
+// redacted. It is safe for concurrent use.
+type Recorder struct {
+	options Options
+
+	mu       sync.Mutex
+	enc      *json.Encoder
+	closer   io.Closer
+	recorded int
+}
+
+// New returns a Recorder which writes to w
+func New(w io.Writer, options Options) *Recorder {
+	return &Recorder{
+		options: options,
+		enc:     json.NewEncoder(w),
+	}
+}
+
+// NewFromPath returns a Recorder which appends to the file at path
+func NewFromPath(path string, options Options) (*Recorder, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	r := New(f, options)
+	r.closer = f
+	return r, nil
+}
+
+// selects returns true if a request with outcome handled by webhook should be
+// recorded
+func (r *Recorder) selects(webhook, outcome string) bool {
+	if len(r.options.Webhooks) > 0 && !slices.Contains(r.options.Webhooks, webhook) {
+		return false
+	}
+	if len(r.options.Outcomes) > 0 && !slices.Contains(r.options.Outcomes, outcome) {
+		return false
+	}
+	return r.options.SampleRatio >= 1 || rand.Float64() < r.options.SampleRatio
+}
+
+// Record writes the request webhook handled and its response, if they are
+// selected. Failures are logged rather than returned since they must never
+// affect the admission decision.
+func (r *Recorder) Record(webhook string, request admissionctl.Request, resp admissionctl.Response, outcome string) {
+	if !r.selects(webhook, outcome) {
+		return
+	}
+	recording := Recording{
+		Time:     time.Now().UTC(),
+		Webhook:  webhook,
+		Outcome:  outcome,
+		Request:  Redact(request.AdmissionRequest),
+		Response: resp.AdmissionResponse,
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.options.MaxRecordings > 0 && r.recorded >= r.options.MaxRecordings {
+		return
+	}
+	if err := r.enc.Encode(recording); err != nil {
+		log.Error(err, "Failed to write recording", "uid", request.UID, "webhook", webhook)
+		return
+	}
+	r.recorded++
+	if r.recorded == r.options.MaxRecordings {
+		log.Info("Stopped recording, the maximum number of recordings has been written", "maxRecordings", r.options.MaxRecordings)
+	}
+}
+
+// Close closes the underlying file, if there is one
+func (r *Recorder) Close() error {
+	if r.closer == nil {
+		return nil
+	}
+	return r.closer.Close()
+}
+
+// Read reads the Recordings written by a Recorder
+func Read(rd io.Reader) ([]Recording, error) {
+	var recordings []Recording
+	dec := json.NewDecoder(rd)
+	for {
+		var recording Recording
+		if err := dec.Decode(&recording); err == io.EOF {
+			return recordings, nil
+		} else if err != nil {
+			return nil, err
+		}
+		recordings = append(recordings, recording)
+	}
+}
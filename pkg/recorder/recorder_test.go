@@ -0,0 +1,149 @@
+package recorder
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks"
+)
+
+// prefixWebhook denies any request for an object named with its prefix
+type prefixWebhook struct {
+	prefix string
+}
+
+func (p *prefixWebhook) Authorized(request admissionctl.Request) admissionctl.Response {
+	if strings.HasPrefix(request.Name, p.prefix) {
+		return admissionctl.Denied("reserved name")
+	}
+	return admissionctl.Allowed("")
+}
+func (p *prefixWebhook) GetURI() string                             { return "/test-hook" }
+func (p *prefixWebhook) Validate(request admissionctl.Request) bool { return request.Name != "" }
+func (p *prefixWebhook) Name() string                               { return "test-validation" }
+func (p *prefixWebhook) FailurePolicy() admissionregv1.FailurePolicyType {
+	return admissionregv1.Ignore
+}
+func (p *prefixWebhook) MatchPolicy() admissionregv1.MatchPolicyType {
+	return admissionregv1.Equivalent
+}
+func (p *prefixWebhook) Rules() []admissionregv1.RuleWithOperations { return nil }
+func (p *prefixWebhook) ObjectSelector() *metav1.LabelSelector      { return nil }
+func (p *prefixWebhook) SideEffects() admissionregv1.SideEffectClass {
+	return admissionregv1.SideEffectClassNone
+}
+func (p *prefixWebhook) TimeoutSeconds() int32                      { return 2 }
+func (p *prefixWebhook) Doc() string                                { return "" }
+func (p *prefixWebhook) SyncSetLabelSelector() metav1.LabelSelector { return metav1.LabelSelector{} }
+func (p *prefixWebhook) ClassicEnabled() bool                       { return true }
+func (p *prefixWebhook) HypershiftEnabled() bool                    { return false }
+
+func request(name string) admissionctl.Request {
+	return admissionctl.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+		UID:       types.UID("uid-" + name),
+		Name:      name,
+		Operation: admissionv1.Create,
+	}}
+}
+
+func TestRecorder(t *testing.T) {
+	buf := &bytes.Buffer{}
+	r := New(buf, Options{
+		SampleRatio:   1,
+		Webhooks:      []string{"test-validation"},
+		Outcomes:      []string{"denied"},
+		MaxRecordings: 2,
+	})
+	denied := admissionctl.Denied("reserved name")
+	r.Record("other-validation", request("a"), denied, "denied")
+	r.Record("test-validation", request("b"), admissionctl.Allowed(""), "allowed")
+	r.Record("test-validation", request("c"), denied, "denied")
+	r.Record("test-validation", request("d"), denied, "denied")
+	r.Record("test-validation", request("e"), denied, "denied")
+
+	recordings, err := Read(buf)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if len(recordings) != 2 || recordings[0].Request.Name != "c" || recordings[1].Request.Name != "d" {
+		t.Fatalf("expected the first 2 selected requests to be recorded, got %+v", recordings)
+	}
+	if recordings[0].Response.Allowed || recordings[0].Response.Result.Message != "reserved name" || recordings[0].Outcome != "denied" {
+		t.Errorf("expected the response to be recorded, got %+v", recordings[0])
+	}
+}
+
+func TestRecorderSampleRatio(t *testing.T) {
+	buf := &bytes.Buffer{}
+	r := New(buf, Options{SampleRatio: 0})
+	r.Record("test-validation", request("a"), admissionctl.Allowed(""), "allowed")
+	if buf.Len() != 0 {
+		t.Errorf("expected nothing to be recorded with a sample ratio of 0, got %s", buf.String())
+	}
+}
+
+func TestReplay(t *testing.T) {
+	buf := &bytes.Buffer{}
+	r := New(buf, Options{SampleRatio: 1})
+	// Recorded when the webhook reserved the "kube-" prefix
+	before := &prefixWebhook{prefix: "kube-"}
+	for _, name := range []string{"kube-system", "openshift-config", ""} {
+		req := request(name)
+		r.Record("test-validation", req, before.Authorized(req), "")
+	}
+	r.Record("removed-validation", request("x"), admissionctl.Allowed(""), "allowed")
+	recordings, err := Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The webhook now reserves the "openshift-" prefix instead
+	hooks := webhooks.RegisteredWebhooks{
+		"test-validation": func() webhooks.Webhook { return &prefixWebhook{prefix: "openshift-"} },
+	}
+	results := Replay(context.Background(), recordings, hooks)
+	if len(results) != 4 {
+		t.Fatalf("expected a result per recording, got %d", len(results))
+	}
+	for i, want := range []bool{true, true, true, false} {
+		if got := results[i].Changed(); got != want {
+			t.Errorf("expected Changed() = %v for %s, got %v (replayed %+v)", want, recordings[i].Request.Name, got, results[i].Replayed)
+		}
+	}
+	if results[3].Err == nil {
+		t.Error("expected an error for a webhook which is no longer registered")
+	}
+}
+
+func TestResultChanged(t *testing.T) {
+	allowed := admissionv1.AdmissionResponse{Allowed: true}
+	tests := []struct {
+		name     string
+		recorded admissionv1.AdmissionResponse
+		replayed admissionctl.Response
+		want     bool
+	}{
+		{name: "allowed with a message", recorded: allowed, replayed: admissionctl.Allowed("RBAC allowed"), want: false},
+		{name: "now denied", recorded: allowed, replayed: admissionctl.Denied("no"), want: true},
+		{name: "now errored", recorded: admissionctl.Denied("no").AdmissionResponse, replayed: admissionctl.Errored(500, errors.New("no")), want: true},
+		{name: "denied for another reason", recorded: admissionctl.Denied("no").AdmissionResponse, replayed: admissionctl.Denied("not like that"), want: false},
+		{name: "patched differently", recorded: allowed, replayed: admissionctl.Response{AdmissionResponse: admissionv1.AdmissionResponse{Allowed: true, Patch: []byte("[]")}}, want: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := Result{Recording: Recording{Response: test.recorded}, Replayed: test.replayed.AdmissionResponse}
+			if got := result.Changed(); got != test.want {
+				t.Errorf("Changed() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
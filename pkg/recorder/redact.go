@@ -0,0 +1,101 @@
+package recorder
+
+import (
+	"encoding/json"
+	"maps"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Redacted replaces the value of a sensitive field
+const Redacted = "REDACTED"
+
+// lastAppliedAnnotation holds a copy of the object as it was last applied by
+// kubectl, so it is as sensitive as the object itself
+const lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// sensitiveFields are the paths to the fields of each kind whose values are
+// redacted. The values of a map are redacted but its keys are kept.
+var sensitiveFields = map[schema.GroupKind][][]string{
+	{Kind: "Secret"}: {
+		{"data"},
+		{"stringData"},
+		{"metadata", "annotations", lastAppliedAnnotation},
+	},
+	{Group: "authentication.k8s.io", Kind: "TokenReview"}: {
+		{"spec", "token"},
+	},
+	{Group: "authentication.k8s.io", Kind: "TokenRequest"}: {
+		{"status", "token"},
+	},
+}
+
+// sensitiveExtra are the keys of UserInfo.Extra whose values are redacted
+var sensitiveExtra = []string{
+	"authentication.kubernetes.io/credential-id",
+}
+
+// Redact returns a copy of request with the values of its sensitive fields
+// replaced by Redacted. An object which cannot be parsed is dropped, since it
+// cannot be known not to hold anything sensitive.
+func Redact(request admissionv1.AdmissionRequest) admissionv1.AdmissionRequest {
+	gk := schema.GroupKind{Group: request.Kind.Group, Kind: request.Kind.Kind}
+	request.Object = redactObject(request.Object, sensitiveFields[gk])
+	request.OldObject = redactObject(request.OldObject, sensitiveFields[gk])
+
+	if len(request.UserInfo.Extra) > 0 {
+		request.UserInfo.Extra = maps.Clone(request.UserInfo.Extra)
+		for _, key := range sensitiveExtra {
+			if _, ok := request.UserInfo.Extra[key]; ok {
+				request.UserInfo.Extra[key] = authenticationv1.ExtraValue{Redacted}
+			}
+		}
+	}
+	return request
+}
+
+func redactObject(object runtime.RawExtension, paths [][]string) runtime.RawExtension {
+	if len(paths) == 0 || (len(object.Raw) == 0 && object.Object == nil) {
+		return object
+	}
+	raw, err := object.MarshalJSON()
+	if err != nil {
+		return runtime.RawExtension{}
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return runtime.RawExtension{}
+	}
+	for _, path := range paths {
+		redactPath(fields, path)
+	}
+	raw, err = json.Marshal(fields)
+	if err != nil {
+		return runtime.RawExtension{}
+	}
+	return runtime.RawExtension{Raw: raw}
+}
+
+// redactPath replaces the value at path in fields
+func redactPath(fields map[string]interface{}, path []string) {
+	value, ok := fields[path[0]]
+	if !ok {
+		return
+	}
+	if len(path) > 1 {
+		if child, ok := value.(map[string]interface{}); ok {
+			redactPath(child, path[1:])
+		}
+		return
+	}
+	if m, ok := value.(map[string]interface{}); ok {
+		for key := range m {
+			m[key] = Redacted
+		}
+		return
+	}
+	fields[path[0]] = Redacted
+}
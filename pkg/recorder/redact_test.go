@@ -0,0 +1,104 @@
+package recorder
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name   string
+		kind   metav1.GroupVersionKind
+		object string
+		want   map[string]interface{}
+	}{
+		{
+			name:   "secret",
+			kind:   metav1.GroupVersionKind{Version: "v1", Kind: "Secret"},
+			object: `{"kind":"Secret","metadata":{"name":"s","annotations":{"kubectl.kubernetes.io/last-applied-configuration":"{\"data\":{\"password\":\"aHVudGVyMg==\"}}","owner":"me"}},"data":{"password":"aHVudGVyMg=="},"stringData":{"token":"abc"},"type":"Opaque"}`,
+			want: map[string]interface{}{
+				"kind": "Secret",
+				"metadata": map[string]interface{}{
+					"name":        "s",
+					"annotations": map[string]interface{}{lastAppliedAnnotation: Redacted, "owner": "me"},
+				},
+				"data":       map[string]interface{}{"password": Redacted},
+				"stringData": map[string]interface{}{"token": Redacted},
+				"type":       "Opaque",
+			},
+		},
+		{
+			name:   "token review",
+			kind:   metav1.GroupVersionKind{Group: "authentication.k8s.io", Version: "v1", Kind: "TokenReview"},
+			object: `{"kind":"TokenReview","spec":{"token":"abc","audiences":["api"]}}`,
+			want: map[string]interface{}{
+				"kind": "TokenReview",
+				"spec": map[string]interface{}{"token": Redacted, "audiences": []interface{}{"api"}},
+			},
+		},
+		{
+			name:   "configmap is not redacted",
+			kind:   metav1.GroupVersionKind{Version: "v1", Kind: "ConfigMap"},
+			object: `{"kind":"ConfigMap","data":{"key":"value"}}`,
+			want: map[string]interface{}{
+				"kind": "ConfigMap",
+				"data": map[string]interface{}{"key": "value"},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := admissionv1.AdmissionRequest{
+				Kind:      test.kind,
+				Object:    runtime.RawExtension{Raw: []byte(test.object)},
+				OldObject: runtime.RawExtension{Raw: []byte(test.object)},
+			}
+			redacted := Redact(request)
+			for _, object := range []runtime.RawExtension{redacted.Object, redacted.OldObject} {
+				var got map[string]interface{}
+				if err := json.Unmarshal(object.Raw, &got); err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(got, test.want) {
+					t.Errorf("expected %v, got %v", test.want, got)
+				}
+			}
+			if string(request.Object.Raw) != test.object {
+				t.Error("expected the original request not to be modified")
+			}
+		})
+	}
+}
+
+func TestRedactUnparseableSecret(t *testing.T) {
+	redacted := Redact(admissionv1.AdmissionRequest{
+		Kind:   metav1.GroupVersionKind{Version: "v1", Kind: "Secret"},
+		Object: runtime.RawExtension{Raw: []byte(`{"data":`)},
+	})
+	if len(redacted.Object.Raw) != 0 {
+		t.Errorf("expected an unparseable Secret to be dropped, got %s", redacted.Object.Raw)
+	}
+}
+
+func TestRedactUserInfoExtra(t *testing.T) {
+	extra := map[string]authenticationv1.ExtraValue{
+		"authentication.kubernetes.io/credential-id": {"JTI=abc"},
+		"scopes.authorization.openshift.io":          {"user:full"},
+	}
+	redacted := Redact(admissionv1.AdmissionRequest{UserInfo: authenticationv1.UserInfo{Username: "someone", Extra: extra}})
+	if got := redacted.UserInfo.Extra["authentication.kubernetes.io/credential-id"]; !reflect.DeepEqual(got, authenticationv1.ExtraValue{Redacted}) {
+		t.Errorf("expected the credential ID to be redacted, got %v", got)
+	}
+	if got := redacted.UserInfo.Extra["scopes.authorization.openshift.io"]; !reflect.DeepEqual(got, authenticationv1.ExtraValue{"user:full"}) {
+		t.Errorf("expected scopes to be kept, got %v", got)
+	}
+	if extra["authentication.kubernetes.io/credential-id"][0] != "JTI=abc" {
+		t.Error("expected the original request not to be modified")
+	}
+}
@@ -0,0 +1,86 @@
+package recorder
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks"
+)
+
+// Result is a Recording's decision as it was recorded and as it is now
+type Result struct {
+	Recording Recording
+	Replayed  admissionv1.AdmissionResponse
+	// Err is set if the Recording could not be replayed, for example because
+	// its webhook is no longer registered
+	Err error
+}
+
+// Changed returns true if the replayed decision differs from the recorded
+// one: it allows a request which was rejected or the other way around, it
+// rejects it with a different code or it mutates it differently
+func (r Result) Changed() bool {
+	if r.Err != nil {
+		return false
+	}
+	recorded, replayed := r.Recording.Response, r.Replayed
+	if recorded.Allowed != replayed.Allowed {
+		return true
+	}
+	if !recorded.Allowed {
+		return code(recorded) != code(replayed)
+	}
+	return !bytes.Equal(recorded.Patch, replayed.Patch)
+}
+
+func code(resp admissionv1.AdmissionResponse) int32 {
+	if resp.Result == nil {
+		return 0
+	}
+	return resp.Result.Code
+}
+
+// Replay asks the webhooks in hooks for their decision on each recorded
+// request, in the same way the dispatcher does. Fields which were redacted
+// when the request was recorded are seen by the webhooks as Redacted.
+func Replay(ctx context.Context, recordings []Recording, hooks webhooks.RegisteredWebhooks) []Result {
+	built := make(map[string]webhooks.Webhook, len(hooks))
+	for name, factory := range hooks {
+		built[name] = factory()
+	}
+	results := make([]Result, 0, len(recordings))
+	for _, recording := range recordings {
+		result := Result{Recording: recording}
+		if hook, ok := built[recording.Webhook]; ok {
+			result.Replayed, result.Err = replay(ctx, hook, recording)
+		} else {
+			result.Err = fmt.Errorf("webhook %s is not registered", recording.Webhook)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+func replay(ctx context.Context, hook webhooks.Webhook, recording Recording) (resp admissionv1.AdmissionResponse, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("webhook panicked: %v", recovered)
+		}
+	}()
+	request := admissionctl.Request{AdmissionRequest: recording.Request}
+	var ret admissionctl.Response
+	switch ctxHook, ok := hook.(webhooks.ContextWebhook); {
+	case !hook.Validate(request):
+		ret = admissionctl.Errored(http.StatusBadRequest, fmt.Errorf("not a valid webhook request"))
+	case ok:
+		ret = ctxHook.AuthorizedWithContext(ctx, request)
+	default:
+		ret = hook.Authorized(request)
+	}
+	return ret.AdmissionResponse, nil
+}
@@ -0,0 +1,154 @@
+// Package tracing sets up optional OpenTelemetry tracing of admission
+// requests. Until Setup is called with an exporter, spans are not recorded.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Exporter is where spans are sent
+type Exporter string
+
+const (
+	// NoExporter disables tracing
+	NoExporter Exporter = ""
+	// OTLPExporter sends spans to an OTLP/HTTP collector
+	OTLPExporter Exporter = "otlp"
+	// FileExporter writes spans to a file as JSON, one object per span
+	FileExporter Exporter = "file"
+
+	// ServiceName identifies this server's spans
+	ServiceName = "managed-cluster-validating-webhooks"
+
+	tracerName = "github.com/openshift/managed-cluster-validating-webhooks"
+)
+
+// Config configures Setup
+type Config struct {
+	Exporter Exporter
+	// Endpoint is the OTLP/HTTP collector URL. If empty the
+	// OTEL_EXPORTER_OTLP_ENDPOINT environment variable, or the OTLP default,
+	// is used.
+	Endpoint string
+	// Path is the file FileExporter writes to
+	Path string
+	// SampleRatio is the fraction of requests without a sampled parent which
+	// are traced
+	SampleRatio float64
+}
+
+// ParseExporter parses an Exporter, where "none" and empty disable tracing
+func ParseExporter(s string) (Exporter, error) {
+	switch exporter := Exporter(s); exporter {
+	case NoExporter, "none":
+		return NoExporter, nil
+	case OTLPExporter, FileExporter:
+		return exporter, nil
+	default:
+		return "", fmt.Errorf("unknown tracing exporter %q, must be one of none, %s or %s", s, OTLPExporter, FileExporter)
+	}
+}
+
+// Setup installs the global TracerProvider and W3C trace context propagator
+// described by config. The returned function flushes any buffered spans and
+// must be called before the process exits.
+func Setup(ctx context.Context, config Config) (shutdown func(context.Context) error, err error) {
+	// The propagator is installed even when tracing is disabled, so that
+	// trace context is passed on to the API server
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	switch config.Exporter {
+	case NoExporter:
+		return func(context.Context) error { return nil }, nil
+	case OTLPExporter:
+		var opts []otlptracehttp.Option
+		if config.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(config.Endpoint))
+		}
+		exporter, err = otlptracehttp.New(ctx, opts...)
+	case FileExporter:
+		var f *os.File
+		if f, err = os.OpenFile(config.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600); err != nil {
+			return nil, fmt.Errorf("couldn't open trace file %s: %w", config.Path, err)
+		}
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(f))
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", config.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("couldn't create %s trace exporter: %w", config.Exporter, err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", ServiceName))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Start starts a span which is a child of any span in ctx
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// Extract returns ctx with the trace context of the caller, from the
+// traceparent header of r
+func Extract(ctx context.Context, r *http.Request) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(r.Header))
+}
+
+// RecordError marks span as failed because of err
+func RecordError(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// WrapTransport wraps rt so that every request made through it, such as a
+// webhook's call to the API server, gets a span of its own and carries the
+// trace context to the server. It has the signature of rest.Config.Wrap.
+func WrapTransport(rt http.RoundTripper) http.RoundTripper {
+	return &transport{next: rt}
+}
+
+type transport struct {
+	next http.RoundTripper
+}
+
+func (t *transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	ctx, span := Start(r.Context(), "HTTP "+r.Method,
+		attribute.String("http.request.method", r.Method),
+		attribute.String("url.path", r.URL.Path),
+		attribute.String("server.address", r.URL.Host),
+	)
+	defer span.End()
+	// A RoundTripper must not modify the request it is given
+	r = r.Clone(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(r.Header))
+
+	resp, err := t.next.RoundTrip(r)
+	if err != nil {
+		RecordError(span, err)
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	if resp.StatusCode >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, resp.Status)
+	}
+	return resp, nil
+}
@@ -0,0 +1,123 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordSpans installs a TracerProvider which records every span
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func TestParseExporter(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Exporter
+		wantErr bool
+	}{
+		{input: "", want: NoExporter},
+		{input: "none", want: NoExporter},
+		{input: "otlp", want: OTLPExporter},
+		{input: "file", want: FileExporter},
+		{input: "jaeger", wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := ParseExporter(test.input)
+		if (err != nil) != test.wantErr {
+			t.Errorf("ParseExporter(%q) error = %v, wantErr %v", test.input, err, test.wantErr)
+		}
+		if got != test.want {
+			t.Errorf("ParseExporter(%q) = %q, want %q", test.input, got, test.want)
+		}
+	}
+}
+
+func TestSetupFileExporter(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	path := filepath.Join(t.TempDir(), "traces.json")
+	shutdown, err := Setup(context.Background(), Config{Exporter: FileExporter, Path: path, SampleRatio: 1})
+	if err != nil {
+		t.Fatalf("Setup() error = %v", err)
+	}
+	_, span := Start(context.Background(), "admission test-validation", attribute.String("webhook", "test-validation"))
+	span.End()
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown error = %v", err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"Name":"admission test-validation"`, `"Value":"test-validation"`, ServiceName} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("expected %s in the trace file, got %s", want, b)
+		}
+	}
+}
+
+func TestWrapTransport(t *testing.T) {
+	recorder := recordSpans(t)
+	if _, err := Setup(context.Background(), Config{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	ctx, parent := Start(context.Background(), "authorize")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/apis/image.openshift.io/v1/imagestreamtags", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := (&http.Client{Transport: WrapTransport(http.DefaultTransport)}).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	parent.End()
+
+	if req.Header.Get("traceparent") != "" {
+		t.Error("expected the caller's request not to be modified")
+	}
+	if !strings.Contains(traceparent, parent.SpanContext().TraceID().String()) {
+		t.Errorf("expected the trace context to be sent to the server, got traceparent %q", traceparent)
+	}
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	call := spans[0]
+	if call.Name() != "HTTP GET" || call.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("expected a child HTTP GET span, got %s with parent %s", call.Name(), call.Parent().SpanID())
+	}
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, attr := range call.Attributes() {
+		attrs[attr.Key] = attr.Value
+	}
+	if attrs["url.path"].AsString() != "/apis/image.openshift.io/v1/imagestreamtags" || attrs["http.response.status_code"].AsInt64() != http.StatusNotFound {
+		t.Errorf("unexpected span attributes %v", call.Attributes())
+	}
+}
@@ -0,0 +1,11 @@
+package webhooks
+
+import (
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/policy"
+)
+
+func init() {
+	for _, p := range policy.Embedded() {
+		Register(p.Name, func() Webhook { return policy.NewWebhook(p) })
+	}
+}
@@ -0,0 +1,34 @@
+package webhooks
+
+import (
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+)
+
+// AdmissionPolicySpec returns the spec of the ValidatingAdmissionPolicy which
+// replaces hook and the validation actions of its binding, or false if hook
+// can't be expressed as one. The match constraints and failure policy are
+// those of the hook's webhook configuration.
+func AdmissionPolicySpec(hook Webhook) (admissionregv1.ValidatingAdmissionPolicySpec, []admissionregv1.ValidationAction, bool) {
+	policyHook, ok := hook.(AdmissionPolicyWebhook)
+	if !ok {
+		return admissionregv1.ValidatingAdmissionPolicySpec{}, nil, false
+	}
+	spec, ok := policyHook.AdmissionPolicy()
+	if !ok {
+		return admissionregv1.ValidatingAdmissionPolicySpec{}, nil, false
+	}
+	failPolicy := hook.FailurePolicy()
+	matchPolicy := hook.MatchPolicy()
+
+	rules := make([]admissionregv1.NamedRuleWithOperations, 0, len(hook.Rules()))
+	for _, rule := range hook.Rules() {
+		rules = append(rules, admissionregv1.NamedRuleWithOperations{RuleWithOperations: rule})
+	}
+	spec.MatchConstraints = &admissionregv1.MatchResources{
+		ObjectSelector: hook.ObjectSelector(),
+		ResourceRules:  rules,
+		MatchPolicy:    &matchPolicy,
+	}
+	spec.FailurePolicy = &failPolicy
+	return spec, policyHook.ValidationActions(), true
+}
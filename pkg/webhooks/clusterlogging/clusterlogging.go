@@ -1,6 +1,7 @@
 package clusterlogging
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"os"
@@ -8,6 +9,8 @@ import (
 	"strconv"
 
 	cl "github.com/openshift/cluster-logging-operator/apis/logging/v1"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/explain"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/helpers"
 	utils "github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
 	admissionregv1 "k8s.io/api/admissionregistration/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -89,6 +92,11 @@ func (s *ClusterloggingWebhook) Validate(req admissionctl.Request) bool {
 	return valid
 }
 
+// SelfTestKind implements SelfTestWebhook interface
+func (s *ClusterloggingWebhook) SelfTestKind() metav1.GroupKind {
+	return metav1.GroupKind{Group: "logging.openshift.io", Kind: ClusterLoggingKind}
+}
+
 type TimeUnit string
 
 type retentionPolicyValidator struct {
@@ -98,20 +106,23 @@ type retentionPolicyValidator struct {
 	upperBound TimeUnit
 }
 
-func (r *retentionPolicyValidator) checkPolicy(retentionPolicy *cl.RetentionPolicySpec) (bool, admissionctl.Response) {
-	isAllowed, deniedMessage, err := r.isAllowed(retentionPolicy)
+func (r *retentionPolicyValidator) checkPolicy(trace *explain.Trace, retentionPolicy *cl.RetentionPolicySpec) (bool, admissionctl.Response) {
+	isAllowed, code, err := r.isAllowed(retentionPolicy)
 	if err != nil {
 		return false, admissionctl.Errored(http.StatusBadRequest, err)
 	}
-	if !isAllowed {
-		return false, admissionctl.Denied(deniedMessage)
+	if !trace.Recordf(isAllowed, "%s retention is set between %s and %s", r.name, r.lowerBound, r.upperBound) {
+		ret := admissionctl.Denied("")
+		utils.WithCode(code, helpers.Args{"Name": r.name, "Hint": r.hint})(&ret)
+		return false, ret
 	}
 	return true, admissionctl.Allowed("Allowed to create ClusterLogging")
 }
 
-func (r *retentionPolicyValidator) isAllowed(retentionPolicy *cl.RetentionPolicySpec) (bool, string, error) {
+// isAllowed returns the code of the denial if retentionPolicy is not allowed
+func (r *retentionPolicyValidator) isAllowed(retentionPolicy *cl.RetentionPolicySpec) (bool, helpers.Code, error) {
 	if retentionPolicy == nil {
-		return false, "The entered retention policy is not allowed. " + r.name + " must not be unset. Hint: " + r.hint, nil
+		return false, helpers.CodeClusterLoggingRetentionUnset, nil
 	}
 
 	isAllowedRetentionDaysLower, err := le(r.lowerBound, TimeUnit(retentionPolicy.MaxAge))
@@ -126,7 +137,7 @@ func (r *retentionPolicyValidator) isAllowed(retentionPolicy *cl.RetentionPolicy
 	}
 
 	if !isAllowedRetentionDaysLower || !isAllowedRetentionDaysUpper {
-		return false, "The entered RetentionPolicy " + r.name + " is not allowed. " + r.hint, nil
+		return false, helpers.CodeClusterLoggingRetentionInvalid, nil
 	}
 
 	return true, "", nil
@@ -134,12 +145,18 @@ func (r *retentionPolicyValidator) isAllowed(retentionPolicy *cl.RetentionPolicy
 
 // Authorized implements Webhook interface
 func (s *ClusterloggingWebhook) Authorized(request admissionctl.Request) admissionctl.Response {
-	r := s.authorized(request)
+	return s.AuthorizedWithContext(context.Background(), request)
+}
+
+// AuthorizedWithContext implements ContextWebhook interface
+func (s *ClusterloggingWebhook) AuthorizedWithContext(ctx context.Context, request admissionctl.Request) admissionctl.Response {
+	r := s.authorized(ctx, request)
 	r.UID = request.AdmissionRequest.UID
 	return r
 }
 
-func (s *ClusterloggingWebhook) authorized(request admissionctl.Request) admissionctl.Response {
+func (s *ClusterloggingWebhook) authorized(ctx context.Context, request admissionctl.Request) admissionctl.Response {
+	trace := explain.FromContext(ctx)
 	clusterLogging, err := s.renderClusterLogging(request)
 	if err != nil {
 		return admissionctl.Errored(http.StatusBadRequest, err)
@@ -153,7 +170,7 @@ func (s *ClusterloggingWebhook) authorized(request admissionctl.Request) admissi
 		lowerBound: TimeUnit("1h"),
 		upperBound: TimeUnit("7d"),
 	}
-	ok, ret := appValidator.checkPolicy(retentionPolicy.App)
+	ok, ret := appValidator.checkPolicy(trace, retentionPolicy.App)
 	if !ok {
 		return ret
 	}
@@ -164,7 +181,7 @@ func (s *ClusterloggingWebhook) authorized(request admissionctl.Request) admissi
 		lowerBound: TimeUnit("1h"),
 		upperBound: TimeUnit("1h"),
 	}
-	ok, ret = infraValidator.checkPolicy(retentionPolicy.Infra)
+	ok, ret = infraValidator.checkPolicy(trace, retentionPolicy.Infra)
 	if !ok {
 		return ret
 	}
@@ -175,7 +192,7 @@ func (s *ClusterloggingWebhook) authorized(request admissionctl.Request) admissi
 		lowerBound: TimeUnit("1h"),
 		upperBound: TimeUnit("1h"),
 	}
-	ok, ret = auditValidator.checkPolicy(retentionPolicy.Audit)
+	ok, ret = auditValidator.checkPolicy(trace, retentionPolicy.Audit)
 	if !ok {
 		return ret
 	}
@@ -1,12 +1,16 @@
 package clusterrole
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"os"
 	"slices"
 	"strings"
 
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/allowlist"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/explain"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/helpers"
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
 	admissionv1 "k8s.io/api/admission/v1"
 	admissionregv1 "k8s.io/api/admissionregistration/v1"
@@ -52,15 +56,22 @@ var (
 		"system:kube-controller-manager",
 	}
 
-	// Users allowed to delete protected ClusterRoles
-	allowedUsers = []string{
-		"backplane-cluster-admin",
-		"system:admin",
+	// Users and groups allowed to delete protected ClusterRoles
+	allowedPrincipals = utils.Principals{
+		Users: []string{
+			"backplane-cluster-admin",
+			"system:admin",
+		},
+		Groups: []string{
+			"system:serviceaccounts:openshift-backplane-srep",
+		},
 	}
 
-	// Groups allowed to delete protected ClusterRoles
-	allowedGroups = []string{
-		"system:serviceaccounts:openshift-backplane-srep",
+	// systemUsers are allowed to delete any ClusterRole, except for
+	// system:admin which is the cluster's kubeadmin-like user
+	systemUsers = utils.Principals{
+		UserPrefixes:  utils.SystemUsers.UserPrefixes,
+		ExcludedUsers: []string{"system:admin"},
 	}
 )
 
@@ -89,27 +100,25 @@ func NewWebhook() *ClusterRoleWebHook {
 
 // Authorized implements Webhook interface
 func (s *ClusterRoleWebHook) Authorized(request admissionctl.Request) admissionctl.Response {
-	return s.authorized(request)
+	return s.authorized(context.Background(), request)
+}
+
+// AuthorizedWithContext implements ContextWebhook interface
+func (s *ClusterRoleWebHook) AuthorizedWithContext(ctx context.Context, request admissionctl.Request) admissionctl.Response {
+	return s.authorized(ctx, request)
 }
 
-func (s *ClusterRoleWebHook) authorized(request admissionctl.Request) admissionctl.Response {
+func (s *ClusterRoleWebHook) authorized(ctx context.Context, request admissionctl.Request) admissionctl.Response {
 	var ret admissionctl.Response
 
-	if request.AdmissionRequest.UserInfo.Username == "system:unauthenticated" {
+	if utils.UnauthenticatedUsers.ExplainsRequest(ctx, "unauthenticated users", request) {
 		log.Info("system:unauthenticated made a webhook request. Check RBAC rules", "request", request.AdmissionRequest)
-		ret = admissionctl.Denied("Unauthenticated")
-		ret.UID = request.AdmissionRequest.UID
-		return ret
-	}
-
-	if strings.HasPrefix(request.AdmissionRequest.UserInfo.Username, "system:") && request.AdmissionRequest.UserInfo.Username != "system:admin" {
-		ret = admissionctl.Allowed("authenticated system: users are allowed")
-		ret.UID = request.AdmissionRequest.UID
+		ret = utils.WebhookResponse(request, false, "", utils.WithCode(helpers.CodeUnauthenticated, nil))
 		return ret
 	}
 
-	if strings.HasPrefix(request.AdmissionRequest.UserInfo.Username, "kube:") {
-		ret = admissionctl.Allowed("kube: users are allowed")
+	if match, ok := systemUsers.ExplainRequest(ctx, "system users", request); ok {
+		ret = admissionctl.Allowed(fmt.Sprintf("authenticated system users are allowed: %s", match))
 		ret.UID = request.AdmissionRequest.UID
 		return ret
 	}
@@ -122,13 +131,13 @@ func (s *ClusterRoleWebHook) authorized(request admissionctl.Request) admissionc
 
 	log.Info(fmt.Sprintf("Found clusterrole: %v", clusterRole.Name))
 
-	if isProtectedClusterRole(clusterRole) && !isAllowedUserGroup(request) {
+	protected := explain.FromContext(ctx).Recordf(isProtectedClusterRole(clusterRole), "ClusterRole is in %q or has prefix %s", protectedClusterRoles, backplanePrefix)
+	if protected && !isAllowedUserGroup(ctx, request) {
 		switch request.Operation {
 		case admissionv1.Delete:
 			log.Info(fmt.Sprintf("Deleting operation detected on ClusterRole: %v", clusterRole.Name))
 
-			ret = admissionctl.Denied(fmt.Sprintf("Deleting ClusterRole %v is not allowed", clusterRole.Name))
-			ret.UID = request.AdmissionRequest.UID
+			ret = utils.WebhookResponse(request, false, "", utils.WithCode(helpers.CodeClusterRoleDelete, helpers.Args{"Name": clusterRole.Name}))
 			return ret
 		}
 	}
@@ -155,16 +164,9 @@ func (s *ClusterRoleWebHook) renderClusterRole(request admissionctl.Request) (*r
 }
 
 // isAllowedUserGroup checks if the user or group is allowed to perform the action
-func isAllowedUserGroup(request admissionctl.Request) bool {
-	if slices.Contains(allowedUsers, request.UserInfo.Username) {
-		return true
-	}
-	for _, group := range allowedGroups {
-		if slices.Contains(request.UserInfo.Groups, group) {
-			return true
-		}
-	}
-	return false
+func isAllowedUserGroup(ctx context.Context, request admissionctl.Request) bool {
+	return explain.FromContext(ctx).Record(allowlist.Allowed(WebhookName, request.AdmissionRequest.UserInfo), "user in allowlist") ||
+		allowedPrincipals.ExplainsRequest(ctx, "allowed principals", request)
 }
 
 // isProtectedClusterRole returns true if the ClusterRole is in the protected list or matches protected patterns
@@ -194,6 +196,11 @@ func (s *ClusterRoleWebHook) Validate(request admissionctl.Request) bool {
 	return valid
 }
 
+// SelfTestKind implements SelfTestWebhook interface
+func (s *ClusterRoleWebHook) SelfTestKind() metav1.GroupKind {
+	return metav1.GroupKind{Group: "rbac.authorization.k8s.io", Kind: "ClusterRole"}
+}
+
 // Name implements Webhook interface
 func (s *ClusterRoleWebHook) Name() string {
 	return WebhookName
@@ -243,3 +250,6 @@ func (s *ClusterRoleWebHook) SyncSetLabelSelector() metav1.LabelSelector {
 func (s *ClusterRoleWebHook) ClassicEnabled() bool { return true }
 
 func (s *ClusterRoleWebHook) HypershiftEnabled() bool { return true }
+
+// AllowlistEnabled implements AllowlistWebhook interface
+func (s *ClusterRoleWebHook) AllowlistEnabled() bool { return true }
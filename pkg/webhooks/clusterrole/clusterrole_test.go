@@ -3,6 +3,7 @@ package clusterrole
 import (
 	"testing"
 
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/allowlist"
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/testutils"
 	admissionv1 "k8s.io/api/admission/v1"
 	authenticationv1 "k8s.io/api/authentication/v1"
@@ -204,3 +205,33 @@ func TestClusterRoleDeletionPositive(t *testing.T) {
 
 	runClusterRoleTests(t, tests)
 }
+
+func TestClusterRoleDeletionAllowlist(t *testing.T) {
+	config, err := allowlist.Parse([]byte("version: v1\nwebhooks:\n  " + WebhookName + ":\n    users:\n    - allowlisted-user\n"))
+	if err != nil {
+		t.Fatalf("Unexpected error parsing allowlist: %v", err)
+	}
+	allowlist.Set(config)
+	defer allowlist.Set(nil)
+
+	tests := []ClusterRoleTestSuites{
+		{
+			testID:            "allowlisted-user-allow",
+			username:          "allowlisted-user",
+			userGroups:        []string{"system:authenticated"},
+			operation:         admissionv1.Delete,
+			shouldBeAllowed:   true,
+			targetClusterRole: "cluster-admin",
+		},
+		{
+			testID:            "unlisted-user-deny",
+			username:          "test-user",
+			userGroups:        []string{"system:authenticated"},
+			operation:         admissionv1.Delete,
+			shouldBeAllowed:   false,
+			targetClusterRole: "cluster-admin",
+		},
+	}
+
+	runClusterRoleTests(t, tests)
+}
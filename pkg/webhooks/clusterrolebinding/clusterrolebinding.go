@@ -1,13 +1,16 @@
 package clusterrolebinding
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"os"
 	"regexp"
 	"slices"
-	"strings"
 
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/allowlist"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/explain"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/helpers"
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
 	admissionv1 "k8s.io/api/admission/v1"
 	admissionregv1 "k8s.io/api/admissionregistration/v1"
@@ -51,11 +54,13 @@ var (
 		"openshift-gitops",
 	}
 
-	allowedUsers = []string{
-		"backplane-cluster-admin",
-	}
-	allowedGroups = []string{
-		"system:serviceaccounts:openshift-backplane-srep",
+	allowedPrincipals = utils.Principals{
+		Users: []string{
+			"backplane-cluster-admin",
+		},
+		Groups: []string{
+			"system:serviceaccounts:openshift-backplane-srep",
+		},
 	}
 )
 
@@ -84,25 +89,25 @@ func NewWebhook() *ClusterRoleBindingWebHook {
 
 // Authorized implements Webhook interface
 func (s *ClusterRoleBindingWebHook) Authorized(request admissionctl.Request) admissionctl.Response {
-	return s.authorized(request)
+	return s.authorized(context.Background(), request)
+}
+
+// AuthorizedWithContext implements ContextWebhook interface
+func (s *ClusterRoleBindingWebHook) AuthorizedWithContext(ctx context.Context, request admissionctl.Request) admissionctl.Response {
+	return s.authorized(ctx, request)
 }
 
-func (s *ClusterRoleBindingWebHook) authorized(request admissionctl.Request) admissionctl.Response {
+func (s *ClusterRoleBindingWebHook) authorized(ctx context.Context, request admissionctl.Request) admissionctl.Response {
 	var ret admissionctl.Response
+	trace := explain.FromContext(ctx)
 
-	if request.AdmissionRequest.UserInfo.Username == "system:unauthenticated" {
+	if utils.UnauthenticatedUsers.ExplainsRequest(ctx, "unauthenticated users", request) {
 		log.Info("system:unauthenticated made a webhook request. Check RBAC rules", "request", request.AdmissionRequest)
-		ret = admissionctl.Denied("Unauthenticated")
-		ret.UID = request.AdmissionRequest.UID
-		return ret
-	}
-	if strings.HasPrefix(request.AdmissionRequest.UserInfo.Username, "system:") {
-		ret = admissionctl.Allowed("authenticated system: users are allowed")
-		ret.UID = request.AdmissionRequest.UID
+		ret = utils.WebhookResponse(request, false, "", utils.WithCode(helpers.CodeUnauthenticated, nil))
 		return ret
 	}
-	if strings.HasPrefix(request.AdmissionRequest.UserInfo.Username, "kube:") {
-		ret = admissionctl.Allowed("kube: users are allowed")
+	if match, ok := utils.SystemUsers.ExplainRequest(ctx, "system users", request); ok {
+		ret = admissionctl.Allowed(fmt.Sprintf("authenticated system users are allowed: %s", match))
 		ret.UID = request.AdmissionRequest.UID
 		return ret
 	}
@@ -115,20 +120,19 @@ func (s *ClusterRoleBindingWebHook) authorized(request admissionctl.Request) adm
 
 	log.Info(fmt.Sprintf("Found clusterrolebinding: %v", clusterRoleBinding.Name))
 
-	if isProtectedNamespace(clusterRoleBinding) && !isAllowedUserGroup(request) {
+	if trace.Recordf(isProtectedNamespace(clusterRoleBinding), "binds a ServiceAccount in a namespace matching %s", protectedNamespaces) && !isAllowedUserGroup(ctx, request) {
 		switch request.Operation {
 		case admissionv1.Delete:
 			log.Info(fmt.Sprintf("Deleting operation detected on ClusterRoleBinding: %v", clusterRoleBinding.Name))
 
 			annotations := clusterRoleBinding.GetObjectMeta().GetAnnotations()
-			if annotations["oc.openshift.io/command"] == "oc adm must-gather" && request.AdmissionRequest.UserInfo.Username == "cluster-admin" {
+			if trace.Record(annotations["oc.openshift.io/command"] == "oc adm must-gather" && request.AdmissionRequest.UserInfo.Username == "cluster-admin", "cluster-admin deleting a must-gather ClusterRoleBinding") {
 				ret = admissionctl.Allowed("cluster-admin: cluster-admin may manage must-gather resources")
 				ret.UID = request.AdmissionRequest.UID
 				return ret
 			}
 
-			ret = admissionctl.Denied(fmt.Sprintf("Deleting ClusterRoleBinding %v is not allowed", clusterRoleBinding.Name))
-			ret.UID = request.AdmissionRequest.UID
+			ret = utils.WebhookResponse(request, false, "", utils.WithCode(helpers.CodeClusterRoleBindingDelete, helpers.Args{"Name": clusterRoleBinding.Name}))
 			return ret
 		}
 	}
@@ -155,16 +159,9 @@ func (s *ClusterRoleBindingWebHook) renderClusterRoleBinding(request admissionct
 }
 
 // isAllowedUserGroup checks if the user or group is allowed to perform the action
-func isAllowedUserGroup(request admissionctl.Request) bool {
-	if slices.Contains(allowedUsers, request.UserInfo.Username) {
-		return true
-	}
-	for _, group := range allowedGroups {
-		if slices.Contains(request.UserInfo.Groups, group) {
-			return true
-		}
-	}
-	return false
+func isAllowedUserGroup(ctx context.Context, request admissionctl.Request) bool {
+	return explain.FromContext(ctx).Record(allowlist.Allowed(WebhookName, request.AdmissionRequest.UserInfo), "user in allowlist") ||
+		allowedPrincipals.ExplainsRequest(ctx, "allowed principals", request)
 }
 
 // isProtectedNamespace returns true if clusterRoleBinding subject link
@@ -195,6 +192,11 @@ func (s *ClusterRoleBindingWebHook) Validate(request admissionctl.Request) bool
 	return valid
 }
 
+// SelfTestKind implements SelfTestWebhook interface
+func (s *ClusterRoleBindingWebHook) SelfTestKind() metav1.GroupKind {
+	return metav1.GroupKind{Group: "rbac.authorization.k8s.io", Kind: "ClusterRoleBinding"}
+}
+
 // Name implements Webhook interface
 func (s *ClusterRoleBindingWebHook) Name() string {
 	return WebhookName
@@ -244,3 +246,6 @@ func (s *ClusterRoleBindingWebHook) SyncSetLabelSelector() metav1.LabelSelector
 func (s *ClusterRoleBindingWebHook) ClassicEnabled() bool { return true }
 
 func (s *ClusterRoleBindingWebHook) HypershiftEnabled() bool { return true }
+
+// AllowlistEnabled implements AllowlistWebhook interface
+func (s *ClusterRoleBindingWebHook) AllowlistEnabled() bool { return true }
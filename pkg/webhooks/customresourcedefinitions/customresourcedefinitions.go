@@ -1,12 +1,14 @@
 package customresourcedefinitions
 
 import (
+	"context"
 	"fmt"
 	"net/http"
-	"regexp"
-	"slices"
 	"strings"
 
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/allowlist"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/explain"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/helpers"
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
 
 	admissionregv1 "k8s.io/api/admissionregistration/v1"
@@ -23,12 +25,13 @@ const (
 )
 
 var (
-	timeout                          int32 = 2
-	allowedUsers                           = []string{"system:admin", "backplane-cluster-admin"}
-	sreAdminGroups                         = []string{"system:serviceaccounts:openshift-backplane-srep"}
-	privilegedServiceAccountGroupsRe       = regexp.MustCompile(utils.PrivilegedServiceAccountGroups)
-	scope                                  = admissionregv1.ClusterScope
-	rules                                  = []admissionregv1.RuleWithOperations{
+	timeout           int32 = 2
+	allowedPrincipals       = utils.Principals{
+		Users:  []string{"system:admin", "backplane-cluster-admin"},
+		Groups: []string{"system:serviceaccounts:openshift-backplane-srep"},
+	}
+	scope = admissionregv1.ClusterScope
+	rules = []admissionregv1.RuleWithOperations{
 		{
 			Operations: []admissionregv1.OperationType{
 				admissionregv1.Create,
@@ -61,11 +64,15 @@ func NewWebhook() *customresourcedefinitionsruleWebhook {
 
 // Authorized implements Webhook interface
 func (s *customresourcedefinitionsruleWebhook) Authorized(request admissionctl.Request) admissionctl.Response {
-	//Implement authorized next
-	return s.authorized(request)
+	return s.authorized(context.Background(), request)
+}
+
+// AuthorizedWithContext implements ContextWebhook interface
+func (s *customresourcedefinitionsruleWebhook) AuthorizedWithContext(ctx context.Context, request admissionctl.Request) admissionctl.Response {
+	return s.authorized(ctx, request)
 }
 
-func (s *customresourcedefinitionsruleWebhook) authorized(request admissionctl.Request) admissionctl.Response {
+func (s *customresourcedefinitionsruleWebhook) authorized(ctx context.Context, request admissionctl.Request) admissionctl.Response {
 	var ret admissionctl.Response
 
 	crd, err := s.renderCustomResourceDefinition(request)
@@ -74,23 +81,20 @@ func (s *customresourcedefinitionsruleWebhook) authorized(request admissionctl.R
 		return admissionctl.Errored(http.StatusBadRequest, err)
 	}
 
-	if utils.IsProtectedByResourceName(crd.GetName()) {
+	if explain.FromContext(ctx).Record(utils.IsProtectedByResourceName(crd.GetName()), "CustomResourceDefinition is managed") {
 		log.Info(fmt.Sprintf("%s operation detected on protected CustomResourceDefinition: %s", request.Operation, crd.Name))
-		if isAllowedUser(request) {
+		if isAllowedUser(ctx, request) {
 			ret = admissionctl.Allowed(fmt.Sprintf("User '%s' in group(s) '%s' can operate on CustomResourceDefinitions", request.UserInfo.Username, strings.Join(request.UserInfo.Groups, ", ")))
 			ret.UID = request.AdmissionRequest.UID
 			return ret
 		}
-		for _, group := range request.UserInfo.Groups {
-			if privilegedServiceAccountGroupsRe.Match([]byte(group)) {
-				ret = admissionctl.Allowed(fmt.Sprintf("Privileged service accounts in group(s) '%s' can operate on CustomResourceDefinitions", strings.Join(request.UserInfo.Groups, ", ")))
-				ret.UID = request.AdmissionRequest.UID
-				return ret
-			}
+		if match, ok := utils.PrivilegedServiceAccounts.ExplainRequest(ctx, "privileged service accounts", request); ok {
+			ret = admissionctl.Allowed(fmt.Sprintf("Privileged service accounts can operate on CustomResourceDefinitions: %s", match))
+			ret.UID = request.AdmissionRequest.UID
+			return ret
 		}
 
-		ret = admissionctl.Denied(fmt.Sprintf("User '%s' prevented from accessing Red Mat managed resources. This is in an effort to prevent harmful actions that may cause unintended consequences or affect the stability of the cluster. If you have any questions about this, please reach out to Red Hat support at https://access.redhat.com/support", request.UserInfo.Username))
-		ret.UID = request.AdmissionRequest.UID
+		ret = utils.WebhookResponse(request, false, "", utils.WithCode(helpers.CodeManagedResource, nil))
 		return ret
 	}
 
@@ -100,18 +104,9 @@ func (s *customresourcedefinitionsruleWebhook) authorized(request admissionctl.R
 }
 
 // isAllowedUser checks if the user or group is allowed to perform the action
-func isAllowedUser(request admissionctl.Request) bool {
-	if slices.Contains(allowedUsers, request.UserInfo.Username) {
-		return true
-	}
-
-	for _, group := range sreAdminGroups {
-		if slices.Contains(request.UserInfo.Groups, group) {
-			return true
-		}
-	}
-
-	return false
+func isAllowedUser(ctx context.Context, request admissionctl.Request) bool {
+	return explain.FromContext(ctx).Record(allowlist.Allowed(WebhookName, request.AdmissionRequest.UserInfo), "user in allowlist") ||
+		allowedPrincipals.ExplainsRequest(ctx, "allowed principals", request)
 }
 
 func (s *customresourcedefinitionsruleWebhook) renderCustomResourceDefinition(req admissionctl.Request) (*apiextensionsv1.CustomResourceDefinition, error) {
@@ -144,6 +139,11 @@ func (s *customresourcedefinitionsruleWebhook) Validate(request admissionctl.Req
 	return valid
 }
 
+// SelfTestKind implements SelfTestWebhook interface
+func (s *customresourcedefinitionsruleWebhook) SelfTestKind() metav1.GroupKind {
+	return metav1.GroupKind{Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition"}
+}
+
 // Name implements Webhook interface
 func (s *customresourcedefinitionsruleWebhook) Name() string {
 	return WebhookName
@@ -193,3 +193,6 @@ func (s *customresourcedefinitionsruleWebhook) SyncSetLabelSelector() metav1.Lab
 func (s *customresourcedefinitionsruleWebhook) ClassicEnabled() bool { return true }
 
 func (s *customresourcedefinitionsruleWebhook) HypershiftEnabled() bool { return false }
+
+// AllowlistEnabled implements AllowlistWebhook interface
+func (s *customresourcedefinitionsruleWebhook) AllowlistEnabled() bool { return true }
@@ -1,11 +1,14 @@
 package hcpnamespace
 
 import (
-	"fmt"
+	"context"
 	"os"
 	"regexp"
 	"slices"
 
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/allowlist"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/explain"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/helpers"
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
 	admissionv1 "k8s.io/api/admission/v1"
 	admissionregv1 "k8s.io/api/admissionregistration/v1"
@@ -112,6 +115,11 @@ func (s *HCPNamespaceWebhook) Validate(req admissionctl.Request) bool {
 	return valid
 }
 
+// SelfTestKind implements SelfTestWebhook interface
+func (s *HCPNamespaceWebhook) SelfTestKind() metav1.GroupKind {
+	return metav1.GroupKind{Kind: "Namespace"}
+}
+
 // isProtectedNamespace checks if the namespace matches any of the protected patterns
 func isProtectedNamespace(namespaceName string) bool {
 	for _, re := range protectedNamespaceRegexps {
@@ -124,15 +132,22 @@ func isProtectedNamespace(namespaceName string) bool {
 
 // Authorized implements Webhook interface
 func (s *HCPNamespaceWebhook) Authorized(request admissionctl.Request) admissionctl.Response {
-	return s.authorized(request)
+	return s.authorized(context.Background(), request)
+}
+
+// AuthorizedWithContext implements ContextWebhook interface
+func (s *HCPNamespaceWebhook) AuthorizedWithContext(ctx context.Context, request admissionctl.Request) admissionctl.Response {
+	return s.authorized(ctx, request)
 }
 
 // Is the request authorized
-func (s *HCPNamespaceWebhook) authorized(request admissionctl.Request) admissionctl.Response {
+func (s *HCPNamespaceWebhook) authorized(ctx context.Context, request admissionctl.Request) admissionctl.Response {
 	var ret admissionctl.Response
+	trace := explain.FromContext(ctx)
 
 	// Allow authorized users/service accounts
-	if slices.Contains(allowedUsers, request.UserInfo.Username) {
+	if trace.Recordf(slices.Contains(allowedUsers, request.UserInfo.Username), "user in %q", allowedUsers) ||
+		trace.Record(allowlist.Allowed(WebhookName, request.UserInfo), "user in allowlist") {
 		ret = admissionctl.Allowed("User/ServiceAccount is authorized to delete HCP namespaces")
 		ret.UID = request.AdmissionRequest.UID
 		return ret
@@ -140,7 +155,7 @@ func (s *HCPNamespaceWebhook) authorized(request admissionctl.Request) admission
 
 	// Check if the namespace is protected
 	namespace := request.Name
-	if !isProtectedNamespace(namespace) {
+	if !trace.Record(isProtectedNamespace(namespace), "namespace is protected") {
 		// If the namespace doesn't match protected patterns, allow the operation
 		ret = admissionctl.Allowed("Namespace is not protected")
 		ret.UID = request.AdmissionRequest.UID
@@ -148,7 +163,7 @@ func (s *HCPNamespaceWebhook) authorized(request admissionctl.Request) admission
 	}
 
 	// If not a delete operation, allow it
-	if request.Operation != admissionv1.Delete {
+	if !trace.Recordf(request.Operation == admissionv1.Delete, "operation is %s", admissionv1.Delete) {
 		ret = admissionctl.Allowed("Only DELETE operations are restricted")
 		ret.UID = request.AdmissionRequest.UID
 		return ret
@@ -160,8 +175,7 @@ func (s *HCPNamespaceWebhook) authorized(request admissionctl.Request) admission
 		"namespace", namespace,
 		"groups", request.UserInfo.Groups)
 
-	ret = admissionctl.Denied(fmt.Sprintf("Only authorized users/service accounts can delete this namespace %s", namespace))
-	ret.UID = request.AdmissionRequest.UID
+	ret = utils.WebhookResponse(request, false, "", utils.WithCode(helpers.CodeHCPNamespaceDelete, helpers.Args{"Namespace": namespace}))
 	return ret
 }
 
@@ -184,6 +198,9 @@ func (s *HCPNamespaceWebhook) ClassicEnabled() bool { return true }
 
 func (s *HCPNamespaceWebhook) HypershiftEnabled() bool { return false }
 
+// AllowlistEnabled implements AllowlistWebhook interface
+func (s *HCPNamespaceWebhook) AllowlistEnabled() bool { return true }
+
 // NewWebhook creates a new webhook
 func NewWebhook() *HCPNamespaceWebhook {
 	scheme := runtime.NewScheme()
@@ -1,12 +1,15 @@
 package imagecontentpolicies
 
 import (
+	"context"
 	"net/http"
 	"regexp"
 
 	"github.com/go-logr/logr"
 	configv1 "github.com/openshift/api/config/v1"
 	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/explain"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/helpers"
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
 	admissionregv1 "k8s.io/api/admissionregistration/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -37,7 +40,17 @@ func NewWebhook() *ImageContentPoliciesWebhook {
 }
 
 func (w *ImageContentPoliciesWebhook) Authorized(request admission.Request) admission.Response {
+	return w.authorized(context.Background(), request)
+}
+
+// AuthorizedWithContext implements ContextWebhook interface
+func (w *ImageContentPoliciesWebhook) AuthorizedWithContext(ctx context.Context, request admission.Request) admission.Response {
+	return w.authorized(ctx, request)
+}
+
+func (w *ImageContentPoliciesWebhook) authorized(ctx context.Context, request admission.Request) admission.Response {
 	decoder := admission.NewDecoder(w.scheme)
+	trace := explain.FromContext(ctx)
 
 	switch request.RequestKind.Kind {
 	case "ImageDigestMirrorSet":
@@ -47,9 +60,9 @@ func (w *ImageContentPoliciesWebhook) Authorized(request admission.Request) admi
 			return admission.Errored(http.StatusBadRequest, err)
 		}
 
-		if !authorizeImageDigestMirrorSet(idms) {
+		if !trace.Recordf(authorizeImageDigestMirrorSet(idms), "no mirror source matches %s", unauthorizedRepositoryMirrors) {
 			w.log.Info("denying ImageDigestMirrorSet", "name", idms.Name)
-			return utils.WebhookResponse(request, false, WebhookDoc)
+			return utils.WebhookResponse(request, false, "", utils.WithCode(helpers.CodeImageMirrors, nil))
 		}
 	case "ImageTagMirrorSet":
 		itms := configv1.ImageTagMirrorSet{}
@@ -58,9 +71,9 @@ func (w *ImageContentPoliciesWebhook) Authorized(request admission.Request) admi
 			return admission.Errored(http.StatusBadRequest, err)
 		}
 
-		if !authorizeImageTagMirrorSet(itms) {
+		if !trace.Recordf(authorizeImageTagMirrorSet(itms), "no mirror source matches %s", unauthorizedRepositoryMirrors) {
 			w.log.Info("denying ImageTagMirrorSet", "name", itms.Name)
-			return utils.WebhookResponse(request, false, WebhookDoc)
+			return utils.WebhookResponse(request, false, "", utils.WithCode(helpers.CodeImageMirrors, nil))
 		}
 	case "ImageContentSourcePolicy":
 		icsp := operatorv1alpha1.ImageContentSourcePolicy{}
@@ -69,9 +82,9 @@ func (w *ImageContentPoliciesWebhook) Authorized(request admission.Request) admi
 			return admission.Errored(http.StatusBadRequest, err)
 		}
 
-		if !authorizeImageContentSourcePolicy(icsp) {
+		if !trace.Recordf(authorizeImageContentSourcePolicy(icsp), "no mirror source matches %s", unauthorizedRepositoryMirrors) {
 			w.log.Info("denying ImageContentSourcePolicy", "name", icsp.Name)
-			return utils.WebhookResponse(request, false, WebhookDoc)
+			return utils.WebhookResponse(request, false, "", utils.WithCode(helpers.CodeImageMirrors, nil))
 		}
 	}
 
@@ -100,6 +113,11 @@ func (w *ImageContentPoliciesWebhook) Validate(request admission.Request) bool {
 	}
 }
 
+// SelfTestKind implements SelfTestWebhook interface
+func (w *ImageContentPoliciesWebhook) SelfTestKind() metav1.GroupKind {
+	return metav1.GroupKind{Group: configv1.GroupName, Kind: "ImageDigestMirrorSet"}
+}
+
 func (w *ImageContentPoliciesWebhook) Name() string {
 	return WebhookName
 }
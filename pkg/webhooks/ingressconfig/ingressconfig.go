@@ -1,6 +1,8 @@
 package ingressconfig
 
 import (
+	"context"
+	"fmt"
 	"os"
 	"regexp"
 	"sync"
@@ -13,6 +15,7 @@ import (
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/helpers"
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
 )
 
@@ -23,9 +26,13 @@ const (
 )
 
 var (
-	log                         = logf.Log.WithName(WebhookName)
-	privilegedServiceAccountsRe = regexp.MustCompile(utils.PrivilegedServiceAccountGroups)
-	privilegedUsersRe           = regexp.MustCompile(privilegedUsers)
+	log = logf.Log.WithName(WebhookName)
+	// privilegedPrincipals are the privileged service accounts and users
+	// which may modify ingress config resources
+	privilegedPrincipals = utils.Principals{
+		UserPatterns:  []*regexp.Regexp{regexp.MustCompile(privilegedUsers)},
+		GroupPatterns: utils.PrivilegedServiceAccounts.GroupPatterns,
+	}
 
 	scope = admissionregv1.ClusterScope
 	rules = []admissionregv1.RuleWithOperations{
@@ -47,21 +54,21 @@ type IngressConfigWebhook struct {
 }
 
 // Authorized will determine if the request is allowed
-func (w *IngressConfigWebhook) Authorized(request admissionctl.Request) (ret admissionctl.Response) {
-	ret = admissionctl.Denied("Only privileged service accounts may access")
-	ret.UID = request.AdmissionRequest.UID
-
-	// allow if modified by an allowlist-ed service account
-	for _, group := range request.UserInfo.Groups {
-		if privilegedServiceAccountsRe.Match([]byte(group)) {
-			ret = admissionctl.Allowed("Privileged service accounts may access")
-			ret.UID = request.AdmissionRequest.UID
-		}
-	}
+func (w *IngressConfigWebhook) Authorized(request admissionctl.Request) admissionctl.Response {
+	return w.authorized(context.Background(), request)
+}
+
+// AuthorizedWithContext implements ContextWebhook interface
+func (w *IngressConfigWebhook) AuthorizedWithContext(ctx context.Context, request admissionctl.Request) admissionctl.Response {
+	return w.authorized(ctx, request)
+}
+
+func (w *IngressConfigWebhook) authorized(ctx context.Context, request admissionctl.Request) (ret admissionctl.Response) {
+	ret = utils.WebhookResponse(request, false, "", utils.WithCode(helpers.CodeIngressConfig, nil))
 
-	// allow if modified by an allowliste-ed user
-	if privilegedUsersRe.Match([]byte(request.UserInfo.Username)) {
-		ret = admissionctl.Allowed("Privileged service accounts may access")
+	// allow if modified by an allowlist-ed service account or user
+	if match, ok := privilegedPrincipals.ExplainRequest(ctx, "privileged principals", request); ok {
+		ret = admissionctl.Allowed(fmt.Sprintf("Privileged service accounts may access: %s", match))
 		ret.UID = request.AdmissionRequest.UID
 	}
 
@@ -80,6 +87,11 @@ func (w *IngressConfigWebhook) Validate(req admissionctl.Request) bool {
 	return valid
 }
 
+// SelfTestKind implements SelfTestWebhook interface
+func (w *IngressConfigWebhook) SelfTestKind() metav1.GroupKind {
+	return metav1.GroupKind{Group: "config.openshift.io", Kind: "Ingress"}
+}
+
 // Name is the name of the webhook
 func (w *IngressConfigWebhook) Name() string { return WebhookName }
 
@@ -1,16 +1,20 @@
 package ingresscontroller
 
 import (
+	"context"
 	"fmt"
 	"net/http"
-	"slices"
 	"strings"
 
 	operatorv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/allowlist"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/explain"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/helpers"
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
 	admissionregv1 "k8s.io/api/admissionregistration/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
@@ -36,8 +40,10 @@ var (
 			},
 		},
 	}
-	allowedUsers = []string{
-		"backplane-cluster-admin",
+	allowedPrincipals = utils.Principals{
+		Users: []string{
+			"backplane-cluster-admin",
+		},
 	}
 )
 
@@ -90,6 +96,11 @@ func (wh *IngressControllerWebhook) Validate(req admissionctl.Request) bool {
 	return valid
 }
 
+// SelfTestKind implements SelfTestWebhook interface
+func (wh *IngressControllerWebhook) SelfTestKind() metav1.GroupKind {
+	return metav1.GroupKind{Group: "operator.openshift.io", Kind: "IngressController"}
+}
+
 func (wh *IngressControllerWebhook) renderIngressController(req admissionctl.Request) (*operatorv1.IngressController, error) {
 	decoder := admissionctl.NewDecoder(&wh.s)
 	ic := &operatorv1.IngressController{}
@@ -101,8 +112,9 @@ func (wh *IngressControllerWebhook) renderIngressController(req admissionctl.Req
 	return ic, nil
 }
 
-func (wh *IngressControllerWebhook) authorized(request admissionctl.Request) admissionctl.Response {
+func (wh *IngressControllerWebhook) authorized(ctx context.Context, request admissionctl.Request) admissionctl.Response {
 	var ret admissionctl.Response
+	trace := explain.FromContext(ctx)
 	ic, err := wh.renderIngressController(request)
 	if err != nil {
 		log.Error(err, "Couldn't render an IngressController from the incoming request")
@@ -110,39 +122,38 @@ func (wh *IngressControllerWebhook) authorized(request admissionctl.Request) adm
 	}
 
 	log.Info("Checking if user is unauthenticated")
-	if request.AdmissionRequest.UserInfo.Username == "system:unauthenticated" {
+	if utils.UnauthenticatedUsers.ExplainsRequest(ctx, "unauthenticated users", request) {
 		// This could highlight a significant problem with RBAC since an
 		// unauthenticated user should have no permissions.
 		log.Info("system:unauthenticated made a webhook request. Check RBAC rules", "request", request.AdmissionRequest)
-		ret = admissionctl.Denied("Unauthenticated")
-		ret.UID = request.AdmissionRequest.UID
-		return ret
-	}
-
-	log.Info("Checking if user is authenticated system: user")
-	if strings.HasPrefix(request.AdmissionRequest.UserInfo.Username, "system:") {
-		ret = admissionctl.Allowed("authenticated system: users are allowed")
-		ret.UID = request.AdmissionRequest.UID
+		ret = utils.WebhookResponse(request, false, "", utils.WithCode(helpers.CodeUnauthenticated, nil))
 		return ret
 	}
 
-	log.Info("Checking if user is kube: user")
-	if strings.HasPrefix(request.AdmissionRequest.UserInfo.Username, "kube:") {
-		ret = admissionctl.Allowed("kube: users are allowed")
+	log.Info("Checking if user is a system user")
+	if match, ok := utils.SystemUsers.ExplainRequest(ctx, "system users", request); ok {
+		ret = admissionctl.Allowed(fmt.Sprintf("authenticated system users are allowed: %s", match))
 		ret.UID = request.AdmissionRequest.UID
 		return ret
 	}
 
 	// Check if the group does not have exceptions
-	if !isAllowedUser(request) {
-		for _, toleration := range ic.Spec.NodePlacement.Tolerations {
-			if strings.Contains(toleration.Key, "node-role.kubernetes.io/master") {
-				ret = admissionctl.Denied("Not allowed to provision ingress controller pods with toleration for master nodes.")
-				ret.UID = request.AdmissionRequest.UID
-
-				return ret
+	// An IngressController without a nodePlacement has no tolerations to check
+	if !isAllowedUser(ctx, request) && trace.Record(ic.Spec.NodePlacement != nil, "IngressController has a nodePlacement") {
+		var errs field.ErrorList
+		path := field.NewPath("spec", "nodePlacement", "tolerations")
+		for i, toleration := range ic.Spec.NodePlacement.Tolerations {
+			if trace.Recordf(strings.Contains(toleration.Key, "node-role.kubernetes.io/master"), "toleration %d tolerates master nodes", i) {
+				errs = append(errs, field.Forbidden(path.Index(i).Child("key"), "may not tolerate master nodes"))
 			}
 		}
+		if len(errs) > 0 {
+			ret = utils.WebhookResponse(request, false, "",
+				utils.WithCode(helpers.CodeIngressControllerToleration, nil),
+				utils.WithFieldErrors(errs))
+
+			return ret
+		}
 	}
 
 	ret = admissionctl.Allowed("IngressController operation is allowed")
@@ -152,10 +163,15 @@ func (wh *IngressControllerWebhook) authorized(request admissionctl.Request) adm
 }
 
 // isAllowedUser checks if the user is allowed to perform the action
-func isAllowedUser(request admissionctl.Request) bool {
-	log.Info(fmt.Sprintf("Checking username %s on whitelist", request.UserInfo.Username))
-	if slices.Contains(allowedUsers, request.UserInfo.Username) {
-		log.Info(fmt.Sprintf("%s is listed in whitelist", request.UserInfo.Username))
+func isAllowedUser(ctx context.Context, request admissionctl.Request) bool {
+	username := request.AdmissionRequest.UserInfo.Username
+	log.Info(fmt.Sprintf("Checking username %s on whitelist", username))
+	if match, ok := allowedPrincipals.ExplainRequest(ctx, "allowed principals", request); ok {
+		log.Info(fmt.Sprintf("%s is listed in whitelist", username), "match", match.String())
+		return true
+	}
+	if explain.FromContext(ctx).Record(allowlist.Allowed(WebhookName, request.AdmissionRequest.UserInfo), "user in allowlist") {
+		log.Info(fmt.Sprintf("%s is listed in the allowlist configuration", username))
 		return true
 	}
 
@@ -166,7 +182,12 @@ func isAllowedUser(request admissionctl.Request) bool {
 
 // Authorized implements Webhook interface
 func (wh *IngressControllerWebhook) Authorized(request admissionctl.Request) admissionctl.Response {
-	return wh.authorized(request)
+	return wh.authorized(context.Background(), request)
+}
+
+// AuthorizedWithContext implements ContextWebhook interface
+func (wh *IngressControllerWebhook) AuthorizedWithContext(ctx context.Context, request admissionctl.Request) admissionctl.Response {
+	return wh.authorized(ctx, request)
 }
 
 // SyncSetLabelSelector returns the label selector to use in the SyncSet.
@@ -192,6 +213,9 @@ func (s *IngressControllerWebhook) ClassicEnabled() bool { return true }
 
 func (s *IngressControllerWebhook) HypershiftEnabled() bool { return false }
 
+// AllowlistEnabled implements AllowlistWebhook interface
+func (s *IngressControllerWebhook) AllowlistEnabled() bool { return true }
+
 // NewWebhook creates a new webhook
 func NewWebhook() *IngressControllerWebhook {
 	scheme := runtime.NewScheme()
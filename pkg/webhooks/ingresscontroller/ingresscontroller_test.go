@@ -3,6 +3,7 @@ package ingresscontroller
 import (
 	"encoding/json"
 	"fmt"
+	"reflect"
 	"testing"
 
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/testutils"
@@ -76,6 +77,20 @@ func createRawIngressControllerJSON(name string, namespace string, nodeSelector
 	return output, nil
 }
 
+// withoutNodePlacement removes spec.nodePlacement from a raw IngressController
+func withoutNodePlacement(raw string) (string, error) {
+	var ic map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &ic); err != nil {
+		return "", err
+	}
+	delete(ic["spec"].(map[string]interface{}), "nodePlacement")
+	output, err := json.Marshal(ic)
+	if err != nil {
+		return "", err
+	}
+	return string(output), nil
+}
+
 type ingressControllerTestSuites struct {
 	testID          string
 	name            string
@@ -86,6 +101,10 @@ type ingressControllerTestSuites struct {
 	nodeSelector    corev1.NodeSelector
 	tolerations     []corev1.Toleration
 	shouldBeAllowed bool
+	// deniedFields are the field causes expected in a denial
+	deniedFields []string
+	// noNodePlacement leaves spec.nodePlacement out of the IngressController
+	noNodePlacement bool
 }
 
 func runIngressControllerTests(t *testing.T, tests []ingressControllerTestSuites) {
@@ -104,6 +123,12 @@ func runIngressControllerTests(t *testing.T, tests []ingressControllerTestSuites
 		if err != nil {
 			t.Fatalf("Couldn't create a JSON fragment %s", err.Error())
 		}
+		if test.noNodePlacement {
+			rawObjString, err = withoutNodePlacement(rawObjString)
+			if err != nil {
+				t.Fatalf("Couldn't remove the nodePlacement %s", err.Error())
+			}
+		}
 
 		obj := runtime.RawExtension{
 			Raw: []byte(rawObjString),
@@ -132,6 +157,19 @@ func runIngressControllerTests(t *testing.T, tests []ingressControllerTestSuites
 			t.Fatalf("[%s] Mismatch: %s (groups=%s) %s %s the ingress controller. Test's expectation is that the user %s", test.testID, test.username, test.userGroups, testutils.CanCanNot(response.Allowed), test.operation, testutils.CanCanNot(test.shouldBeAllowed))
 
 		}
+		if test.deniedFields != nil {
+			var fields []string
+			if response.Result != nil && response.Result.Details != nil {
+				for _, cause := range response.Result.Details.Causes {
+					if cause.Field != "" {
+						fields = append(fields, cause.Field)
+					}
+				}
+			}
+			if !reflect.DeepEqual(fields, test.deniedFields) {
+				t.Fatalf("[%s] Expected field causes %v, got %v", test.testID, test.deniedFields, fields)
+			}
+		}
 	}
 }
 
@@ -188,6 +226,31 @@ func TestIngressControllerTolerations(t *testing.T) {
 			tolerations:     []corev1.Toleration{},
 			shouldBeAllowed: true,
 		},
+		{
+			testID:     "toleration-test-create-4",
+			name:       "shiny-newingress",
+			namespace:  "openshift-ingress-operator",
+			username:   "admin",
+			userGroups: []string{"system:authenticated", "dedicated-admin"},
+			operation:  admissionv1.Create,
+			nodeSelector: corev1.NodeSelector{
+				NodeSelectorTerms: []corev1.NodeSelectorTerm{},
+			},
+			tolerations: []corev1.Toleration{
+				{
+					Key:      "node-role.kubernetes.io/infra",
+					Operator: "Exists",
+					Effect:   "NoSchedule",
+				},
+				{
+					Key:      "node-role.kubernetes.io/master",
+					Operator: "Exists",
+					Effect:   "NoSchedule",
+				},
+			},
+			shouldBeAllowed: false,
+			deniedFields:    []string{"spec.nodePlacement.tolerations[1].key"},
+		},
 		{
 			testID:     "toleration-test-update-1",
 			name:       "shiny-newingress",
@@ -239,6 +302,16 @@ func TestIngressControllerTolerations(t *testing.T) {
 			tolerations:     []corev1.Toleration{},
 			shouldBeAllowed: true,
 		},
+		{
+			testID:          "toleration-test-create-no-node-placement",
+			name:            "shiny-newingress",
+			namespace:       "openshift-ingress-operator",
+			username:        "admin",
+			userGroups:      []string{"system:authenticated", "dedicated-admin"},
+			operation:       admissionv1.Create,
+			noNodePlacement: true,
+			shouldBeAllowed: true,
+		},
 	}
 	runIngressControllerTests(t, tests)
 }
@@ -1,20 +1,24 @@
 package namespace
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"os"
 	"regexp"
-	"slices"
 	"sync"
 
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/allowlist"
 	hookconfig "github.com/openshift/managed-cluster-validating-webhooks/pkg/config"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/explain"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/helpers"
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
 	admissionv1 "k8s.io/api/admission/v1"
 	admissionregv1 "k8s.io/api/admissionregistration/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
@@ -34,10 +38,15 @@ var (
 )
 
 var (
-	clusterAdminUsers           = []string{"kube:admin", "system:admin", "backplane-cluster-admin"}
-	sreAdminGroups              = []string{"system:serviceaccounts:openshift-backplane-srep"}
-	privilegedServiceAccountsRe = regexp.MustCompile(utils.PrivilegedServiceAccountGroups)
-	layeredProductNamespaceRe   = regexp.MustCompile(layeredProductNamespace)
+	// admins are cluster and SRE admins
+	admins = utils.Principals{
+		Users:  []string{"kube:admin", "system:admin", "backplane-cluster-admin"},
+		Groups: []string{clusterAdminGroup, "system:serviceaccounts:openshift-backplane-srep"},
+	}
+	layeredProductAdmins = utils.Principals{
+		Groups: []string{layeredProductAdminGroupName},
+	}
+	layeredProductNamespaceRe = regexp.MustCompile(layeredProductNamespace)
 	// protectedLabels are labels which managed customers should not be allowed
 	// change by dedicated-admins.
 	protectedLabels = []string{
@@ -111,6 +120,11 @@ func (s *NamespaceWebhook) Validate(req admissionctl.Request) bool {
 	return valid
 }
 
+// SelfTestKind implements SelfTestWebhook interface
+func (s *NamespaceWebhook) SelfTestKind() metav1.GroupKind {
+	return metav1.GroupKind{Kind: "Namespace"}
+}
+
 // renderNamespace decodes a *corev1.Namespace from the incoming request and
 // gives preference to the OldObject (if it exists) over the Object. This method
 // is functionally similar to the renderOldAndNewNamespaces method except we
@@ -176,25 +190,34 @@ func (s *NamespaceWebhook) renderOldAndNewNamespaces(req admissionctl.Request) (
 
 // Authorized implements Webhook interface
 func (s *NamespaceWebhook) Authorized(request admissionctl.Request) admissionctl.Response {
-	return s.authorized(request)
+	return s.authorized(context.Background(), request)
+}
+
+// AuthorizedWithContext implements ContextWebhook interface
+func (s *NamespaceWebhook) AuthorizedWithContext(ctx context.Context, request admissionctl.Request) admissionctl.Response {
+	return s.authorized(ctx, request)
 }
 
 // Is the request authorized?
-func (s *NamespaceWebhook) authorized(request admissionctl.Request) admissionctl.Response {
+func (s *NamespaceWebhook) authorized(ctx context.Context, request admissionctl.Request) admissionctl.Response {
 	var ret admissionctl.Response
+	trace := explain.FromContext(ctx)
 	// Admins are allowed to perform any operation
-	if amIAdmin(request) {
-		ret = admissionctl.Allowed("Cluster and SRE admins may access")
+	if match, ok := admins.ExplainRequest(ctx, "admins", request); ok {
+		ret = admissionctl.Allowed(fmt.Sprintf("Cluster and SRE admins may access: %s", match))
 		ret.UID = request.AdmissionRequest.UID
 		return ret
 	}
 	// Privileged ServiceAccounts are allowed to perform any operation
-	for _, group := range request.UserInfo.Groups {
-		if privilegedServiceAccountsRe.Match([]byte(group)) {
-			ret = admissionctl.Allowed("Privileged service accounts may access")
-			ret.UID = request.AdmissionRequest.UID
-			return ret
-		}
+	if match, ok := utils.PrivilegedServiceAccounts.ExplainRequest(ctx, "privileged service accounts", request); ok {
+		ret = admissionctl.Allowed(fmt.Sprintf("Privileged service accounts may access: %s", match))
+		ret.UID = request.AdmissionRequest.UID
+		return ret
+	}
+	if trace.Record(allowlist.Allowed(WebhookName, request.AdmissionRequest.UserInfo), "user in allowlist") {
+		ret = admissionctl.Allowed("Allowlisted users may access")
+		ret.UID = request.AdmissionRequest.UID
+		return ret
 	}
 
 	ns, err := s.renderNamespace(request)
@@ -204,8 +227,8 @@ func (s *NamespaceWebhook) authorized(request admissionctl.Request) admissionctl
 	}
 
 	// Layered Product SRE can access their own namespaces
-	if slices.Contains(request.UserInfo.Groups, layeredProductAdminGroupName) &&
-		layeredProductNamespaceRe.Match([]byte(ns.GetName())) {
+	if layeredProductAdmins.ExplainsRequest(ctx, "layered product admins", request) &&
+		trace.Recordf(layeredProductNamespaceRe.Match([]byte(ns.GetName())), "namespace matches %s", layeredProductNamespace) {
 		ret = admissionctl.Allowed("Layered product admins may access")
 		ret.UID = request.AdmissionRequest.UID
 		return ret
@@ -213,24 +236,28 @@ func (s *NamespaceWebhook) authorized(request admissionctl.Request) admissionctl
 
 	// Unprivileged users cannot modify privileged namespaces
 	// L64-73
-	if hookconfig.IsPrivilegedNamespace(ns.GetName()) {
+	if trace.Record(hookconfig.IsPrivilegedNamespace(ns.GetName()), "namespace is privileged") {
 		log.Info("Non-admin attempted to access a privileged namespace matching a regex from this list", "list", hookconfig.PrivilegedNamespaces, "request", request.AdmissionRequest)
-		ret = admissionctl.Denied(fmt.Sprintf("Prevented from accessing Red Hat managed namespaces. Customer workloads should be placed in customer namespaces, and should not match an entry in this list of regular expressions: %v", hookconfig.PrivilegedNamespaces))
-		ret.UID = request.AdmissionRequest.UID
+		ret = utils.WebhookResponse(request, false, "", utils.WithCode(helpers.CodeNamespacePrivileged, helpers.Args{"Namespaces": hookconfig.PrivilegedNamespaces}))
 		return ret
 	}
 	// Unprivileged users cannot create namespaces with certain names
-	if BadNamespaceRe.Match([]byte(ns.GetName())) {
+	if trace.Recordf(BadNamespaceRe.Match([]byte(ns.GetName())), "namespace matches %s", badNamespace) {
 		log.Info("Non-admin attempted to access a potentially harmful namespace (eg matching this regex)", "regex", badNamespace, "request", request.AdmissionRequest)
-		ret = admissionctl.Denied(fmt.Sprintf("Prevented from creating a potentially harmful namespace. Customer namespaces should not match this regular expression, as this would impact DNS resolution: %s", badNamespace))
-		ret.UID = request.AdmissionRequest.UID
+		ret = utils.WebhookResponse(request, false, "", utils.WithCode(helpers.CodeNamespaceHarmful, helpers.Args{"Regex": badNamespace}))
 		return ret
 	}
 	// Check labels.
-	unauthorized, err := s.unauthorizedLabelChanges(request)
-	if !amIAdmin(request) && unauthorized {
-		ret = admissionctl.Denied(fmt.Sprintf("Denied. Err %+v", err))
-		ret.UID = request.AdmissionRequest.UID
+	action, fieldErrs, err := s.unauthorizedLabelChanges(request)
+	if !amIAdmin(ctx, request) && trace.Recordf(err != nil || action != "", "protected labels %q changed", protectedLabels) {
+		if err != nil {
+			ret = admissionctl.Errored(http.StatusBadRequest, err)
+			ret.UID = request.AdmissionRequest.UID
+			return ret
+		}
+		ret = utils.WebhookResponse(request, false, "",
+			utils.WithCode(helpers.CodeNamespaceProtectedLabels, helpers.Args{"Action": action, "Labels": protectedLabels}),
+			utils.WithFieldErrors(fieldErrs))
 		return ret
 	}
 	// L75-L77
@@ -239,26 +266,34 @@ func (s *NamespaceWebhook) authorized(request admissionctl.Request) admissionctl
 	return ret
 }
 
-// unauthorizedLabelChanges returns true if the request should be denied because of a label violation. The error is the reason for denial.
-func (s *NamespaceWebhook) unauthorizedLabelChanges(req admissionctl.Request) (bool, error) {
+// unauthorizedLabelChanges returns what the request does to protected labels
+// which it may not, e.g. "add or remove", or "" if the request may be
+// allowed. The field errors identify the labels concerned. An error means the
+// namespaces couldn't be decoded.
+func (s *NamespaceWebhook) unauthorizedLabelChanges(req admissionctl.Request) (string, field.ErrorList, error) {
 	// When there's a delete operation there are no meaningful changes to protected labels
 	if req.Operation == admissionv1.Delete {
-		return false, nil
+		return "", nil, nil
 	}
 
 	newNamespace, oldNamespace, err := s.renderOldAndNewNamespaces(req)
 	if err != nil {
-		return true, err
+		return "", nil, err
 	}
+	labelsPath := field.NewPath("metadata", "labels")
+	var errs field.ErrorList
 	if req.Operation == admissionv1.Create {
 		// For creations, we look to newNamespace and ensure no protectedLabels are set
 		// We don't care about oldNamespace.
 		protectedLabelsFound := doesNamespaceContainProtectedLabels(newNamespace)
 		if len(protectedLabelsFound) == 0 {
-			return false, nil
+			return "", nil, nil
 		}
 		// There were some found
-		return true, fmt.Errorf("Managed OpenShift customers may not directly set certain protected labels (%s) on Namespaces", protectedLabels)
+		for _, labelKey := range protectedLabelsFound {
+			errs = append(errs, field.Forbidden(labelsPath.Key(labelKey), "may not be set"))
+		}
+		return "directly set", errs, nil
 	} else if req.Operation == admissionv1.Update {
 		// For Updates we must see if the new object is making a change to the old one for any protected labels.
 		// First, let's see if the old object had any protected labels we ought to
@@ -267,29 +302,33 @@ func (s *NamespaceWebhook) unauthorizedLabelChanges(req admissionctl.Request) (b
 		// did not have any protected labels doesn't necessarily mean that we can
 		// ignore potential setting of those labels' values in the newNamespace.
 
+		// First check: Were any protectedLabels added or deleted?
+		for _, labelKey := range protectedLabels {
+			_, inOld := oldNamespace.Labels[labelKey]
+			_, inNew := newNamespace.Labels[labelKey]
+			if inOld != inNew {
+				errs = append(errs, field.Forbidden(labelsPath.Key(labelKey), "may not be added or removed"))
+			}
+		}
+		if len(errs) > 0 {
+			return "add or remove", errs, nil
+		}
 		// protectedLabelsFoundInOld is a slice of all instances of protectedLabels
 		// that appeared in the oldNamespace that we need to be sure have not
 		// changed.
 		protectedLabelsFoundInOld := doesNamespaceContainProtectedLabels(oldNamespace)
-		// protectedLabelsFoundInNew is a slice of all instances of protectedLabels
-		// that appeared in the newNamespace that we need to be sure do not have a
-		// value different than oldNamespace.
-		protectedLabelsFoundInNew := doesNamespaceContainProtectedLabels(newNamespace)
-
-		// First check: Were any protectedLabels deleted?
-		if len(protectedLabelsFoundInOld) != len(protectedLabelsFoundInNew) {
-			// If we have x protectedLabels in the oldNamespace then we expect to also
-			// have x protectedLabels in the newNamespace. Any difference is a removal or addition
-			return true, fmt.Errorf("Managed OpenShift customers may not add or remove protected labels (%s) from Namespaces", protectedLabels)
-		}
 		// Next check: Compare values to ensure there are no changes in the protected labels
 		for _, labelKey := range protectedLabelsFoundInOld {
-			if oldNamespace.Labels[labelKey] != newNamespace.ObjectMeta.Labels[labelKey] {
-				return true, fmt.Errorf("Managed OpenShift customers may not change the value or certain protected labels (%s) on Namespaces. %s changed from %s to %s", protectedLabels, labelKey, oldNamespace.Labels[labelKey], newNamespace.ObjectMeta.Labels[labelKey])
+			oldValue, newValue := oldNamespace.Labels[labelKey], newNamespace.Labels[labelKey]
+			if oldValue != newValue {
+				errs = append(errs, field.Forbidden(labelsPath.Key(labelKey), fmt.Sprintf("may not be changed from %q to %q", oldValue, newValue)))
 			}
 		}
+		if len(errs) > 0 {
+			return "change the value of", errs, nil
+		}
 	}
-	return false, nil
+	return "", nil, nil
 }
 
 // doesNamespaceContainProtectedLabels checks the namespace for any instances of
@@ -313,6 +352,9 @@ func (s *NamespaceWebhook) ClassicEnabled() bool { return true }
 
 func (s *NamespaceWebhook) HypershiftEnabled() bool { return false }
 
+// AllowlistEnabled implements AllowlistWebhook interface
+func (s *NamespaceWebhook) AllowlistEnabled() bool { return true }
+
 // NewWebhook creates a new webhook
 func NewWebhook() *NamespaceWebhook {
 	scheme := runtime.NewScheme()
@@ -333,16 +375,6 @@ func NewWebhook() *NamespaceWebhook {
 	}
 }
 
-func amIAdmin(request admissionctl.Request) bool {
-	if slices.Contains(clusterAdminUsers, request.UserInfo.Username) || slices.Contains(request.UserInfo.Groups, clusterAdminGroup) {
-		return true
-	}
-
-	for _, group := range sreAdminGroups {
-		if slices.Contains(request.UserInfo.Groups, group) {
-			return true
-		}
-	}
-
-	return false
+func amIAdmin(ctx context.Context, request admissionctl.Request) bool {
+	return admins.ExplainsRequest(ctx, "admins", request)
 }
@@ -3,8 +3,11 @@ package namespace
 import (
 	"encoding/json"
 	"fmt"
+	"reflect"
+	"strings"
 	"testing"
 
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/allowlist"
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/testutils"
 
 	admissionv1 "k8s.io/api/admission/v1"
@@ -53,6 +56,10 @@ type namespaceTestSuites struct {
 	operation       admissionv1.Operation
 	labels          map[string]string
 	shouldBeAllowed bool
+	// deniedFields are the field causes expected in a denial
+	deniedFields []string
+	// deniedMessage is expected in the message of a denial
+	deniedMessage string
 }
 
 func runNamespaceTests(t *testing.T, tests []namespaceTestSuites) {
@@ -88,7 +95,52 @@ func runNamespaceTests(t *testing.T, tests []namespaceTestSuites) {
 		if response.Allowed != test.shouldBeAllowed {
 			t.Fatalf("Mismatch: %s (groups=%s) %s %s the %s namespace. Test's expectation is that the user %s. Reason: %+v", test.username, test.userGroups, testutils.CanCanNot(response.Allowed), string(test.operation), test.targetNamespace, testutils.CanCanNot(test.shouldBeAllowed), response)
 		}
+		if test.deniedFields != nil {
+			var fields []string
+			if response.Result != nil && response.Result.Details != nil {
+				for _, cause := range response.Result.Details.Causes {
+					if cause.Field != "" {
+						fields = append(fields, cause.Field)
+					}
+				}
+			}
+			if !reflect.DeepEqual(fields, test.deniedFields) {
+				t.Fatalf("[%s] Expected field causes %v, got %v", test.testID, test.deniedFields, fields)
+			}
+		}
+		if test.deniedMessage != "" && (response.Result == nil || !strings.Contains(response.Result.Message, test.deniedMessage)) {
+			t.Fatalf("[%s] Expected a message containing %q, got %+v", test.testID, test.deniedMessage, response.Result)
+		}
+	}
+}
+
+func TestAllowlist(t *testing.T) {
+	config, err := allowlist.Parse([]byte("version: v1\nwebhooks:\n  " + WebhookName + ":\n    users:\n    - allowlisted-user\n"))
+	if err != nil {
+		t.Fatalf("Unexpected error parsing allowlist: %v", err)
+	}
+	allowlist.Set(config)
+	defer allowlist.Set(nil)
+
+	tests := []namespaceTestSuites{
+		{
+			testID:          "allowlisted-user-update-priv-ns",
+			targetNamespace: privilegedNamespace,
+			username:        "allowlisted-user",
+			userGroups:      []string{"system:authenticated"},
+			operation:       admissionv1.Update,
+			shouldBeAllowed: true,
+		},
+		{
+			testID:          "unlisted-user-update-priv-ns",
+			targetNamespace: privilegedNamespace,
+			username:        "test-user",
+			userGroups:      []string{"system:authenticated"},
+			operation:       admissionv1.Update,
+			shouldBeAllowed: false,
+		},
 	}
+	runNamespaceTests(t, tests)
 }
 
 // TestDedicatedAdmins will test everything a dedicated admin can and can not do
@@ -886,6 +938,7 @@ func TestLabelCreates(t *testing.T) {
 				"managed.openshift.io/storage-pv-quota-exempt": "true",
 			},
 			shouldBeAllowed: false,
+			deniedFields:    []string{"metadata.labels[managed.openshift.io/storage-pv-quota-exempt]"},
 		},
 		{
 			testID:          "dedicated-admin-cant-create-normal-ns-with-priv-label",
@@ -930,6 +983,8 @@ func TestLabellingUpdates(t *testing.T) {
 			}),
 			labels:          map[string]string{},
 			shouldBeAllowed: false,
+			deniedFields:    []string{"metadata.labels[managed.openshift.io/storage-pv-quota-exempt]"},
+			deniedMessage:   "[MCVW-NS-003] Managed OpenShift customers may not add or remove the protected labels [managed.openshift.io/storage-pv-quota-exempt managed.openshift.io/service-lb-quota-exempt] on Namespaces.",
 		},
 		{
 			// if for some reason the quota was explicitly set to false we shouldn't allow that to be removed (part 2)
@@ -956,6 +1011,7 @@ func TestLabellingUpdates(t *testing.T) {
 			}),
 			labels:          map[string]string{"managed.openshift.io/storage-lb-quota-exempt": "false"},
 			shouldBeAllowed: false,
+			deniedFields:    []string{"metadata.labels[managed.openshift.io/storage-pv-quota-exempt]"},
 		},
 		{
 			// Nothing is changing here
@@ -985,6 +1041,7 @@ func TestLabellingUpdates(t *testing.T) {
 				"managed.openshift.io/storage-pv-quota-exempt": "true",
 			},
 			shouldBeAllowed: false,
+			deniedFields:    []string{"metadata.labels[managed.openshift.io/storage-pv-quota-exempt]"},
 		},
 		{
 			testID:          "sres-can-exempt-customer-ns",
@@ -1,17 +1,21 @@
 package networkoperator
 
 import (
+	"context"
 	"net/http"
 	"os"
-	"slices"
 
 	operatorv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/allowlist"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/explain"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/helpers"
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
 	admissionv1 "k8s.io/api/admission/v1"
 	admissionregv1 "k8s.io/api/admissionregistration/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
@@ -19,6 +23,8 @@ import (
 const (
 	WebhookName string = "network-operator-validation"
 	docString   string = `Managed OpenShift customers may not modify critical fields in the network.operator CRD (such as spec.migration.networkType) because it can disrupt Cluster Network Operator operations and CNI migrations. Only backplane-cluster-admin, SRE, Cluster Network Operator (CNO), and Managed Upgrade Operator (MUO) service accounts are allowed to modify these critical fields. Regular cluster-admin users (system:admin) are explicitly blocked.`
+	// migrationForbidden is the detail of the field errors for critical migration fields
+	migrationForbidden string = "may only be changed by SRE and the network operators"
 )
 
 var (
@@ -37,18 +43,19 @@ var (
 		},
 	}
 
-	// Users allowed to modify critical migration fields (exact username match).
-	// Includes backplane-cluster-admin and CNO/MUO service accounts (username format: system:serviceaccount:<namespace>:<name>).
-	allowedUsers = []string{
-		"backplane-cluster-admin",
-		"system:serviceaccount:openshift-network-operator:cluster-network-operator",
-		"system:serviceaccount:openshift-managed-upgrade-operator:managed-upgrade-operator",
-	}
-
-	// Groups allowed to modify critical migration fields (SRE service accounts only).
-	// Kubernetes only assigns system:serviceaccounts and system:serviceaccounts:<namespace>; CNO/MUO are in allowedUsers.
-	sreAdminGroups = []string{
-		"system:serviceaccounts:openshift-backplane-srep",
+	// Principals allowed to modify critical migration fields: backplane-cluster-admin,
+	// the CNO/MUO service accounts (username format: system:serviceaccount:<namespace>:<name>)
+	// and SRE service accounts. Kubernetes only assigns the groups system:serviceaccounts
+	// and system:serviceaccounts:<namespace>, so CNO/MUO are matched by username.
+	allowedPrincipals = utils.Principals{
+		Users: []string{
+			"backplane-cluster-admin",
+			"system:serviceaccount:openshift-network-operator:cluster-network-operator",
+			"system:serviceaccount:openshift-managed-upgrade-operator:managed-upgrade-operator",
+		},
+		Groups: []string{
+			"system:serviceaccounts:openshift-backplane-srep",
+		},
 	}
 )
 
@@ -58,9 +65,19 @@ type NetworkOperatorWebhook struct {
 
 // Authorized will determine if the request is allowed
 func (w *NetworkOperatorWebhook) Authorized(request admissionctl.Request) admissionctl.Response {
+	return w.authorized(context.Background(), request)
+}
+
+// AuthorizedWithContext implements ContextWebhook interface
+func (w *NetworkOperatorWebhook) AuthorizedWithContext(ctx context.Context, request admissionctl.Request) admissionctl.Response {
+	return w.authorized(ctx, request)
+}
+
+func (w *NetworkOperatorWebhook) authorized(ctx context.Context, request admissionctl.Request) admissionctl.Response {
+	trace := explain.FromContext(ctx)
 	// Block regular cluster-admin users (system:admin) from modifying critical migration fields
 	// Only backplane-cluster-admin, SRE, CNO, and MUO service accounts are allowed
-	if request.Operation == admissionv1.Update {
+	if trace.Recordf(request.Operation == admissionv1.Update, "operation is %s", admissionv1.Update) {
 		decoder := admissionctl.NewDecoder(&w.s)
 		object := &operatorv1.Network{}
 		oldObject := &operatorv1.Network{}
@@ -79,7 +96,7 @@ func (w *NetworkOperatorWebhook) Authorized(request admissionctl.Request) admiss
 		}
 
 		// Check if critical migration fields have been modified
-		if hasCriticalMigrationFieldChanges(oldObject, object) {
+		if errs := criticalMigrationFieldChanges(oldObject, object); trace.Record(len(errs) > 0, "critical migration fields changed") {
 			// Log user information for debugging
 			log.Info("Critical migration field change detected",
 				"username", request.AdmissionRequest.UserInfo.Username,
@@ -90,7 +107,7 @@ func (w *NetworkOperatorWebhook) Authorized(request admissionctl.Request) admiss
 
 			// Allow only backplane-cluster-admin, SRE, CNO, and MUO service accounts to modify critical migration fields
 			// Regular cluster-admin (system:admin) is explicitly blocked
-			if isAllowedUserGroup(request) {
+			if isAllowedUserGroup(ctx, request) {
 				log.Info("User is allowed to modify critical migration fields")
 				return utils.WebhookResponse(request, true, "Privileged users are allowed to modify critical migration fields")
 			}
@@ -98,12 +115,11 @@ func (w *NetworkOperatorWebhook) Authorized(request admissionctl.Request) admiss
 			log.Info("User is denied access to modify critical migration fields",
 				"username", request.AdmissionRequest.UserInfo.Username,
 				"groups", request.AdmissionRequest.UserInfo.Groups,
+				"fields", errs.ToAggregate().Error(),
 			)
-			return utils.WebhookResponse(
-				request,
-				false,
-				"Modification of critical migration fields (spec.migration.networkType and related migration configuration) is not allowed, even for cluster-admin users. These fields are managed by the Cluster Network Operator and manual changes can disrupt CNI migrations.",
-			)
+			return utils.WebhookResponse(request, false, "",
+				utils.WithCode(helpers.CodeNetworkOperatorMigration, nil),
+				utils.WithFieldErrors(errs))
 		}
 
 		// Allow modifications to non-critical fields
@@ -115,116 +131,75 @@ func (w *NetworkOperatorWebhook) Authorized(request admissionctl.Request) admiss
 	return utils.WebhookResponse(request, true, "CREATE and DELETE operations are allowed")
 }
 
-// hasCriticalMigrationFieldChanges checks if any critical migration fields have been modified
-func hasCriticalMigrationFieldChanges(oldObj, newObj *operatorv1.Network) bool {
+// criticalMigrationFieldChanges returns an error for each critical migration
+// field which has been modified
+func criticalMigrationFieldChanges(oldObj, newObj *operatorv1.Network) field.ErrorList {
 	oldMigration := oldObj.Spec.Migration
 	newMigration := newObj.Spec.Migration
+	path := field.NewPath("spec", "migration")
 
-	// If migration was nil and now it's set, that's a change
-	if oldMigration == nil && newMigration != nil {
-		return true
-	}
-
-	// If migration was set and now it's nil, that's a change
-	if oldMigration != nil && newMigration == nil {
-		return true
+	// If migration was set or unset, that's a change
+	if (oldMigration == nil) != (newMigration == nil) {
+		return field.ErrorList{field.Forbidden(path, migrationForbidden)}
 	}
 
 	// If both are nil, no migration changes
 	if oldMigration == nil && newMigration == nil {
-		return false
+		return nil
 	}
 
 	// Check for changes in critical migration fields
+	var errs field.ErrorList
 	if oldMigration.NetworkType != newMigration.NetworkType {
-		return true
+		errs = append(errs, field.Forbidden(path.Child("networkType"), migrationForbidden))
 	}
 
 	if oldMigration.Mode != newMigration.Mode {
-		return true
+		errs = append(errs, field.Forbidden(path.Child("mode"), migrationForbidden))
 	}
 
 	// Check if Features field has changed (pointer comparison for nil, then deep comparison)
-	if (oldMigration.Features == nil) != (newMigration.Features == nil) {
-		return true
-	}
-	if oldMigration.Features != nil && newMigration.Features != nil {
-		// FeaturesMigration is a struct, so we compare the values
-		if *oldMigration.Features != *newMigration.Features {
-			return true
-		}
+	// FeaturesMigration is a struct, so we compare the values
+	if (oldMigration.Features == nil) != (newMigration.Features == nil) ||
+		(oldMigration.Features != nil && *oldMigration.Features != *newMigration.Features) {
+		errs = append(errs, field.Forbidden(path.Child("features"), migrationForbidden))
 	}
 
 	// MTU migration changes are also critical
-	if (oldMigration.MTU == nil) != (newMigration.MTU == nil) {
-		return true
-	}
-	if oldMigration.MTU != nil && newMigration.MTU != nil {
+	mtuPath := path.Child("mtu")
+	switch {
+	case (oldMigration.MTU == nil) != (newMigration.MTU == nil):
+		errs = append(errs, field.Forbidden(mtuPath, migrationForbidden))
+	case oldMigration.MTU != nil:
 		// Compare MTUMigration Network field
-		if (oldMigration.MTU.Network == nil) != (newMigration.MTU.Network == nil) {
-			return true
-		}
-		if oldMigration.MTU.Network != nil && newMigration.MTU.Network != nil {
-			if *oldMigration.MTU.Network != *newMigration.MTU.Network {
-				return true
-			}
+		if (oldMigration.MTU.Network == nil) != (newMigration.MTU.Network == nil) ||
+			(oldMigration.MTU.Network != nil && *oldMigration.MTU.Network != *newMigration.MTU.Network) {
+			errs = append(errs, field.Forbidden(mtuPath.Child("network"), migrationForbidden))
 		}
 		// Compare MTUMigration Machine field
-		if (oldMigration.MTU.Machine == nil) != (newMigration.MTU.Machine == nil) {
-			return true
-		}
-		if oldMigration.MTU.Machine != nil && newMigration.MTU.Machine != nil {
-			if *oldMigration.MTU.Machine != *newMigration.MTU.Machine {
-				return true
-			}
+		if (oldMigration.MTU.Machine == nil) != (newMigration.MTU.Machine == nil) ||
+			(oldMigration.MTU.Machine != nil && *oldMigration.MTU.Machine != *newMigration.MTU.Machine) {
+			errs = append(errs, field.Forbidden(mtuPath.Child("machine"), migrationForbidden))
 		}
 	}
 
-	return false
+	return errs
 }
 
 // isAllowedUserGroup checks if the user or group is allowed to modify critical migration fields
-func isAllowedUserGroup(request admissionctl.Request) bool {
-	// Prioritize AdmissionRequest.UserInfo as it contains the actual impersonated user info
-	// This is consistent with other webhooks in this repository
-	username := request.AdmissionRequest.UserInfo.Username
-	if username == "" {
-		username = request.UserInfo.Username
-	}
-
-	log.Info("Checking user authorization",
-		"username", username,
-		"admissionRequestUsername", request.AdmissionRequest.UserInfo.Username,
-		"userInfoUsername", request.UserInfo.Username,
-		"admissionRequestGroups", request.AdmissionRequest.UserInfo.Groups,
-		"userInfoGroups", request.UserInfo.Groups,
-		"allowedUsers", allowedUsers,
-		"sreAdminGroups", sreAdminGroups,
-	)
-
-	// Check username first
-	if slices.Contains(allowedUsers, username) {
-		log.Info("User is in allowedUsers list", "username", username)
+func isAllowedUserGroup(ctx context.Context, request admissionctl.Request) bool {
+	userInfo := request.AdmissionRequest.UserInfo
+	if match, ok := allowedPrincipals.ExplainRequest(ctx, "allowed principals", request); ok {
+		log.Info("User is allowed", "username", userInfo.Username, "match", match.String())
 		return true
 	}
 
-	// Check groups from AdmissionRequest.UserInfo first (for impersonation)
-	for _, group := range sreAdminGroups {
-		if slices.Contains(request.AdmissionRequest.UserInfo.Groups, group) {
-			log.Info("User is in allowed group (from AdmissionRequest)", "group", group)
-			return true
-		}
-	}
-
-	// Check groups from UserInfo as fallback (avoid duplicates by checking separately)
-	for _, group := range sreAdminGroups {
-		if slices.Contains(request.UserInfo.Groups, group) {
-			log.Info("User is in allowed group (from UserInfo)", "group", group)
-			return true
-		}
+	if explain.FromContext(ctx).Record(allowlist.Allowed(WebhookName, userInfo), "user in allowlist") {
+		log.Info("User is in the allowlist configuration", "username", userInfo.Username)
+		return true
 	}
 
-	log.Info("User is not authorized", "username", username)
+	log.Info("User is not authorized", "username", userInfo.Username, "groups", userInfo.Groups)
 	return false
 }
 
@@ -246,6 +221,11 @@ func (w *NetworkOperatorWebhook) Validate(req admissionctl.Request) bool {
 	return valid
 }
 
+// SelfTestKind implements SelfTestWebhook interface
+func (w *NetworkOperatorWebhook) SelfTestKind() metav1.GroupKind {
+	return metav1.GroupKind{Group: "operator.openshift.io", Kind: "Network"}
+}
+
 // Name is the name of the webhook
 func (w *NetworkOperatorWebhook) Name() string { return WebhookName }
 
@@ -292,6 +272,9 @@ func (w *NetworkOperatorWebhook) ClassicEnabled() bool { return true }
 // HypershiftEnabled will return boolean value for hypershift enabled configurations
 func (w *NetworkOperatorWebhook) HypershiftEnabled() bool { return true }
 
+// AllowlistEnabled implements AllowlistWebhook interface
+func (w *NetworkOperatorWebhook) AllowlistEnabled() bool { return true }
+
 // NewWebhook creates a new webhook
 func NewWebhook() *NetworkOperatorWebhook {
 	scheme := runtime.NewScheme()
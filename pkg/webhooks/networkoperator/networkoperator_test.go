@@ -19,6 +19,7 @@ func TestAuthorized(t *testing.T) {
 		Name          string
 		Request       admissionctl.Request
 		ExpectAllowed bool
+		ExpectFields  []string
 	}{
 		{
 			Name: "cluster-admin user modifying migration.networkType should be denied",
@@ -64,6 +65,7 @@ func TestAuthorized(t *testing.T) {
 				},
 			},
 			ExpectAllowed: false,
+			ExpectFields:  []string{"spec.migration.networkType"},
 		},
 		{
 			Name: "regular user modifying migration.networkType should be denied",
@@ -109,6 +111,7 @@ func TestAuthorized(t *testing.T) {
 				},
 			},
 			ExpectAllowed: false,
+			ExpectFields:  []string{"spec.migration.networkType"},
 		},
 		{
 			Name: "SRE service account modifying migration.networkType should be allowed",
@@ -196,6 +199,7 @@ func TestAuthorized(t *testing.T) {
 				},
 			},
 			ExpectAllowed: false,
+			ExpectFields:  []string{"spec.migration"},
 		},
 		{
 			Name: "adding migration field when it was nil should be denied",
@@ -236,6 +240,7 @@ func TestAuthorized(t *testing.T) {
 				},
 			},
 			ExpectAllowed: false,
+			ExpectFields:  []string{"spec.migration"},
 		},
 		{
 			Name: "removing migration field should be denied",
@@ -276,6 +281,7 @@ func TestAuthorized(t *testing.T) {
 				},
 			},
 			ExpectAllowed: false,
+			ExpectFields:  []string{"spec.migration"},
 		},
 		{
 			Name: "modifying non-critical fields should be allowed",
@@ -608,6 +614,7 @@ func TestAuthorized(t *testing.T) {
 				},
 			},
 			ExpectAllowed: false,
+			ExpectFields:  []string{"spec.migration.features"},
 		},
 		{
 			Name: "adding migration.features when it was nil should be denied",
@@ -655,6 +662,7 @@ func TestAuthorized(t *testing.T) {
 				},
 			},
 			ExpectAllowed: false,
+			ExpectFields:  []string{"spec.migration.features"},
 		},
 		{
 			Name: "modifying migration.mtu.network should be denied",
@@ -711,6 +719,7 @@ func TestAuthorized(t *testing.T) {
 				},
 			},
 			ExpectAllowed: false,
+			ExpectFields:  []string{"spec.migration.mtu.network"},
 		},
 		{
 			Name: "modifying migration.mtu.machine should be denied",
@@ -767,6 +776,7 @@ func TestAuthorized(t *testing.T) {
 				},
 			},
 			ExpectAllowed: false,
+			ExpectFields:  []string{"spec.migration.mtu.machine"},
 		},
 		{
 			Name: "adding migration.mtu when it was nil should be denied",
@@ -817,6 +827,7 @@ func TestAuthorized(t *testing.T) {
 				},
 			},
 			ExpectAllowed: false,
+			ExpectFields:  []string{"spec.migration.mtu"},
 		},
 	}
 
@@ -829,6 +840,17 @@ func TestAuthorized(t *testing.T) {
 				t.Errorf("TestAuthorized() %s: request %v - allowed: %t, expected: %t, reason: %s\n",
 					test.Name, test.Request, ret.Allowed, test.ExpectAllowed, ret.Result.Message)
 			}
+			var fields []string
+			if ret.Result.Details != nil {
+				for _, cause := range ret.Result.Details.Causes {
+					if cause.Field != "" {
+						fields = append(fields, cause.Field)
+					}
+				}
+			}
+			if !reflect.DeepEqual(fields, test.ExpectFields) {
+				t.Errorf("TestAuthorized() %s: expected field causes %v, got %v", test.Name, test.ExpectFields, fields)
+			}
 		})
 	}
 }
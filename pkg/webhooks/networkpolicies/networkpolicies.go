@@ -1,13 +1,15 @@
 package networkpolicies
 
 import (
+	"context"
 	"fmt"
 	"net/http"
-	"regexp"
-	"slices"
 	"strings"
 
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/allowlist"
 	hookconfig "github.com/openshift/managed-cluster-validating-webhooks/pkg/config"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/explain"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/helpers"
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
 
 	admissionregv1 "k8s.io/api/admissionregistration/v1"
@@ -24,12 +26,13 @@ const (
 )
 
 var (
-	timeout                          int32 = 2
-	allowedUsers                           = []string{"system:admin", "backplane-cluster-admin"}
-	sreAdminGroups                         = []string{"system:serviceaccounts:openshift-backplane-srep"}
-	privilegedServiceAccountGroupsRe       = regexp.MustCompile(utils.PrivilegedServiceAccountGroups)
-	scope                                  = admissionregv1.NamespacedScope
-	rules                                  = []admissionregv1.RuleWithOperations{
+	timeout           int32 = 2
+	allowedPrincipals       = utils.Principals{
+		Users:  []string{"system:admin", "backplane-cluster-admin"},
+		Groups: []string{"system:serviceaccounts:openshift-backplane-srep"},
+	}
+	scope = admissionregv1.NamespacedScope
+	rules = []admissionregv1.RuleWithOperations{
 		{
 			Operations: []admissionregv1.OperationType{
 				admissionregv1.Create,
@@ -62,12 +65,17 @@ func NewWebhook() *networkpoliciesruleWebhook {
 
 // Authorized implements Webhook interface
 func (s *networkpoliciesruleWebhook) Authorized(request admissionctl.Request) admissionctl.Response {
-	//Implement authorized next
-	return s.authorized(request)
+	return s.authorized(context.Background(), request)
+}
+
+// AuthorizedWithContext implements ContextWebhook interface
+func (s *networkpoliciesruleWebhook) AuthorizedWithContext(ctx context.Context, request admissionctl.Request) admissionctl.Response {
+	return s.authorized(ctx, request)
 }
 
-func (s *networkpoliciesruleWebhook) authorized(request admissionctl.Request) admissionctl.Response {
+func (s *networkpoliciesruleWebhook) authorized(ctx context.Context, request admissionctl.Request) admissionctl.Response {
 	var ret admissionctl.Response
+	trace := explain.FromContext(ctx)
 
 	np, err := s.renderNetworkPolicy(request)
 	if err != nil {
@@ -75,41 +83,35 @@ func (s *networkpoliciesruleWebhook) authorized(request admissionctl.Request) ad
 		return admissionctl.Errored(http.StatusBadRequest, err)
 	}
 
-	if !isAllowedNamespace(np.GetNamespace()) {
+	if !trace.Record(isAllowedNamespace(np.GetNamespace()), "namespace is not privileged or is openshift-ingress") {
 		log.Info(fmt.Sprintf("%s operation detected on managed namespace: %s", request.Operation, np.GetNamespace()))
-		if isAllowedUser(request) {
+		if isAllowedUser(ctx, request) {
 			ret = admissionctl.Allowed(fmt.Sprintf("User '%s' in group(s) '%s' can operate on NetworkPolicies", request.UserInfo.Username, strings.Join(request.UserInfo.Groups, ", ")))
 			ret.UID = request.AdmissionRequest.UID
 			return ret
 		}
-		for _, group := range request.UserInfo.Groups {
-			if privilegedServiceAccountGroupsRe.Match([]byte(group)) {
-				ret = admissionctl.Allowed(fmt.Sprintf("Privileged service accounts in group(s) '%s' can operate on NetworkPolicies", strings.Join(request.UserInfo.Groups, ", ")))
-				ret.UID = request.AdmissionRequest.UID
-				return ret
-			}
+		if match, ok := utils.PrivilegedServiceAccounts.ExplainRequest(ctx, "privileged service accounts", request); ok {
+			ret = admissionctl.Allowed(fmt.Sprintf("Privileged service accounts can operate on NetworkPolicies: %s", match))
+			ret.UID = request.AdmissionRequest.UID
+			return ret
 		}
 
-		ret = admissionctl.Denied(fmt.Sprintf("User '%s' prevented from accessing Red Mat managed resources. This is in an effort to prevent harmful actions that may cause unintended consequences or affect the stability of the cluster. If you have any questions about this, please reach out to Red Hat support at https://access.redhat.com/support", request.UserInfo.Username))
-		ret.UID = request.AdmissionRequest.UID
+		ret = utils.WebhookResponse(request, false, "", utils.WithCode(helpers.CodeManagedResource, nil))
 		return ret
 	}
 
-	if np.GetNamespace() == "openshift-ingress" {
+	if trace.Record(np.GetNamespace() == "openshift-ingress", "namespace is openshift-ingress") {
 		// Allow privileged service accounts (e.g. redhat-*, openshift-*) to
 		// manage NetworkPolicies for non-ingress-controller pods deployed in
 		// this namespace, such as kube-auth-proxy or payload-processing.
-		for _, group := range request.UserInfo.Groups {
-			if privilegedServiceAccountGroupsRe.Match([]byte(group)) {
-				ret = admissionctl.Allowed(fmt.Sprintf("Privileged service accounts in group(s) '%s' can operate on NetworkPolicies in openshift-ingress", strings.Join(request.UserInfo.Groups, ", ")))
-				ret.UID = request.AdmissionRequest.UID
-				return ret
-			}
+		if match, ok := utils.PrivilegedServiceAccounts.ExplainRequest(ctx, "privileged service accounts", request); ok {
+			ret = admissionctl.Allowed(fmt.Sprintf("Privileged service accounts can operate on NetworkPolicies in openshift-ingress: %s", match))
+			ret.UID = request.AdmissionRequest.UID
+			return ret
 		}
 		ingressName, labelFound := np.Spec.PodSelector.MatchLabels["ingresscontroller.operator.openshift.io/deployment-ingresscontroller"]
-		if !labelFound || ingressName == "default" {
-			ret = admissionctl.Denied(fmt.Sprintf("User '%s' prevented from creating network policy that may impact default ingress, which is managed by Red Hat. This is in an effort to prevent harmful actions that may cause unintended consequences or affect the stability of the cluster. If you have any questions about this, please reach out to Red Hat support at https://access.redhat.com/support", request.UserInfo.Username))
-			ret.UID = request.AdmissionRequest.UID
+		if trace.Record(!labelFound || ingressName == "default", "pod selector has no ingresscontroller label or the default one") {
+			ret = utils.WebhookResponse(request, false, "", utils.WithCode(helpers.CodeNetworkPolicyIngress, nil))
 			return ret
 		}
 	}
@@ -125,18 +127,9 @@ func isAllowedNamespace(namespace string) bool {
 }
 
 // isAllowedUser checks if the user or group is allowed to perform the action
-func isAllowedUser(request admissionctl.Request) bool {
-	if slices.Contains(allowedUsers, request.UserInfo.Username) {
-		return true
-	}
-
-	for _, group := range sreAdminGroups {
-		if slices.Contains(request.UserInfo.Groups, group) {
-			return true
-		}
-	}
-
-	return false
+func isAllowedUser(ctx context.Context, request admissionctl.Request) bool {
+	return explain.FromContext(ctx).Record(allowlist.Allowed(WebhookName, request.AdmissionRequest.UserInfo), "user in allowlist") ||
+		allowedPrincipals.ExplainsRequest(ctx, "allowed principals", request)
 }
 
 func (s *networkpoliciesruleWebhook) renderNetworkPolicy(req admissionctl.Request) (*networkingv1.NetworkPolicy, error) {
@@ -165,6 +158,11 @@ func (s *networkpoliciesruleWebhook) Validate(request admissionctl.Request) bool
 	return valid
 }
 
+// SelfTestKind implements SelfTestWebhook interface
+func (s *networkpoliciesruleWebhook) SelfTestKind() metav1.GroupKind {
+	return metav1.GroupKind{Group: "networking.k8s.io", Kind: "NetworkPolicy"}
+}
+
 // Name implements Webhook interface
 func (s *networkpoliciesruleWebhook) Name() string {
 	return WebhookName
@@ -214,3 +212,6 @@ func (s *networkpoliciesruleWebhook) SyncSetLabelSelector() metav1.LabelSelector
 func (s *networkpoliciesruleWebhook) ClassicEnabled() bool { return true }
 
 func (s *networkpoliciesruleWebhook) HypershiftEnabled() bool { return false }
+
+// AllowlistEnabled implements AllowlistWebhook interface
+func (s *networkpoliciesruleWebhook) AllowlistEnabled() bool { return true }
@@ -1,10 +1,13 @@
 package node
 
 import (
+	"context"
+	"fmt"
 	"net/http"
-	"slices"
-	"strings"
 
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/allowlist"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/explain"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/helpers"
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/localmetrics"
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
 	admissionv1 "k8s.io/api/admission/v1"
@@ -26,10 +29,12 @@ const (
 )
 
 var (
-	adminGroups = []string{"system:serviceaccounts:openshift-backplane-srep"}
-	adminUsers  = []string{"backplane-cluster-admin"}
-	scope       = admissionregv1.AllScopes
-	rules       = []admissionregv1.RuleWithOperations{
+	admins = utils.Principals{
+		Users:  []string{"backplane-cluster-admin"},
+		Groups: []string{"system:serviceaccounts:openshift-backplane-srep"},
+	}
+	scope = admissionregv1.AllScopes
+	rules = []admissionregv1.RuleWithOperations{
 		{
 			Operations: []admissionregv1.OperationType{
 				admissionregv1.OperationType(admissionv1.Create),
@@ -94,44 +99,47 @@ func (s *NodeWebhook) Validate(req admissionctl.Request) bool {
 	return valid
 }
 
+// SelfTestKind implements SelfTestWebhook interface
+func (s *NodeWebhook) SelfTestKind() metav1.GroupKind {
+	return metav1.GroupKind{Kind: "Node"}
+}
+
 // Authorized implements Webhook interface
 func (s *NodeWebhook) Authorized(request admissionctl.Request) admissionctl.Response {
-	return s.authorized(request)
+	return s.authorized(context.Background(), request)
+}
+
+// AuthorizedWithContext implements ContextWebhook interface
+func (s *NodeWebhook) AuthorizedWithContext(ctx context.Context, request admissionctl.Request) admissionctl.Response {
+	return s.authorized(ctx, request)
 }
 
-func (s *NodeWebhook) authorized(request admissionctl.Request) admissionctl.Response {
+func (s *NodeWebhook) authorized(ctx context.Context, request admissionctl.Request) admissionctl.Response {
 	var ret admissionctl.Response
+	trace := explain.FromContext(ctx)
 
-	if request.AdmissionRequest.UserInfo.Username == "system:unauthenticated" {
+	if utils.UnauthenticatedUsers.ExplainsRequest(ctx, "unauthenticated users", request) {
 		// This could highlight a significant problem with RBAC since an
 		// unauthenticated user should have no permissions.
 		log.Info("system:unauthenticated made a webhook request. Check RBAC rules", "request", request.AdmissionRequest)
-		ret = admissionctl.Denied("Unauthenticated")
-		ret.UID = request.AdmissionRequest.UID
+		ret = utils.WebhookResponse(request, false, "", utils.WithCode(helpers.CodeUnauthenticated, nil))
 		return ret
 	}
-	if strings.HasPrefix(request.AdmissionRequest.UserInfo.Username, "system:") {
-		ret = admissionctl.Allowed("authenticated system: users are allowed")
+	if match, ok := utils.SystemUsers.ExplainRequest(ctx, "system users", request); ok {
+		ret = admissionctl.Allowed(fmt.Sprintf("authenticated system users are allowed: %s", match))
 		ret.UID = request.AdmissionRequest.UID
 		return ret
 	}
-	if strings.HasPrefix(request.AdmissionRequest.UserInfo.Username, "kube:") {
-		ret = admissionctl.Allowed("kube: users are allowed")
+	if match, ok := admins.ExplainRequest(ctx, "admins", request); ok {
+		ret = admissionctl.Allowed(fmt.Sprintf("Specified admin users and members of admin groups are allowed: %s", match))
 		ret.UID = request.AdmissionRequest.UID
 		return ret
 	}
-	if slices.Contains(adminUsers, request.AdmissionRequest.UserInfo.Username) {
-		ret = admissionctl.Allowed("Specified admin users are allowed")
+	if trace.Record(allowlist.Allowed(WebhookName, request.AdmissionRequest.UserInfo), "user in allowlist") {
+		ret = admissionctl.Allowed("Allowlisted users are allowed")
 		ret.UID = request.AdmissionRequest.UID
 		return ret
 	}
-	for _, userGroup := range request.UserInfo.Groups {
-		if slices.Contains(adminGroups, userGroup) {
-			ret = admissionctl.Allowed("Members of admin groups are allowed")
-			ret.UID = request.AdmissionRequest.UID
-			return ret
-		}
-	}
 
 	//Checks for non-adminGroups non-ceeGroup non-adminGroups users
 	if request.Kind.Kind == "Node" {
@@ -153,34 +161,30 @@ func (s *NodeWebhook) authorized(request admissionctl.Request) admissionctl.Resp
 		}
 		log.Info("Processing request for", "node", node.Name, "operation", request.Operation, "user", request.UserInfo.Username)
 
-		if request.Operation == admissionv1.Delete {
+		if trace.Recordf(request.Operation == admissionv1.Delete, "operation is %s", admissionv1.Delete) {
 			localmetrics.IncrementNodeWebhookBlockedRequest(request.UserInfo.Username)
-			ret = admissionctl.Denied("Prevented from deleting nodes. This is in an effort to prevent harmful actions that may cause unintended consequences or affect the stability of the cluster. If you have any questions about this, please reach out to Red Hat support at https://access.redhat.com/support")
-			ret.UID = request.AdmissionRequest.UID
+			ret = utils.WebhookResponse(request, false, "", utils.WithCode(helpers.CodeNodeDelete, nil))
 			return ret
 		}
 
-		if _, ok := node.Labels["node-role.kubernetes.io/infra"]; ok {
+		if _, ok := node.Labels["node-role.kubernetes.io/infra"]; trace.Record(ok, "node has the node-role.kubernetes.io/infra label") {
 			localmetrics.IncrementNodeWebhookBlockedRequest(request.UserInfo.Username)
 			log.Info("Denying access to infra node")
-			ret = admissionctl.Denied("Prevented from modifying Red Hat managed infra nodes. This is in an effort to prevent harmful actions that may cause unintended consequences or affect the stability of the cluster. If you have any questions about this, please reach out to Red Hat support at https://access.redhat.com/support")
-			ret.UID = request.AdmissionRequest.UID
+			ret = utils.WebhookResponse(request, false, "", utils.WithCode(helpers.CodeNodeManaged, helpers.Args{"Role": "infra"}))
 			return ret
 		}
 
-		if _, ok := node.Labels["node-role.kubernetes.io/control-plane"]; ok {
+		if _, ok := node.Labels["node-role.kubernetes.io/control-plane"]; trace.Record(ok, "node has the node-role.kubernetes.io/control-plane label") {
 			localmetrics.IncrementNodeWebhookBlockedRequest(request.UserInfo.Username)
 			log.Info("Denying access to control plane node")
-			ret = admissionctl.Denied("Prevented from modifying Red Hat managed control plane nodes. This is in an effort to prevent harmful actions that may cause unintended consequences or affect the stability of the cluster. If you have any questions about this, please reach out to Red Hat support at https://access.redhat.com/support")
-			ret.UID = request.AdmissionRequest.UID
+			ret = utils.WebhookResponse(request, false, "", utils.WithCode(helpers.CodeNodeManaged, helpers.Args{"Role": "control plane"}))
 			return ret
 		}
 
-		if _, ok := node.Labels["node-role.kubernetes.io/master"]; ok {
+		if _, ok := node.Labels["node-role.kubernetes.io/master"]; trace.Record(ok, "node has the node-role.kubernetes.io/master label") {
 			localmetrics.IncrementNodeWebhookBlockedRequest(request.UserInfo.Username)
 			log.Info("Denying access to control plane node")
-			ret = admissionctl.Denied("Prevented from modifying Red Hat managed master nodes. This is in an effort to prevent harmful actions that may cause unintended consequences or affect the stability of the cluster. If you have any questions about this, please reach out to Red Hat support at https://access.redhat.com/support")
-			ret.UID = request.AdmissionRequest.UID
+			ret = utils.WebhookResponse(request, false, "", utils.WithCode(helpers.CodeNodeManaged, helpers.Args{"Role": "master"}))
 			return ret
 		}
 
@@ -191,8 +195,7 @@ func (s *NodeWebhook) authorized(request admissionctl.Request) admissionctl.Resp
 
 	// Should never get here
 	log.Info("Unexpectedly denying access", "request", request.AdmissionRequest)
-	ret = admissionctl.Denied("Prevented from accessing Red Hat managed resources. This is in an effort to prevent harmful actions that may cause unintended consequences or affect the stability of the cluster. If you have any questions about this, please reach out to Red Hat support at https://access.redhat.com/support")
-	ret.UID = request.AdmissionRequest.UID
+	ret = utils.WebhookResponse(request, false, "", utils.WithCode(helpers.CodeManagedResource, nil))
 	return ret
 }
 
@@ -205,6 +208,9 @@ func (s *NodeWebhook) ClassicEnabled() bool { return true }
 
 func (s *NodeWebhook) HypershiftEnabled() bool { return false }
 
+// AllowlistEnabled implements AllowlistWebhook interface
+func (s *NodeWebhook) AllowlistEnabled() bool { return true }
+
 // NewWebhook creates a new webhook
 func NewWebhook() *NodeWebhook {
 	return &NodeWebhook{
@@ -1,6 +1,7 @@
 package pod
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"os"
@@ -16,12 +17,16 @@ import (
 	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
 	hookconfig "github.com/openshift/managed-cluster-validating-webhooks/pkg/config"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/explain"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/helpers"
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
 )
 
 const (
 	WebhookName           string = "pod-validation"
 	unprivilegedNamespace string = `(openshift-logging|openshift-operators)`
+	infraTaintKey         string = "node-role.kubernetes.io/infra"
+	masterTaintKey        string = "node-role.kubernetes.io/master"
 	docString             string = `Managed OpenShift Customers may use tolerations on Pods that could cause those Pods to be scheduled on infra or master nodes.`
 )
 
@@ -93,6 +98,11 @@ func (s *PodWebhook) Validate(req admissionctl.Request) bool {
 	return valid
 }
 
+// SelfTestKind implements SelfTestWebhook interface
+func (s *PodWebhook) SelfTestKind() metav1.GroupKind {
+	return metav1.GroupKind{Kind: "Pod"}
+}
+
 func (s *PodWebhook) renderPod(req admissionctl.Request) (*corev1.Pod, error) {
 	decoder := admissionctl.NewDecoder(&s.s)
 	pod := &corev1.Pod{}
@@ -120,10 +130,15 @@ func isRequestPrivileged(namespace string) bool {
 
 // Authorized implements Webhook interface
 func (s *PodWebhook) Authorized(request admissionctl.Request) admissionctl.Response {
-	return s.authorized(request)
+	return s.authorized(context.Background(), request)
 }
 
-func (s *PodWebhook) authorized(request admissionctl.Request) admissionctl.Response {
+// AuthorizedWithContext implements ContextWebhook interface
+func (s *PodWebhook) AuthorizedWithContext(ctx context.Context, request admissionctl.Request) admissionctl.Response {
+	return s.authorized(ctx, request)
+}
+
+func (s *PodWebhook) authorized(ctx context.Context, request admissionctl.Request) admissionctl.Response {
 	var ret admissionctl.Response
 	pod, err := s.renderPod(request)
 	if err != nil {
@@ -133,37 +148,52 @@ func (s *PodWebhook) authorized(request admissionctl.Request) admissionctl.Respo
 
 	// If the incoming Pod is aimed at a privileged namespace except for unprivilegedNamespace, allow it to do whatever it wants.
 	// However, if the pod is targeting a customer's namespace (aka non-privileged), then it may not tolerate certain master/infra node taints.
-	if !isRequestPrivileged(pod.ObjectMeta.GetNamespace()) {
-		for _, toleration := range pod.Spec.Tolerations {
-			if toleration.Key == "node-role.kubernetes.io/infra" && toleration.Effect == corev1.TaintEffectNoSchedule {
-				ret = admissionctl.Denied("Not allowed to schedule a pod with NoSchedule taint on infra node")
-				ret.UID = request.AdmissionRequest.UID
-				return ret
+	trace := explain.FromContext(ctx)
+	var warnings []string
+	if !trace.Recordf(isRequestPrivileged(pod.ObjectMeta.GetNamespace()), "namespace is privileged and does not match %s", unprivilegedNamespace) {
+		for i, toleration := range pod.Spec.Tolerations {
+			tolerates := func(key string, effect corev1.TaintEffect) bool {
+				return trace.Recordf(toleration.Key == key && toleration.Effect == effect, "toleration %d tolerates %s:%s", i, key, effect)
+			}
+			if tolerates(infraTaintKey, corev1.TaintEffectNoSchedule) {
+				return utils.WebhookResponse(request, false, "", utils.WithCode(helpers.CodePodTolerations, helpers.Args{"Effect": "NoSchedule", "Role": "infra"}), utils.WithRuleID("infra-noschedule"))
 			}
-			if toleration.Key == "node-role.kubernetes.io/infra" && toleration.Effect == corev1.TaintEffectPreferNoSchedule {
-				ret = admissionctl.Denied("Not allowed to schedule a pod with PreferNoSchedule taint on infra node")
-				ret.UID = request.AdmissionRequest.UID
-				return ret
+			if tolerates(infraTaintKey, corev1.TaintEffectPreferNoSchedule) {
+				return utils.WebhookResponse(request, false, "", utils.WithCode(helpers.CodePodTolerations, helpers.Args{"Effect": "PreferNoSchedule", "Role": "infra"}), utils.WithRuleID("infra-prefernoschedule"))
 			}
-			if toleration.Key == "node-role.kubernetes.io/master" && toleration.Effect == corev1.TaintEffectNoSchedule {
-				ret = admissionctl.Denied("Not allowed to schedule a pod with NoSchedule taint on master node")
-				ret.UID = request.AdmissionRequest.UID
-				return ret
+			if tolerates(masterTaintKey, corev1.TaintEffectNoSchedule) {
+				return utils.WebhookResponse(request, false, "", utils.WithCode(helpers.CodePodTolerations, helpers.Args{"Effect": "NoSchedule", "Role": "master"}), utils.WithRuleID("master-noschedule"))
 			}
-			if toleration.Key == "node-role.kubernetes.io/master" && toleration.Effect == corev1.TaintEffectPreferNoSchedule {
-				ret = admissionctl.Denied("Not allowed to schedule a pod with PreferNoSchedule taint on master node")
-				ret.UID = request.AdmissionRequest.UID
-				return ret
+			if tolerates(masterTaintKey, corev1.TaintEffectPreferNoSchedule) {
+				return utils.WebhookResponse(request, false, "", utils.WithCode(helpers.CodePodTolerations, helpers.Args{"Effect": "PreferNoSchedule", "Role": "master"}), utils.WithRuleID("master-prefernoschedule"))
+			}
+			if warning := tolerationWarning(toleration); warning != "" {
+				warnings = append(warnings, warning)
 			}
 		}
 	}
 
 	// Hereafter, all requests are controlled by RBAC
-	ret = admissionctl.Allowed("Allowed to create Pod because of RBAC")
-	ret.UID = request.AdmissionRequest.UID
+	ret = utils.WebhookResponse(request, true, "Allowed to create Pod because of RBAC", utils.WithWarnings(warnings...))
 	return ret
 }
 
+// tolerationWarning returns a warning for a toleration which is allowed today
+// but will be rejected in a future release because, by tolerating every
+// effect, it tolerates the infra or master NoSchedule taints
+func tolerationWarning(toleration corev1.Toleration) string {
+	if toleration.Effect != "" {
+		return ""
+	}
+	switch {
+	case toleration.Key == infraTaintKey || toleration.Key == masterTaintKey:
+		return fmt.Sprintf("A toleration of every effect of the %s taint will be rejected in a future release, tolerate only the NoExecute effect instead", toleration.Key)
+	case toleration.Key == "" && toleration.Operator == corev1.TolerationOpExists:
+		return "A toleration of every taint will be rejected in a future release, as it tolerates the infra and master node taints"
+	}
+	return ""
+}
+
 // SyncSetLabelSelector returns the label selector to use in the SyncSet.
 func (s *PodWebhook) SyncSetLabelSelector() metav1.LabelSelector {
 	return utils.DefaultLabelSelector()
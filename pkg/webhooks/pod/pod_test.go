@@ -11,6 +11,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/testutils"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
 )
 
 func createRawPodJSON(name string, tolerations []corev1.Toleration, uid string, namespace string) (string, error) {
@@ -43,6 +44,8 @@ type podTestSuites struct {
 	userGroups      []string
 	tolerations     []corev1.Toleration
 	shouldBeAllowed bool
+	// expectedWarnings is how many admission warnings the response has
+	expectedWarnings int
 }
 
 func runPodTests(t *testing.T, tests []podTestSuites) {
@@ -85,6 +88,12 @@ func runPodTests(t *testing.T, tests []podTestSuites) {
 		if response.Allowed != test.shouldBeAllowed {
 			t.Fatalf("Mismatch: %s (groups=%s) %s %s the pod. Test's expectation is that the user %s", test.username, test.userGroups, testutils.CanCanNot(response.Allowed), test.operation, testutils.CanCanNot(test.shouldBeAllowed))
 		}
+		if len(response.Warnings) != test.expectedWarnings {
+			t.Fatalf("%s: expected %d warnings, got %v", test.testID, test.expectedWarnings, response.Warnings)
+		}
+		if !response.Allowed && response.AuditAnnotations[utils.RuleIDAnnotation] == "" {
+			t.Fatalf("%s: expected the denial to name the rule which denied it, got %v", test.testID, response.AuditAnnotations)
+		}
 	}
 }
 
@@ -583,3 +592,74 @@ func TestUserPositive(t *testing.T) {
 	}
 	runPodTests(t, tests)
 }
+
+func TestTolerationsRejectedInFutureRelease(t *testing.T) {
+	tests := []podTestSuites{
+		{
+			testID:     "every-infra-effect",
+			targetPod:  "my-test-pod",
+			namespace:  "my-monitoring",
+			username:   "system:unauthenticated",
+			userGroups: []string{"system:unauthenticated"},
+			operation:  admissionv1.Create,
+			tolerations: []corev1.Toleration{
+				{
+					Key:      "node-role.kubernetes.io/infra",
+					Operator: corev1.TolerationOpExists,
+				},
+			},
+			shouldBeAllowed:  true,
+			expectedWarnings: 1,
+		},
+		{
+			testID:     "every-taint",
+			targetPod:  "my-test-pod",
+			namespace:  "my-monitoring",
+			username:   "system:unauthenticated",
+			userGroups: []string{"system:unauthenticated"},
+			operation:  admissionv1.Create,
+			tolerations: []corev1.Toleration{
+				{
+					Operator: corev1.TolerationOpExists,
+				},
+				{
+					Key:      "node-role.kubernetes.io/master",
+					Operator: corev1.TolerationOpExists,
+				},
+			},
+			shouldBeAllowed:  true,
+			expectedWarnings: 2,
+		},
+		{
+			testID:     "privileged-namespace",
+			targetPod:  "my-test-pod",
+			namespace:  privilegedNamespace,
+			username:   "system:unauthenticated",
+			userGroups: []string{"system:unauthenticated"},
+			operation:  admissionv1.Create,
+			tolerations: []corev1.Toleration{
+				{
+					Operator: corev1.TolerationOpExists,
+				},
+			},
+			shouldBeAllowed: true,
+		},
+		{
+			testID:     "noexecute-only",
+			targetPod:  "my-test-pod",
+			namespace:  "my-monitoring",
+			username:   "system:unauthenticated",
+			userGroups: []string{"system:unauthenticated"},
+			operation:  admissionv1.Create,
+			tolerations: []corev1.Toleration{
+				{
+					Key:      "node-role.kubernetes.io/infra",
+					Operator: corev1.TolerationOpExists,
+					Effect:   corev1.TaintEffectNoExecute,
+				},
+			},
+			shouldBeAllowed: true,
+		},
+	}
+	runPodTests(t, tests)
+}
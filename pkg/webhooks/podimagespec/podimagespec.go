@@ -6,13 +6,17 @@ import (
 	"net/http"
 	"os"
 	"regexp"
+	"sync"
 
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/explain"
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/k8sutil"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/tracing"
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
 
 	imagestreamv1 "github.com/openshift/api/image/v1"
 	registryv1 "github.com/openshift/api/imageregistry/v1"
 	operatorv1 "github.com/openshift/api/operator/v1"
+	"go.opentelemetry.io/otel/attribute"
 	admissionv1 "k8s.io/api/admission/v1"
 	admissionregv1 "k8s.io/api/admissionregistration/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -51,7 +55,10 @@ var (
 
 // PodImageSpecWebhook mutates an image spec in a pod
 type PodImageSpecWebhook struct {
-	s          *runtime.Scheme
+	s *runtime.Scheme
+	// mu guards kubeClient, which is built on first use and then shared by
+	// concurrent requests
+	mu         sync.Mutex
 	kubeClient client.Client
 }
 
@@ -92,7 +99,13 @@ func NewWebhook() *PodImageSpecWebhook {
 
 // Authorized implements Webhook interface
 func (s *PodImageSpecWebhook) Authorized(request admissionctl.Request) admissionctl.Response {
-	ret := s.authorized(request)
+	return s.AuthorizedWithContext(context.Background(), request)
+}
+
+// AuthorizedWithContext implements ContextWebhook interface so that calls to
+// the API server are abandoned when the admission request's deadline passes
+func (s *PodImageSpecWebhook) AuthorizedWithContext(ctx context.Context, request admissionctl.Request) admissionctl.Response {
+	ret := s.authorized(ctx, request)
 	if err := ret.Complete(request); err != nil {
 		log.Error(err, "Failed to complete the request")
 		ret = admissionctl.Errored(http.StatusInternalServerError, err)
@@ -102,19 +115,16 @@ func (s *PodImageSpecWebhook) Authorized(request admissionctl.Request) admission
 	return ret
 }
 
-func (s *PodImageSpecWebhook) authorized(request admissionctl.Request) admissionctl.Response {
+func (s *PodImageSpecWebhook) authorized(ctx context.Context, request admissionctl.Request) admissionctl.Response {
 	var err error
 	var ret admissionctl.Response
-	ctx := context.Background()
+	trace := explain.FromContext(ctx)
 
-	if s.kubeClient == nil {
-		s.kubeClient, err = k8sutil.KubeClient(s.s)
-		if err != nil {
-			log.Error(err, "Fail creating KubeClient for PodImageSpecWebhook")
-			ret = admissionctl.Errored(http.StatusBadRequest, err)
-			ret.UID = request.AdmissionRequest.UID
-			return ret
-		}
+	if _, err = s.client(); err != nil {
+		log.Error(err, "Fail creating KubeClient for PodImageSpecWebhook")
+		ret = admissionctl.Errored(http.StatusBadRequest, err)
+		ret.UID = request.AdmissionRequest.UID
+		return ret
 	}
 
 	pod, err := s.renderPod(request)
@@ -125,7 +135,7 @@ func (s *PodImageSpecWebhook) authorized(request admissionctl.Request) admission
 		return ret
 	}
 
-	if !podContainsContainerRegexMatch(pod) {
+	if !trace.Record(podContainsContainerRegexMatch(pod), "a container image is in the internal registry's openshift namespace") {
 		ret = admissionctl.Allowed("Pod image spec is valid")
 		ret.UID = request.AdmissionRequest.UID
 		return ret
@@ -139,7 +149,7 @@ func (s *PodImageSpecWebhook) authorized(request admissionctl.Request) admission
 		return ret
 	}
 
-	if registryAvailable {
+	if trace.Recordf(registryAvailable, "image registry is %s", operatorv1.Managed) {
 		ret = admissionctl.Allowed("Image registry is available, no mutation required")
 		ret.UID = request.AdmissionRequest.UID
 		return ret
@@ -158,6 +168,28 @@ func (s *PodImageSpecWebhook) authorized(request admissionctl.Request) admission
 	return ret
 }
 
+// client returns the webhook's kube client, building it on first use. A failure
+// is not cached so that a later request may succeed.
+func (s *PodImageSpecWebhook) client() (client.Client, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.kubeClient == nil {
+		kubeClient, err := k8sutil.KubeClient(s.s)
+		if err != nil {
+			return nil, err
+		}
+		s.kubeClient = kubeClient
+	}
+	return s.kubeClient, nil
+}
+
+// CheckClusterAccess implements ClusterAccessWebhook interface by reading the
+// image registry configuration, as every mutation does
+func (s *PodImageSpecWebhook) CheckClusterAccess(ctx context.Context) error {
+	_, err := s.checkImageRegistryStatus(ctx)
+	return err
+}
+
 // renderPod renders the Pod in the admission Request
 func (s *PodImageSpecWebhook) renderPod(request admissionctl.Request) (*corev1.Pod, error) {
 	decoder := admissionctl.NewDecoder(s.s)
@@ -213,11 +245,17 @@ func (s *PodImageSpecWebhook) mutatePod(ctx context.Context, pod *corev1.Pod) ([
 
 // checkImageRegistryStatus checks the status of the image registry service
 func (s *PodImageSpecWebhook) checkImageRegistryStatus(ctx context.Context) (bool, error) {
-	var err error
+	ctx, span := tracing.Start(ctx, "get image registry config")
+	defer span.End()
 	registryV1 := &registryv1.Config{}
 
-	err = s.kubeClient.Get(ctx, client.ObjectKey{Name: "cluster"}, registryV1)
+	kubeClient, err := s.client()
+	if err != nil {
+		return false, err
+	}
+	err = kubeClient.Get(ctx, client.ObjectKey{Name: "cluster"}, registryV1)
 	if err != nil {
+		tracing.RecordError(span, err)
 		return false, fmt.Errorf("failed to get image registry config: %v", err)
 	}
 
@@ -242,17 +280,22 @@ func checkContainerImageSpecByRegex(imagespec string) (bool, string, string, str
 }
 
 func (s *PodImageSpecWebhook) lookupImageStreamTagSpec(ctx context.Context, imagespec string) (string, error) {
-	var err error
-
 	matched, namespace, image, tag := checkContainerImageSpecByRegex(imagespec)
 	if !matched {
 		return imagespec, nil
 	}
 
 	// get the image refrence from the imagestream
+	ctx, span := tracing.Start(ctx, "get ImageStreamTag", attribute.String("namespace", namespace), attribute.String("name", image+":"+tag))
+	defer span.End()
 	imageStreamTag := imagestreamv1.ImageStreamTag{}
-	err = s.kubeClient.Get(ctx, client.ObjectKey{Name: fmt.Sprintf("%s:%s", image, tag), Namespace: namespace}, &imageStreamTag)
+	kubeClient, err := s.client()
+	if err != nil {
+		return imagespec, err
+	}
+	err = kubeClient.Get(ctx, client.ObjectKey{Name: fmt.Sprintf("%s:%s", image, tag), Namespace: namespace}, &imageStreamTag)
 	if err != nil {
+		tracing.RecordError(span, err)
 		return imagespec, fmt.Errorf("failed to get image spec: %v", err)
 	}
 
@@ -276,6 +319,11 @@ func (s *PodImageSpecWebhook) Validate(request admissionctl.Request) bool {
 	return valid
 }
 
+// SelfTestKind implements SelfTestWebhook interface
+func (s *PodImageSpecWebhook) SelfTestKind() metav1.GroupKind {
+	return metav1.GroupKind{Kind: "Pod"}
+}
+
 // Name implements Webhook interface
 func (s *PodImageSpecWebhook) Name() string {
 	return WebhookName
@@ -0,0 +1,121 @@
+package policy
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// exemptCondition is the name of the match condition which skips the exempt
+// principals in a ValidatingAdmissionPolicy
+const exemptCondition = "exclude-exempt-principals"
+
+// AdmissionPolicy implements AdmissionPolicyWebhook interface. The exempt
+// principals become a match condition and a policy without validations gets
+// one which always fails with its message. Principals allowlisted at runtime
+// are not exempt from the ValidatingAdmissionPolicy. A policy with params
+// can't be rendered since there is no parameter resource to read them from.
+func (w *Webhook) AdmissionPolicy() (admissionregv1.ValidatingAdmissionPolicySpec, bool) {
+	if w.policy.Params != nil {
+		return admissionregv1.ValidatingAdmissionPolicySpec{}, false
+	}
+	spec := admissionregv1.ValidatingAdmissionPolicySpec{
+		Variables: w.policy.Variables,
+	}
+	// The policy's message is the fallback for validations without one of
+	// their own, which the API server doesn't know about
+	for _, v := range w.policy.Validations {
+		if v.Message == "" && v.MessageExpression == "" {
+			v.Message = w.policy.Message
+		}
+		spec.Validations = append(spec.Validations, v)
+	}
+	if exempt := w.policy.Exempt.celExpression(); exempt != "" {
+		spec.MatchConditions = append(spec.MatchConditions, admissionregv1.MatchCondition{
+			Name:       exemptCondition,
+			Expression: fmt.Sprintf("!(%s)", exempt),
+		})
+	}
+	spec.MatchConditions = append(spec.MatchConditions, w.policy.MatchConditions...)
+	if len(spec.Validations) == 0 {
+		reason := metav1.StatusReasonForbidden
+		spec.Validations = []admissionregv1.Validation{{
+			Expression: "false",
+			Message:    w.policy.Message,
+			Reason:     &reason,
+		}}
+	}
+	return spec, true
+}
+
+// ValidationActions implements AdmissionPolicyWebhook interface
+func (w *Webhook) ValidationActions() []admissionregv1.ValidationAction {
+	return w.policy.ValidationActions
+}
+
+// celExpression returns a CEL expression which is true for the principals'
+// requests, or an empty string if there are no principals
+func (e Principals) celExpression() string {
+	var clauses []string
+	if len(e.Users) > 0 {
+		clauses = append(clauses, fmt.Sprintf("request.userInfo.username in %s", celList(e.Users)))
+	}
+	for _, prefix := range e.UserPrefixes {
+		clauses = append(clauses, fmt.Sprintf("request.userInfo.username.startsWith(%s)", strconv.Quote(prefix)))
+	}
+	for _, pattern := range e.UserPatterns {
+		clauses = append(clauses, fmt.Sprintf("request.userInfo.username.matches(%s)", strconv.Quote(pattern)))
+	}
+	// A request's userInfo has no groups field when the user has no groups
+	groups := "(has(request.userInfo.groups) ? request.userInfo.groups : [])"
+	if len(e.Groups) > 0 {
+		clauses = append(clauses, fmt.Sprintf("%s.exists(g, g in %s)", groups, celList(e.Groups)))
+	}
+	for _, pattern := range e.GroupPatterns {
+		clauses = append(clauses, fmt.Sprintf("%s.exists(g, g.matches(%s))", groups, strconv.Quote(pattern)))
+	}
+	expression := strings.Join(clauses, " || ")
+	conditions := e.celConditions()
+	if expression == "" || len(conditions) == 0 {
+		return expression
+	}
+	return fmt.Sprintf("(%s) && %s", expression, strings.Join(conditions, " && "))
+}
+
+// celConditions returns the CEL expressions for RequiredExtra and
+// ExcludedExtra, which must all be true for the principals to match. Like the
+// groups, a request's userInfo has no extra field when it is empty.
+func (e Principals) celConditions() []string {
+	const extra = "(has(request.userInfo.extra) ? request.userInfo.extra : {})"
+	var conditions []string
+	for _, key := range slices.Sorted(maps.Keys(e.RequiredExtra)) {
+		conditions = append(conditions, celHasExtra(extra, key, e.RequiredExtra[key]))
+	}
+	for _, key := range slices.Sorted(maps.Keys(e.ExcludedExtra)) {
+		conditions = append(conditions, "!"+celHasExtra(extra, key, e.ExcludedExtra[key]))
+	}
+	return conditions
+}
+
+// celHasExtra returns a CEL expression which is true when the extra map has
+// key, with one of values if there are any
+func celHasExtra(extra, key string, values []string) string {
+	quoted := strconv.Quote(key)
+	if len(values) == 0 {
+		return fmt.Sprintf("(%s in %s)", quoted, extra)
+	}
+	return fmt.Sprintf("(%s in %s && %s[%s].exists(v, v in %s))", quoted, extra, extra, quoted, celList(values))
+}
+
+func celList(values []string) string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = strconv.Quote(value)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
@@ -0,0 +1,198 @@
+package policy
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/cel-go/cel"
+	admissionv1 "k8s.io/api/admission/v1"
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+func TestAdmissionPolicy(t *testing.T) {
+	p, err := Parse([]byte(testPolicy))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	spec, ok := NewWebhook(p).AdmissionPolicy()
+	if !ok {
+		t.Fatalf("Expected %s to be expressible as a ValidatingAdmissionPolicy", p.Name)
+	}
+	if len(spec.MatchConditions) != 1 || spec.MatchConditions[0].Name != exemptCondition {
+		t.Fatalf("Expected only the %s match condition, got %v", exemptCondition, spec.MatchConditions)
+	}
+	if len(spec.Validations) != 1 || spec.Validations[0].Expression != "false" || spec.Validations[0].Message != p.Message {
+		t.Errorf("Expected a validation which always fails with the policy's message, got %v", spec.Validations)
+	}
+	if spec.Validations[0].Reason == nil || *spec.Validations[0].Reason != metav1.StatusReasonForbidden {
+		t.Errorf("Expected reason %s, got %v", metav1.StatusReasonForbidden, spec.Validations[0].Reason)
+	}
+	if actions := NewWebhook(p).ValidationActions(); !reflect.DeepEqual(actions, []admissionregv1.ValidationAction{admissionregv1.Deny}) {
+		t.Errorf("Expected validation actions to default to Deny, got %v", actions)
+	}
+}
+
+func TestAdmissionPolicyCEL(t *testing.T) {
+	p, err := Parse([]byte(testCELPolicy))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if _, ok := NewWebhook(p).AdmissionPolicy(); ok {
+		t.Errorf("Expected a policy with params not to be expressible as a ValidatingAdmissionPolicy")
+	}
+
+	p.Params = nil
+	spec, ok := NewWebhook(p).AdmissionPolicy()
+	if !ok {
+		t.Fatalf("Expected %s to be expressible as a ValidatingAdmissionPolicy", p.Name)
+	}
+	if len(spec.MatchConditions) != 2 || spec.MatchConditions[0].Name != exemptCondition || spec.MatchConditions[1].Name != "not-deleted" {
+		t.Errorf("Expected the exempt match condition before the policy's, got %v", spec.MatchConditions)
+	}
+	if !reflect.DeepEqual(spec.Variables, p.Variables) {
+		t.Errorf("Expected the policy's variables to be used unchanged")
+	}
+	if len(spec.Validations) != len(p.Validations) {
+		t.Fatalf("Expected %d validations, got %d", len(p.Validations), len(spec.Validations))
+	}
+	for i, v := range spec.Validations {
+		want := p.Validations[i]
+		if want.Message == "" && want.MessageExpression == "" {
+			want.Message = p.Message
+		}
+		if !reflect.DeepEqual(v, want) {
+			t.Errorf("Expected validation %d to be %v, got %v", i, want, v)
+		}
+	}
+}
+
+// TestExemptCondition checks the exempt match condition excludes the same
+// requests as the webhook's exempt principals
+func TestExemptCondition(t *testing.T) {
+	p, err := Parse([]byte(testPolicy))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	spec, _ := NewWebhook(p).AdmissionPolicy()
+	condition, err := compile(spec.MatchConditions[0].Expression, cel.BoolType)
+	if err != nil {
+		t.Fatalf("Exempt match condition doesn't compile: %s", err)
+	}
+	exempt, err := p.Exempt.compile()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	tests := []struct {
+		username string
+		groups   []string
+	}{
+		{username: "admin"},
+		{username: "administrator"},
+		{username: "system:serviceaccount:openshift-widgets:widget-controller"},
+		{username: "system:serviceaccount:widgets:widget-controller"},
+		{username: "someone", groups: []string{"system:authenticated", "admins"}},
+		{username: "someone", groups: []string{"system:authenticated"}},
+		{username: "someone"},
+	}
+	for _, test := range tests {
+		req := request(test.username, test.groups, admissionv1.Delete, "example.com", "Widget")
+		activation, err := (&programs{}).activation(req)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		matches, err := condition.evalBool(context.Background(), activation)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", test.username, err)
+		}
+		if isExempt := exempt.Matches(req.UserInfo); matches == isExempt {
+			t.Errorf("%s %v: webhook exempt is %t but the match condition is %t", test.username, test.groups, isExempt, matches)
+		}
+	}
+}
+
+// TestExemptExtraCondition checks the exempt match condition narrows the
+// principals by their extra in the same way as the webhook
+func TestExemptExtraCondition(t *testing.T) {
+	p, err := Parse([]byte(strings.Replace(testPolicy, `  groups: ["admins"]`, `  groups: ["admins"]
+  requiredExtra:
+    authentication.kubernetes.io/credential-id: []
+  excludedExtra:
+    scopes.authorization.openshift.io: ["user:info", "user:check-access"]`, 1)))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	spec, _ := NewWebhook(p).AdmissionPolicy()
+	condition, err := compile(spec.MatchConditions[0].Expression, cel.BoolType)
+	if err != nil {
+		t.Fatalf("Exempt match condition doesn't compile: %s", err)
+	}
+	exempt, err := p.Exempt.compile()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	credential := []string{"JTI=1234"}
+	tests := []struct {
+		name     string
+		username string
+		extra    map[string]authenticationv1.ExtraValue
+		exempt   bool
+	}{
+		{name: "credential without scopes", username: "admin", extra: map[string]authenticationv1.ExtraValue{
+			"authentication.kubernetes.io/credential-id": credential,
+		}, exempt: true},
+		{name: "no credential", username: "admin"},
+		{name: "excluded scope", username: "admin", extra: map[string]authenticationv1.ExtraValue{
+			"authentication.kubernetes.io/credential-id": credential,
+			utils.ScopesExtraKey:                         {"user:info"},
+		}},
+		{name: "other scope", username: "admin", extra: map[string]authenticationv1.ExtraValue{
+			"authentication.kubernetes.io/credential-id": credential,
+			utils.ScopesExtraKey:                         {"user:full"},
+		}, exempt: true},
+		{name: "user who is not exempt", username: "someone", extra: map[string]authenticationv1.ExtraValue{
+			"authentication.kubernetes.io/credential-id": credential,
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := request(test.username, nil, admissionv1.Delete, "example.com", "Widget")
+			req.UserInfo.Extra = test.extra
+			activation, err := (&programs{}).activation(req)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			matches, err := condition.evalBool(context.Background(), activation)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if isExempt := exempt.Matches(req.UserInfo); isExempt != test.exempt || matches == isExempt {
+				t.Errorf("Expected exempt %t, the webhook's exempt is %t and the match condition is %t", test.exempt, isExempt, matches)
+			}
+		})
+	}
+}
+
+func TestEmbeddedAdmissionPolicies(t *testing.T) {
+	for _, p := range Embedded() {
+		spec, ok := NewWebhook(p).AdmissionPolicy()
+		if !ok {
+			continue
+		}
+		for _, condition := range spec.MatchConditions {
+			if _, err := compile(condition.Expression, cel.BoolType); err != nil {
+				t.Errorf("%s: match condition %s: %s", p.Name, condition.Name, err)
+			}
+		}
+		for _, validation := range spec.Validations {
+			if _, err := compile(validation.Expression, cel.BoolType); err != nil {
+				t.Errorf("%s: %s", p.Name, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,301 @@
+package policy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/ext"
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// The variables available to expressions, named as in a
+// ValidatingAdmissionPolicy so that expressions can be moved to one unchanged
+const (
+	objectVar    = "object"
+	oldObjectVar = "oldObject"
+	requestVar   = "request"
+	paramsVar    = "params"
+	variablesVar = "variables"
+)
+
+const (
+	// perCallLimit is the most an expression may cost to evaluate, the same
+	// limit the API server puts on each ValidatingAdmissionPolicy expression
+	perCallLimit = 1000000
+	// interruptCheckFrequency is how many comprehension iterations pass
+	// between checks of whether the request's deadline has passed
+	interruptCheckFrequency = 100
+)
+
+var validVariableName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// env is the CEL environment expressions are compiled in. It has the
+// variables and the standard extension libraries of the API server's
+// environment. The Kubernetes specific libraries, such as quantity, url and
+// authorizer, are not available, so expressions using them fail to compile.
+var env = func() *cel.Env {
+	e, err := cel.NewEnv(
+		cel.Variable(objectVar, cel.DynType),
+		cel.Variable(oldObjectVar, cel.DynType),
+		cel.Variable(requestVar, cel.DynType),
+		cel.Variable(paramsVar, cel.DynType),
+		cel.Variable(variablesVar, cel.MapType(cel.StringType, cel.DynType)),
+		cel.CrossTypeNumericComparisons(true),
+		cel.OptionalTypes(),
+		ext.Strings(ext.StringsVersion(2)),
+		ext.Sets(),
+	)
+	if err != nil {
+		panic(fmt.Sprintf("couldn't create CEL environment: %s", err))
+	}
+	return e
+}()
+
+// expression is a compiled CEL expression and its source
+type expression struct {
+	source  string
+	program cel.Program
+}
+
+// eval evaluates the expression, giving up when ctx is done
+func (e expression) eval(ctx context.Context, activation map[string]interface{}) (ref.Val, error) {
+	val, _, err := e.program.ContextEval(ctx, activation)
+	if err != nil {
+		return nil, fmt.Errorf("expression %q failed: %w", e.source, err)
+	}
+	return val, nil
+}
+
+// evalBool evaluates an expression which must return a bool
+func (e expression) evalBool(ctx context.Context, activation map[string]interface{}) (bool, error) {
+	val, err := e.eval(ctx, activation)
+	if err != nil {
+		return false, err
+	}
+	b, ok := val.(types.Bool)
+	if !ok {
+		return false, fmt.Errorf("expression %q returned %s, not bool", e.source, val.Type().TypeName())
+	}
+	return bool(b), nil
+}
+
+// compile type-checks source, which must return a value of type want or dyn.
+// Any type is accepted when want is dyn.
+func compile(source string, want *cel.Type) (expression, error) {
+	ast, issues := env.Compile(source)
+	if issues.Err() != nil {
+		return expression{}, fmt.Errorf("couldn't compile %q: %w", source, issues.Err())
+	}
+	if !want.IsExactType(cel.DynType) && !ast.OutputType().IsExactType(want) && !ast.OutputType().IsExactType(cel.DynType) {
+		return expression{}, fmt.Errorf("expression %q returns %s, not %s", source, ast.OutputType(), want)
+	}
+	program, err := env.Program(ast,
+		cel.CostLimit(perCallLimit),
+		cel.InterruptCheckFrequency(interruptCheckFrequency),
+	)
+	if err != nil {
+		return expression{}, fmt.Errorf("couldn't compile %q: %w", source, err)
+	}
+	return expression{source: source, program: program}, nil
+}
+
+// validation is a compiled admissionregv1.Validation
+type validation struct {
+	admissionregv1.Validation
+	expression expression
+	message    *expression
+}
+
+// variable is a compiled admissionregv1.Variable
+type variable struct {
+	name       string
+	expression expression
+}
+
+// programs are a policy's compiled CEL expressions
+type programs struct {
+	matchConditions []expression
+	variables       []variable
+	validations     []validation
+	params          interface{}
+}
+
+// compilePrograms compiles the policy's CEL expressions and decodes its params
+func (p *Policy) compilePrograms() (*programs, error) {
+	progs, err := compileExpressions(p.MatchConditions, p.Variables, p.Validations)
+	if err != nil {
+		return nil, err
+	}
+	if p.Params != nil {
+		params, err := decodeJSON(p.Params.Raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid params: %w", err)
+		}
+		progs.params = params
+	}
+	return progs, nil
+}
+
+// compileExpressions compiles the CEL expressions of a policy or a
+// ValidatingAdmissionPolicy
+func compileExpressions(matchConditions []admissionregv1.MatchCondition, variables []admissionregv1.Variable, validations []admissionregv1.Validation) (*programs, error) {
+	progs := &programs{}
+	for _, condition := range matchConditions {
+		expr, err := compile(condition.Expression, cel.BoolType)
+		if err != nil {
+			return nil, fmt.Errorf("match condition %s: %w", condition.Name, err)
+		}
+		progs.matchConditions = append(progs.matchConditions, expr)
+	}
+	for _, v := range variables {
+		if !validVariableName.MatchString(v.Name) {
+			return nil, fmt.Errorf("variable name %q must be a CEL identifier", v.Name)
+		}
+		expr, err := compile(v.Expression, cel.DynType)
+		if err != nil {
+			return nil, fmt.Errorf("variable %s: %w", v.Name, err)
+		}
+		progs.variables = append(progs.variables, variable{name: v.Name, expression: expr})
+	}
+	for i, v := range validations {
+		expr, err := compile(v.Expression, cel.BoolType)
+		if err != nil {
+			return nil, fmt.Errorf("validation %d: %w", i, err)
+		}
+		compiled := validation{Validation: v, expression: expr}
+		if v.MessageExpression != "" {
+			message, err := compile(v.MessageExpression, cel.StringType)
+			if err != nil {
+				return nil, fmt.Errorf("validation %d message: %w", i, err)
+			}
+			compiled.message = &message
+		}
+		progs.validations = append(progs.validations, compiled)
+	}
+	return progs, nil
+}
+
+// failureMessage returns the message for the failed validation v. As in a
+// ValidatingAdmissionPolicy, a messageExpression which fails or returns an
+// empty string falls back to the message, then to fallback and then to the
+// expression. The messageExpression's error is returned alongside the message.
+func (v validation) failureMessage(ctx context.Context, activation map[string]interface{}, fallback string) (string, error) {
+	var err error
+	if v.message != nil {
+		var val ref.Val
+		if val, err = v.message.eval(ctx, activation); err == nil {
+			if message, ok := val.Value().(string); ok && message != "" {
+				return message, nil
+			}
+		}
+	}
+	switch {
+	case v.Message != "":
+		return v.Message, err
+	case fallback != "":
+		return fallback, err
+	default:
+		return fmt.Sprintf("failed expression: %s", v.Expression), err
+	}
+}
+
+// activation returns the variables expressions are evaluated with for
+// request. As in a ValidatingAdmissionPolicy, object is null for DELETE and
+// oldObject is null for CREATE and CONNECT.
+func (progs *programs) activation(request admissionctl.Request) (map[string]interface{}, error) {
+	object, err := decodeObject(request.Object)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't decode object: %w", err)
+	}
+	oldObject, err := decodeObject(request.OldObject)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't decode oldObject: %w", err)
+	}
+	// The request variable is the AdmissionRequest without the objects, which
+	// have variables of their own
+	r := request.AdmissionRequest
+	r.Object, r.OldObject = runtime.RawExtension{}, runtime.RawExtension{}
+	b, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	decoded, err := decodeJSON(b)
+	if err != nil {
+		return nil, err
+	}
+	req := decoded.(map[string]interface{})
+	delete(req, "object")
+	delete(req, "oldObject")
+
+	return map[string]interface{}{
+		objectVar:    object,
+		oldObjectVar: oldObject,
+		requestVar:   req,
+		paramsVar:    progs.params,
+		variablesVar: map[string]interface{}{},
+	}, nil
+}
+
+// evalVariables adds the policy's variables to activation, in order, so
+// each can refer to those before it
+func (progs *programs) evalVariables(ctx context.Context, activation map[string]interface{}) error {
+	variables := map[string]interface{}{}
+	for _, v := range progs.variables {
+		activation[variablesVar] = variables
+		val, err := v.expression.eval(ctx, activation)
+		if err != nil {
+			return fmt.Errorf("variable %s: %w", v.name, err)
+		}
+		variables[v.name] = val
+	}
+	activation[variablesVar] = variables
+	return nil
+}
+
+func decodeObject(object runtime.RawExtension) (interface{}, error) {
+	if len(object.Raw) == 0 {
+		return nil, nil
+	}
+	return decodeJSON(object.Raw)
+}
+
+// decodeJSON decodes b with whole numbers as int64 rather than float64, as
+// the API server does, so that expressions such as object.spec.replicas + 1
+// type-check the same way in a ValidatingAdmissionPolicy
+func decodeJSON(b []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var decoded interface{}
+	if err := dec.Decode(&decoded); err != nil {
+		return nil, err
+	}
+	return convertNumbers(decoded), nil
+}
+
+func convertNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			v[key] = convertNumbers(child)
+		}
+	case []interface{}:
+		for i, child := range v {
+			v[i] = convertNumbers(child)
+		}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	}
+	return value
+}
@@ -0,0 +1,213 @@
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// Decision is what the API server would do with a request for a
+// ValidatingAdmissionPolicy
+type Decision struct {
+	// Matched is false if the request is outside the policy's match
+	// constraints or fails one of its match conditions
+	Matched bool
+	// Allowed is false if the request is denied
+	Allowed bool
+	// Message is the message of the failed validation, which is a warning or
+	// an audit annotation rather than a denial unless the binding has the
+	// Deny action
+	Message string
+	// Err is set if an expression could not be evaluated. The request is then
+	// treated according to the policy's failure policy.
+	Err error
+}
+
+// AdmissionPolicyEvaluator evaluates a ValidatingAdmissionPolicy in-process,
+// the way the API server would, so that its decisions can be checked without
+// a cluster
+type AdmissionPolicyEvaluator struct {
+	spec    admissionregv1.ValidatingAdmissionPolicySpec
+	actions []admissionregv1.ValidationAction
+	progs   *programs
+}
+
+// NewAdmissionPolicyEvaluator compiles the expressions of spec, which is bound
+// with actions. Params and namespace selectors need objects from a cluster, so
+// policies using them are rejected.
+func NewAdmissionPolicyEvaluator(spec admissionregv1.ValidatingAdmissionPolicySpec, actions []admissionregv1.ValidationAction) (*AdmissionPolicyEvaluator, error) {
+	if spec.ParamKind != nil {
+		return nil, fmt.Errorf("paramKind is not supported")
+	}
+	if mc := spec.MatchConstraints; mc != nil && mc.NamespaceSelector != nil && !isEmptySelector(mc.NamespaceSelector) {
+		return nil, fmt.Errorf("namespaceSelector is not supported")
+	}
+	progs, err := compileExpressions(spec.MatchConditions, spec.Variables, spec.Validations)
+	if err != nil {
+		return nil, err
+	}
+	return &AdmissionPolicyEvaluator{spec: spec, actions: actions, progs: progs}, nil
+}
+
+// Evaluate returns the decision on request
+func (e *AdmissionPolicyEvaluator) Evaluate(ctx context.Context, request admissionctl.Request) Decision {
+	if mc := e.spec.MatchConstraints; mc != nil {
+		matched, err := matchesResources(*mc, request)
+		if err != nil {
+			return e.errored(err)
+		}
+		if !matched {
+			return Decision{Allowed: true}
+		}
+	}
+	activation, err := e.progs.activation(request)
+	if err != nil {
+		return e.errored(err)
+	}
+	for _, condition := range e.progs.matchConditions {
+		matched, err := condition.evalBool(ctx, activation)
+		if err != nil {
+			return e.errored(err)
+		}
+		if !matched {
+			return Decision{Allowed: true}
+		}
+	}
+	if err := e.progs.evalVariables(ctx, activation); err != nil {
+		return e.errored(err)
+	}
+	for _, v := range e.progs.validations {
+		valid, err := v.expression.evalBool(ctx, activation)
+		if err != nil {
+			return e.errored(err)
+		}
+		if !valid {
+			message, err := v.failureMessage(ctx, activation, "")
+			return Decision{Matched: true, Allowed: !e.denies(), Message: message, Err: err}
+		}
+	}
+	return Decision{Matched: true, Allowed: true}
+}
+
+// errored is the decision on a request whose expressions could not be
+// evaluated. With the Fail failure policy the error counts as a failed
+// validation, so it is subject to the binding's actions.
+func (e *AdmissionPolicyEvaluator) errored(err error) Decision {
+	if e.spec.FailurePolicy != nil && *e.spec.FailurePolicy == admissionregv1.Ignore {
+		return Decision{Matched: true, Allowed: true, Err: err}
+	}
+	return Decision{Matched: true, Allowed: !e.denies(), Message: err.Error(), Err: err}
+}
+
+func (e *AdmissionPolicyEvaluator) denies() bool {
+	return slices.Contains(e.actions, admissionregv1.Deny)
+}
+
+// Matches returns true if the API server would send request to a webhook
+// with rules and objectSelector
+func Matches(rules []admissionregv1.RuleWithOperations, objectSelector *metav1.LabelSelector, request admissionctl.Request) (bool, error) {
+	named := make([]admissionregv1.NamedRuleWithOperations, 0, len(rules))
+	for _, rule := range rules {
+		named = append(named, admissionregv1.NamedRuleWithOperations{RuleWithOperations: rule})
+	}
+	return matchesResources(admissionregv1.MatchResources{ResourceRules: named, ObjectSelector: objectSelector}, request)
+}
+
+// matchesResources returns true if request is within the match constraints
+func matchesResources(mc admissionregv1.MatchResources, request admissionctl.Request) (bool, error) {
+	matchesAny := func(rules []admissionregv1.NamedRuleWithOperations) bool {
+		return slices.ContainsFunc(rules, func(rule admissionregv1.NamedRuleWithOperations) bool {
+			return matchesRule(rule, request)
+		})
+	}
+	if !matchesAny(mc.ResourceRules) || matchesAny(mc.ExcludeResourceRules) {
+		return false, nil
+	}
+	if mc.ObjectSelector == nil || isEmptySelector(mc.ObjectSelector) {
+		return true, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(mc.ObjectSelector)
+	if err != nil {
+		return false, fmt.Errorf("invalid objectSelector: %w", err)
+	}
+	// As in the API server, either the object or the old object must match
+	for _, object := range []runtime.RawExtension{request.Object, request.OldObject} {
+		if len(object.Raw) == 0 {
+			continue
+		}
+		objectLabels, err := labelsOf(object)
+		if err != nil {
+			return false, err
+		}
+		if selector.Matches(objectLabels) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// matchesRule implements the API server's matching of a request's operation
+// and resource to a rule
+func matchesRule(rule admissionregv1.NamedRuleWithOperations, request admissionctl.Request) bool {
+	matchesValue := func(values []string, value string) bool {
+		return slices.Contains(values, "*") || slices.Contains(values, value)
+	}
+	operations := make([]string, 0, len(rule.Operations))
+	for _, operation := range rule.Operations {
+		operations = append(operations, string(operation))
+	}
+	if !matchesValue(operations, string(request.Operation)) ||
+		!matchesValue(rule.APIGroups, request.Resource.Group) ||
+		!matchesValue(rule.APIVersions, request.Resource.Version) ||
+		!matchesResource(rule.Resources, request.Resource.Resource, request.SubResource) {
+		return false
+	}
+	if len(rule.ResourceNames) > 0 && !slices.Contains(rule.ResourceNames, request.Name) {
+		return false
+	}
+	// Namespaces are cluster scoped, although requests for them have the
+	// namespace's name as their namespace
+	isNamespace := request.Resource.Group == "" && request.Resource.Resource == "namespaces"
+	switch {
+	case rule.Scope == nil || *rule.Scope == admissionregv1.AllScopes:
+		return true
+	case *rule.Scope == admissionregv1.ClusterScope:
+		return isNamespace || request.Namespace == ""
+	default:
+		return !isNamespace && request.Namespace != ""
+	}
+}
+
+func matchesResource(resources []string, resource, subResource string) bool {
+	for _, r := range resources {
+		name, sub, _ := strings.Cut(r, "/")
+		if (name == "*" || name == resource) && (sub == "*" || sub == subResource) {
+			return true
+		}
+	}
+	return false
+}
+
+func labelsOf(object runtime.RawExtension) (labels.Set, error) {
+	var meta struct {
+		Metadata struct {
+			Labels map[string]string `json:"labels"`
+		} `json:"metadata"`
+	}
+	if err := json.Unmarshal(object.Raw, &meta); err != nil {
+		return nil, fmt.Errorf("couldn't decode object labels: %w", err)
+	}
+	return labels.Set(meta.Metadata.Labels), nil
+}
+
+func isEmptySelector(selector *metav1.LabelSelector) bool {
+	return len(selector.MatchLabels) == 0 && len(selector.MatchExpressions) == 0
+}
@@ -0,0 +1,257 @@
+package policy
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func resourceRequest(operation admissionv1.Operation, namespace, resource, subResource string) admissionv1.AdmissionRequest {
+	return admissionv1.AdmissionRequest{
+		Operation:   operation,
+		Namespace:   namespace,
+		Resource:    metav1.GroupVersionResource{Group: "example.com", Version: "v1", Resource: resource},
+		SubResource: subResource,
+	}
+}
+
+func TestMatches(t *testing.T) {
+	namespaced := admissionregv1.NamespacedScope
+	cluster := admissionregv1.ClusterScope
+	rule := func(resources []string, scope *admissionregv1.ScopeType) []admissionregv1.RuleWithOperations {
+		return []admissionregv1.RuleWithOperations{{
+			Operations: []admissionregv1.OperationType{admissionregv1.Create, admissionregv1.Update},
+			Rule: admissionregv1.Rule{
+				APIGroups:   []string{"example.com"},
+				APIVersions: []string{"*"},
+				Resources:   resources,
+				Scope:       scope,
+			},
+		}}
+	}
+	labelled := runtime.RawExtension{Raw: []byte(`{"metadata":{"labels":{"managed":"true"}}}`)}
+	unlabelled := runtime.RawExtension{Raw: []byte(`{"metadata":{}}`)}
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{"managed": "true"}}
+
+	tests := []struct {
+		name           string
+		rules          []admissionregv1.RuleWithOperations
+		objectSelector *metav1.LabelSelector
+		request        admissionv1.AdmissionRequest
+		object         runtime.RawExtension
+		oldObject      runtime.RawExtension
+		shouldMatch    bool
+	}{
+		{
+			name:        "resource",
+			rules:       rule([]string{"widgets"}, nil),
+			request:     resourceRequest(admissionv1.Create, "ns", "widgets", ""),
+			shouldMatch: true,
+		},
+		{
+			name:    "other operation",
+			rules:   rule([]string{"widgets"}, nil),
+			request: resourceRequest(admissionv1.Delete, "ns", "widgets", ""),
+		},
+		{
+			name:    "other resource",
+			rules:   rule([]string{"widgets"}, nil),
+			request: resourceRequest(admissionv1.Create, "ns", "gadgets", ""),
+		},
+		{
+			name:    "subresource not in rule",
+			rules:   rule([]string{"widgets"}, nil),
+			request: resourceRequest(admissionv1.Update, "ns", "widgets", "status"),
+		},
+		{
+			name:        "subresource",
+			rules:       rule([]string{"widgets/status"}, nil),
+			request:     resourceRequest(admissionv1.Update, "ns", "widgets", "status"),
+			shouldMatch: true,
+		},
+		{
+			name:    "wildcard excludes subresources",
+			rules:   rule([]string{"*"}, nil),
+			request: resourceRequest(admissionv1.Update, "ns", "widgets", "status"),
+		},
+		{
+			name:        "wildcard subresources",
+			rules:       rule([]string{"*/*"}, nil),
+			request:     resourceRequest(admissionv1.Update, "ns", "widgets", "status"),
+			shouldMatch: true,
+		},
+		{
+			name:    "namespaced scope",
+			rules:   rule([]string{"widgets"}, &namespaced),
+			request: resourceRequest(admissionv1.Create, "", "widgets", ""),
+		},
+		{
+			name:        "cluster scope",
+			rules:       rule([]string{"widgets"}, &cluster),
+			request:     resourceRequest(admissionv1.Create, "", "widgets", ""),
+			shouldMatch: true,
+		},
+		{
+			name:           "labelled object",
+			rules:          rule([]string{"widgets"}, nil),
+			objectSelector: selector,
+			request:        resourceRequest(admissionv1.Update, "ns", "widgets", ""),
+			object:         unlabelled,
+			oldObject:      labelled,
+			shouldMatch:    true,
+		},
+		{
+			name:           "unlabelled object",
+			rules:          rule([]string{"widgets"}, nil),
+			objectSelector: selector,
+			request:        resourceRequest(admissionv1.Update, "ns", "widgets", ""),
+			object:         unlabelled,
+			oldObject:      unlabelled,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := request("someone", nil, test.request.Operation, "example.com", "Widget")
+			req.Namespace = test.request.Namespace
+			req.Resource = test.request.Resource
+			req.SubResource = test.request.SubResource
+			req.Object, req.OldObject = test.object, test.oldObject
+			matched, err := Matches(test.rules, test.objectSelector, req)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if matched != test.shouldMatch {
+				t.Errorf("Expected match to be %t, got %t", test.shouldMatch, matched)
+			}
+		})
+	}
+}
+
+func TestAdmissionPolicyEvaluator(t *testing.T) {
+	p, err := Parse([]byte(testCELPolicy))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	p.Params = nil
+	// params aren't available to a ValidatingAdmissionPolicy
+	p.Variables[1].Expression = "10"
+	spec, _ := NewWebhook(p).AdmissionPolicy()
+	fail := admissionregv1.Fail
+	spec.FailurePolicy = &fail
+	spec.MatchConstraints = &admissionregv1.MatchResources{
+		ResourceRules: []admissionregv1.NamedRuleWithOperations{{RuleWithOperations: p.Rules[0]}},
+	}
+
+	tests := []struct {
+		name            string
+		actions         []admissionregv1.ValidationAction
+		request         func() admissionv1.AdmissionRequest
+		shouldMatch     bool
+		shouldBeAllowed bool
+		message         string
+	}{
+		{
+			name:            "valid",
+			actions:         []admissionregv1.ValidationAction{admissionregv1.Deny},
+			shouldMatch:     true,
+			shouldBeAllowed: true,
+		},
+		{
+			name:    "invalid",
+			actions: []admissionregv1.ValidationAction{admissionregv1.Deny},
+			request: func() admissionv1.AdmissionRequest {
+				return celRequest("someone", admissionv1.Update, "widget-a", 11, 11).AdmissionRequest
+			},
+			shouldMatch: true,
+			message:     "widget widget-a is bigger than 10",
+		},
+		{
+			name:    "invalid with warn",
+			actions: []admissionregv1.ValidationAction{admissionregv1.Warn},
+			request: func() admissionv1.AdmissionRequest {
+				return celRequest("someone", admissionv1.Update, "widget-a", 11, 11).AdmissionRequest
+			},
+			shouldMatch:     true,
+			shouldBeAllowed: true,
+			message:         "widget widget-a is bigger than 10",
+		},
+		{
+			name:    "exempt",
+			actions: []admissionregv1.ValidationAction{admissionregv1.Deny},
+			request: func() admissionv1.AdmissionRequest {
+				return celRequest("admin", admissionv1.Update, "widget-a", 11, 11).AdmissionRequest
+			},
+			shouldBeAllowed: true,
+		},
+		{
+			name:    "outside the match constraints",
+			actions: []admissionregv1.ValidationAction{admissionregv1.Deny},
+			request: func() admissionv1.AdmissionRequest {
+				req := celRequest("someone", admissionv1.Update, "widget-a", 11, 11).AdmissionRequest
+				req.Resource.Resource = "gadgets"
+				return req
+			},
+			shouldBeAllowed: true,
+		},
+		{
+			name:    "errored with failurePolicy Fail",
+			actions: []admissionregv1.ValidationAction{admissionregv1.Deny},
+			request: func() admissionv1.AdmissionRequest {
+				req := celRequest("someone", admissionv1.Update, "widget-a", 5, 5).AdmissionRequest
+				req.Object = runtime.RawExtension{Raw: []byte(`{"spec":{}}`)}
+				return req
+			},
+			shouldMatch: true,
+			message:     "no such key: size",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			evaluator, err := NewAdmissionPolicyEvaluator(spec, test.actions)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			req := celRequest("someone", admissionv1.Update, "widget-a", 5, 5)
+			if test.request != nil {
+				req.AdmissionRequest = test.request()
+			}
+			req.Resource = metav1.GroupVersionResource{Group: "example.com", Version: "v1", Resource: req.Resource.Resource}
+			if req.Resource.Resource == "" {
+				req.Resource.Resource = "widgets"
+			}
+			decision := evaluator.Evaluate(context.Background(), req)
+			if decision.Matched != test.shouldMatch {
+				t.Errorf("Expected matched to be %t, got %t", test.shouldMatch, decision.Matched)
+			}
+			if decision.Allowed != test.shouldBeAllowed {
+				t.Errorf("Expected allowed to be %t, got %t: %s", test.shouldBeAllowed, decision.Allowed, decision.Message)
+			}
+			if !strings.Contains(decision.Message, test.message) {
+				t.Errorf("Expected message containing %q, got %q", test.message, decision.Message)
+			}
+		})
+	}
+}
+
+func TestAdmissionPolicyEvaluatorUnsupported(t *testing.T) {
+	specs := map[string]admissionregv1.ValidatingAdmissionPolicySpec{
+		"paramKind": {
+			ParamKind: &admissionregv1.ParamKind{APIVersion: "v1", Kind: "ConfigMap"},
+		},
+		"namespaceSelector": {
+			MatchConstraints: &admissionregv1.MatchResources{
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"managed": "true"}},
+			},
+		},
+	}
+	for errMsg, spec := range specs {
+		if _, err := NewAdmissionPolicyEvaluator(spec, nil); err == nil || !strings.Contains(err.Error(), errMsg) {
+			t.Errorf("Expected error containing %q, got %v", errMsg, err)
+		}
+	}
+}
@@ -0,0 +1,480 @@
+// Package policy builds webhooks from declarative YAML policies, for
+// guardrails which deny some operations on a kind unless the request is made
+// by an exempt principal or passes CEL validations. The policies in the
+// policies directory are embedded into the binary and registered alongside
+// the webhooks written in Go, so they are served, deployed and documented in
+// the same way.
+package policy
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"regexp"
+	"slices"
+
+	"github.com/ghodss/yaml"
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/allowlist"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/explain"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/helpers"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+const (
+	defaultTimeoutSeconds int32 = 2
+	maxTimeoutSeconds     int32 = 30
+)
+
+var (
+	//go:embed policies/*.yaml
+	policyFiles embed.FS
+
+	log = logf.Log.WithName("policy")
+
+	// validName is what Kubernetes allows in a webhook name, which the policy
+	// name is also used as the URI of
+	validName = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+)
+
+// Principals are the users and groups a policy does not apply to. Patterns
+// are regular expressions. RequiredExtra and ExcludedExtra narrow the users
+// and groups by their UserInfo.Extra, e.g. to leave out scoped OAuth tokens.
+type Principals struct {
+	Users         []string            `json:"users,omitempty"`
+	UserPrefixes  []string            `json:"userPrefixes,omitempty"`
+	UserPatterns  []string            `json:"userPatterns,omitempty"`
+	Groups        []string            `json:"groups,omitempty"`
+	GroupPatterns []string            `json:"groupPatterns,omitempty"`
+	RequiredExtra map[string][]string `json:"requiredExtra,omitempty"`
+	ExcludedExtra map[string][]string `json:"excludedExtra,omitempty"`
+}
+
+// Targets are the kinds of cluster a policy is deployed to
+type Targets struct {
+	Classic    bool `json:"classic,omitempty"`
+	Hypershift bool `json:"hypershift,omitempty"`
+}
+
+// Policy denies every request matched by its rules, unless it is made by one
+// of the Exempt principals or a principal allowlisted for the policy. A policy
+// with Validations only denies the requests which fail one of them.
+type Policy struct {
+	// Name is the webhook's name and URI
+	Name string `json:"name"`
+	// Doc documents the policy for customers
+	Doc string `json:"doc"`
+	// Rules select the requests which are denied
+	Rules []admissionregv1.RuleWithOperations `json:"rules"`
+	// Kinds are the kinds of object a well-formed request can be for
+	Kinds []metav1.GroupKind `json:"kinds"`
+	// ObjectSelector narrows Rules to the objects with matching labels
+	ObjectSelector *metav1.LabelSelector `json:"objectSelector,omitempty"`
+	// FailurePolicy defaults to Ignore
+	FailurePolicy admissionregv1.FailurePolicyType `json:"failurePolicy,omitempty"`
+	// TimeoutSeconds defaults to 2
+	TimeoutSeconds int32 `json:"timeoutSeconds,omitempty"`
+	// Exempt principals may make any request
+	Exempt Principals `json:"exempt,omitempty"`
+	// Code identifies the policy's denials in the message catalog. The
+	// catalog's message for the code is the policy's message, so a policy
+	// with a code can't have a message of its own.
+	Code helpers.Code `json:"code,omitempty"`
+	// Message is returned when a request is denied. It is required unless
+	// the policy has a code or every validation has a message of its own.
+	Message string `json:"message,omitempty"`
+	// MatchConditions, Variables and Validations are CEL expressions with
+	// the variables and semantics of a ValidatingAdmissionPolicy. A request
+	// which does not meet every match condition is allowed.
+	MatchConditions []admissionregv1.MatchCondition `json:"matchConditions,omitempty"`
+	Variables       []admissionregv1.Variable       `json:"variables,omitempty"`
+	Validations     []admissionregv1.Validation     `json:"validations,omitempty"`
+	// Params are the value of the params variable, which a
+	// ValidatingAdmissionPolicy would read from its parameter resource
+	Params *runtime.RawExtension `json:"params,omitempty"`
+	// ValidationActions are how the policy's ValidatingAdmissionPolicyBinding
+	// enforces it. They default to Deny. The webhook is enforced according
+	// to its enforcement mode instead.
+	ValidationActions []admissionregv1.ValidationAction `json:"validationActions,omitempty"`
+	// SyncSetMatchExpressions are added to the default SyncSet label
+	// selector, to deploy the policy to fewer clusters
+	SyncSetMatchExpressions []metav1.LabelSelectorRequirement `json:"syncSetMatchExpressions,omitempty"`
+	Targets                 Targets                           `json:"targets"`
+
+	programs *programs
+}
+
+// messageArgs are the Args a policy's code is rendered with, so that the
+// catalog's message lists the principals the policy really exempts
+func (p *Policy) messageArgs() helpers.Args {
+	return helpers.Args{
+		"ExemptUsers":        p.Exempt.Users,
+		"ExemptUserPatterns": p.Exempt.UserPatterns,
+		"ExemptGroups":       p.Exempt.Groups,
+	}
+}
+
+// Parse decodes and validates a policy. Unknown fields are rejected so that a
+// misspelt field cannot silently widen what a policy allows.
+func Parse(b []byte) (*Policy, error) {
+	p := &Policy{}
+	disallowUnknownFields := func(d *json.Decoder) *json.Decoder {
+		d.DisallowUnknownFields()
+		return d
+	}
+	if err := yaml.Unmarshal(b, p, disallowUnknownFields); err != nil {
+		return nil, err
+	}
+	if p.FailurePolicy == "" {
+		p.FailurePolicy = admissionregv1.Ignore
+	}
+	if p.TimeoutSeconds == 0 {
+		p.TimeoutSeconds = defaultTimeoutSeconds
+	}
+	if len(p.ValidationActions) == 0 {
+		p.ValidationActions = []admissionregv1.ValidationAction{admissionregv1.Deny}
+	}
+	if p.Code != "" {
+		if p.Message != "" {
+			return nil, fmt.Errorf("invalid policy %s: message comes from code %s", p.Name, p.Code)
+		}
+		if _, ok := helpers.Lookup(p.Code); !ok {
+			return nil, fmt.Errorf("invalid policy %s: code %s is not in the message catalog", p.Name, p.Code)
+		}
+		message, err := p.Code.Render(p.messageArgs())
+		if err != nil {
+			return nil, fmt.Errorf("invalid policy %s: code %s can't be rendered from its exempt principals: %w", p.Name, p.Code, err)
+		}
+		p.Message = message
+	}
+	if err := p.validate(); err != nil {
+		return nil, fmt.Errorf("invalid policy %s: %w", p.Name, err)
+	}
+	programs, err := p.compilePrograms()
+	if err != nil {
+		return nil, fmt.Errorf("invalid policy %s: %w", p.Name, err)
+	}
+	p.programs = programs
+	return p, nil
+}
+
+func (p *Policy) validate() error {
+	switch {
+	case !validName.MatchString(p.Name):
+		return fmt.Errorf("name %q must be a lowercase DNS label", p.Name)
+	case p.Doc == "":
+		return fmt.Errorf("doc is required")
+	case p.Message == "" && !p.validationsHaveMessages():
+		return fmt.Errorf("message is required")
+	case len(p.Rules) == 0:
+		return fmt.Errorf("at least one rule is required")
+	case len(p.Kinds) == 0:
+		return fmt.Errorf("at least one kind is required")
+	case p.FailurePolicy != admissionregv1.Ignore && p.FailurePolicy != admissionregv1.Fail:
+		return fmt.Errorf("failurePolicy must be %s or %s", admissionregv1.Ignore, admissionregv1.Fail)
+	case p.TimeoutSeconds < 1 || p.TimeoutSeconds > maxTimeoutSeconds:
+		return fmt.Errorf("timeoutSeconds must be between 1 and %d", maxTimeoutSeconds)
+	case !p.Targets.Classic && !p.Targets.Hypershift:
+		return fmt.Errorf("at least one of targets.classic and targets.hypershift must be true")
+	}
+	for _, rule := range p.Rules {
+		if len(rule.Operations) == 0 {
+			return fmt.Errorf("every rule needs at least one operation")
+		}
+	}
+	for _, action := range p.ValidationActions {
+		switch action {
+		case admissionregv1.Deny, admissionregv1.Warn, admissionregv1.Audit:
+		default:
+			return fmt.Errorf("unknown validation action %q", action)
+		}
+	}
+	if slices.Contains(p.ValidationActions, admissionregv1.Deny) && slices.Contains(p.ValidationActions, admissionregv1.Warn) {
+		return fmt.Errorf("validationActions cannot contain both %s and %s", admissionregv1.Deny, admissionregv1.Warn)
+	}
+	if p.ObjectSelector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(p.ObjectSelector); err != nil {
+			return fmt.Errorf("invalid objectSelector: %w", err)
+		}
+	}
+	_, err := p.Exempt.compile()
+	return err
+}
+
+// validationsHaveMessages returns true if every validation has a message, so
+// the policy does not need one
+func (p *Policy) validationsHaveMessages() bool {
+	if len(p.Validations) == 0 {
+		return false
+	}
+	for _, v := range p.Validations {
+		if v.Message == "" && v.MessageExpression == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// hasUsers returns true if the principals name any users or groups
+func (e Principals) hasUsers() bool {
+	return len(e.Users) > 0 || len(e.UserPrefixes) > 0 || len(e.UserPatterns) > 0 || len(e.Groups) > 0 || len(e.GroupPatterns) > 0
+}
+
+// compile converts the principals into the form the webhooks match with
+func (e Principals) compile() (utils.Principals, error) {
+	if !e.hasUsers() && (len(e.RequiredExtra) > 0 || len(e.ExcludedExtra) > 0) {
+		return utils.Principals{}, fmt.Errorf("requiredExtra and excludedExtra need users or groups to narrow")
+	}
+	principals := utils.Principals{
+		Users:         e.Users,
+		UserPrefixes:  e.UserPrefixes,
+		Groups:        e.Groups,
+		RequiredExtra: e.RequiredExtra,
+		ExcludedExtra: e.ExcludedExtra,
+	}
+	for _, pattern := range e.UserPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return utils.Principals{}, fmt.Errorf("invalid user pattern %q: %w", pattern, err)
+		}
+		principals.UserPatterns = append(principals.UserPatterns, re)
+	}
+	for _, pattern := range e.GroupPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return utils.Principals{}, fmt.Errorf("invalid group pattern %q: %w", pattern, err)
+		}
+		principals.GroupPatterns = append(principals.GroupPatterns, re)
+	}
+	return principals, nil
+}
+
+// Load parses every policy in fsys matching *.yaml, in name order
+func Load(fsys fs.FS) ([]*Policy, error) {
+	names, err := fs.Glob(fsys, "*.yaml")
+	if err != nil {
+		return nil, err
+	}
+	policies := make([]*Policy, 0, len(names))
+	seen := map[string]string{}
+	for _, name := range names {
+		b, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		p, err := Parse(b)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if other, ok := seen[p.Name]; ok {
+			return nil, fmt.Errorf("%s: policy %s is already defined by %s", name, p.Name, other)
+		}
+		seen[p.Name] = name
+		policies = append(policies, p)
+	}
+	return policies, nil
+}
+
+// Embedded returns the policies built into the binary. They are checked by
+// the package's tests, so an error here means the binary is broken and it
+// panics rather than serving without them.
+func Embedded() []*Policy {
+	fsys, err := fs.Sub(policyFiles, "policies")
+	if err != nil {
+		panic(err)
+	}
+	policies, err := Load(fsys)
+	if err != nil {
+		panic(fmt.Sprintf("couldn't load embedded policies: %s", err))
+	}
+	return policies
+}
+
+// Webhook enforces a Policy
+type Webhook struct {
+	policy *Policy
+	exempt utils.Principals
+}
+
+// NewWebhook returns the Webhook enforcing p, which must have been returned
+// by Parse
+func NewWebhook(p *Policy) *Webhook {
+	// Parse has already checked the patterns compile
+	exempt, _ := p.Exempt.compile()
+	return &Webhook{policy: p, exempt: exempt}
+}
+
+// Authorized implements Webhook interface
+func (w *Webhook) Authorized(request admissionctl.Request) admissionctl.Response {
+	return w.AuthorizedWithContext(context.Background(), request)
+}
+
+// AuthorizedWithContext implements ContextWebhook interface, so that CEL
+// evaluation stops when the request's deadline passes
+func (w *Webhook) AuthorizedWithContext(ctx context.Context, request admissionctl.Request) admissionctl.Response {
+	trace := explain.FromContext(ctx)
+	if match, ok := w.exempt.ExplainRequest(ctx, "exempt", request); ok {
+		return utils.WebhookResponse(request, true, fmt.Sprintf("Exempt from policy %s: %s", w.policy.Name, match))
+	}
+	if trace.Record(allowlist.Allowed(w.policy.Name, request.AdmissionRequest.UserInfo), "user in allowlist") {
+		return utils.WebhookResponse(request, true, fmt.Sprintf("Allowlisted users are exempt from policy %s", w.policy.Name))
+	}
+	if !trace.Recordf(w.restricts(admissionregv1.OperationType(request.Operation)), "policy restricts %s", request.Operation) {
+		return utils.WebhookResponse(request, true, fmt.Sprintf("Policy %s does not restrict %s operations", w.policy.Name, request.Operation))
+	}
+	if len(w.policy.Validations) > 0 {
+		return w.validate(ctx, request)
+	}
+	return w.deny(request, w.policy.Message)
+}
+
+func (w *Webhook) deny(request admissionctl.Request, message string) admissionctl.Response {
+	log.Info("Denied by policy", "policy", w.policy.Name,
+		"operation", request.Operation,
+		"user", request.AdmissionRequest.UserInfo.Username,
+		"groups", request.AdmissionRequest.UserInfo.Groups)
+	if w.policy.Code != "" {
+		return utils.WebhookResponse(request, false, "", utils.WithCodeText(w.policy.Code, message))
+	}
+	return utils.WebhookResponse(request, false, message)
+}
+
+// validate evaluates the policy's CEL expressions against request
+func (w *Webhook) validate(ctx context.Context, request admissionctl.Request) admissionctl.Response {
+	trace := explain.FromContext(ctx)
+	progs := w.policy.programs
+	activation, err := progs.activation(request)
+	if err != nil {
+		return w.errored(request, err)
+	}
+	for _, condition := range progs.matchConditions {
+		matched, err := condition.evalBool(ctx, activation)
+		if err != nil {
+			return w.errored(request, err)
+		}
+		if !trace.Recordf(matched, "match condition %q", condition.source) {
+			return utils.WebhookResponse(request, true, fmt.Sprintf("Request does not meet the match conditions of policy %s", w.policy.Name))
+		}
+	}
+	if err := progs.evalVariables(ctx, activation); err != nil {
+		return w.errored(request, err)
+	}
+	for _, v := range progs.validations {
+		valid, err := v.expression.evalBool(ctx, activation)
+		if err != nil {
+			return w.errored(request, err)
+		}
+		if trace.Recordf(valid, "validation %q", v.expression.source) {
+			continue
+		}
+		ret := w.deny(request, w.message(ctx, v, activation))
+		// A code is the reason for every denial by its policy
+		if v.Reason != nil && w.policy.Code == "" {
+			ret.Result.Reason = *v.Reason
+		}
+		return ret
+	}
+	return utils.WebhookResponse(request, true, fmt.Sprintf("Request passed the validations of policy %s", w.policy.Name))
+}
+
+// message returns the message for a failed validation, falling back to the
+// policy's message
+func (w *Webhook) message(ctx context.Context, v validation, activation map[string]interface{}) string {
+	message, err := v.failureMessage(ctx, activation, w.policy.Message)
+	if err != nil {
+		log.Error(err, "Failed to evaluate message expression", "policy", w.policy.Name)
+	}
+	return message
+}
+
+// errored handles a request whose expressions could not be evaluated the way
+// the API server would for a ValidatingAdmissionPolicy with the same failure
+// policy: Fail denies the request and Ignore allows it
+func (w *Webhook) errored(request admissionctl.Request, err error) admissionctl.Response {
+	log.Error(err, "Failed to evaluate policy", "policy", w.policy.Name, "uid", request.UID)
+	if w.policy.FailurePolicy == admissionregv1.Ignore {
+		return utils.WebhookResponse(request, true, fmt.Sprintf("Ignored an error evaluating policy %s: %s", w.policy.Name, err))
+	}
+	ret := admissionctl.Errored(http.StatusInternalServerError, fmt.Errorf("policy %s: %w", w.policy.Name, err))
+	ret.UID = request.AdmissionRequest.UID
+	return ret
+}
+
+// restricts returns true if any of the policy's rules denies operation. The
+// API server only sends those operations, but a request replayed or routed
+// here by mistake must not be denied.
+func (w *Webhook) restricts(operation admissionregv1.OperationType) bool {
+	for _, rule := range w.policy.Rules {
+		if slices.Contains(rule.Operations, operation) || slices.Contains(rule.Operations, admissionregv1.OperationAll) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetURI implements Webhook interface
+func (w *Webhook) GetURI() string { return path.Join("/", w.policy.Name) }
+
+// Validate checks the request is for one of the policy's kinds
+func (w *Webhook) Validate(request admissionctl.Request) bool {
+	if request.AdmissionRequest.UserInfo.Username == "" {
+		return false
+	}
+	kind := metav1.GroupKind{Group: request.Kind.Group, Kind: request.Kind.Kind}
+	return slices.Contains(w.policy.Kinds, kind)
+}
+
+// SelfTestKind implements SelfTestWebhook interface
+func (w *Webhook) SelfTestKind() metav1.GroupKind { return w.policy.Kinds[0] }
+
+// Name implements Webhook interface
+func (w *Webhook) Name() string { return w.policy.Name }
+
+// FailurePolicy implements Webhook interface
+func (w *Webhook) FailurePolicy() admissionregv1.FailurePolicyType { return w.policy.FailurePolicy }
+
+// MatchPolicy implements Webhook interface
+func (w *Webhook) MatchPolicy() admissionregv1.MatchPolicyType { return admissionregv1.Equivalent }
+
+// Rules implements Webhook interface
+func (w *Webhook) Rules() []admissionregv1.RuleWithOperations { return w.policy.Rules }
+
+// ObjectSelector implements Webhook interface
+func (w *Webhook) ObjectSelector() *metav1.LabelSelector { return w.policy.ObjectSelector }
+
+// SideEffects implements Webhook interface
+func (w *Webhook) SideEffects() admissionregv1.SideEffectClass {
+	return admissionregv1.SideEffectClassNone
+}
+
+// TimeoutSeconds implements Webhook interface
+func (w *Webhook) TimeoutSeconds() int32 { return w.policy.TimeoutSeconds }
+
+// Doc implements Webhook interface
+func (w *Webhook) Doc() string { return w.policy.Doc }
+
+// SyncSetLabelSelector returns the default label selector with the policy's
+// match expressions added
+func (w *Webhook) SyncSetLabelSelector() metav1.LabelSelector {
+	selector := utils.DefaultLabelSelector()
+	selector.MatchExpressions = append(selector.MatchExpressions, w.policy.SyncSetMatchExpressions...)
+	return selector
+}
+
+// ClassicEnabled implements Webhook interface
+func (w *Webhook) ClassicEnabled() bool { return w.policy.Targets.Classic }
+
+// HypershiftEnabled implements Webhook interface
+func (w *Webhook) HypershiftEnabled() bool { return w.policy.Targets.Hypershift }
+
+// AllowlistEnabled implements AllowlistWebhook interface
+func (w *Webhook) AllowlistEnabled() bool { return true }
@@ -0,0 +1,851 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/allowlist"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/explain"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/helpers"
+)
+
+const testPolicy = `
+name: test-validation
+doc: Only admins may delete widgets
+rules:
+- operations: ["DELETE"]
+  apiGroups: ["example.com"]
+  apiVersions: ["*"]
+  resources: ["widgets"]
+  scope: Namespaced
+kinds:
+- group: example.com
+  kind: Widget
+objectSelector:
+  matchLabels:
+    example.com/managed: "true"
+exempt:
+  users: ["admin"]
+  userPatterns: ["^system:serviceaccount:openshift-.*:widget-controller$"]
+  groups: ["admins"]
+message: Only admins may delete widgets
+syncSetMatchExpressions:
+- key: example.com/widgets
+  operator: Exists
+targets:
+  hypershift: true
+`
+
+func embeddedWebhook(t *testing.T, name string) *Webhook {
+	t.Helper()
+	for _, p := range Embedded() {
+		if p.Name == name {
+			return NewWebhook(p)
+		}
+	}
+	t.Fatalf("policy %s is not embedded", name)
+	return nil
+}
+
+func request(username string, groups []string, operation admissionv1.Operation, group, kind string) admissionctl.Request {
+	return admissionctl.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			UID: "test-uid",
+			UserInfo: authenticationv1.UserInfo{
+				Username: username,
+				Groups:   groups,
+			},
+			Operation: operation,
+			Kind: metav1.GroupVersionKind{
+				Group: group,
+				Kind:  kind,
+			},
+		},
+	}
+}
+
+func TestParse(t *testing.T) {
+	p, err := Parse([]byte(testPolicy))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	webhook := NewWebhook(p)
+	if webhook.Name() != "test-validation" || webhook.GetURI() != "/test-validation" {
+		t.Errorf("Unexpected name %s or URI %s", webhook.Name(), webhook.GetURI())
+	}
+	if webhook.FailurePolicy() != admissionregv1.Ignore {
+		t.Errorf("Expected failurePolicy to default to Ignore, got %s", webhook.FailurePolicy())
+	}
+	if webhook.TimeoutSeconds() != 2 {
+		t.Errorf("Expected timeoutSeconds to default to 2, got %d", webhook.TimeoutSeconds())
+	}
+	if webhook.ClassicEnabled() || !webhook.HypershiftEnabled() {
+		t.Errorf("Expected only hypershift to be targeted")
+	}
+	if *webhook.Rules()[0].Scope != admissionregv1.NamespacedScope {
+		t.Errorf("Expected Namespaced scope, got %s", *webhook.Rules()[0].Scope)
+	}
+	if webhook.ObjectSelector().MatchLabels["example.com/managed"] != "true" {
+		t.Errorf("Unexpected object selector %v", webhook.ObjectSelector())
+	}
+	selector := webhook.SyncSetLabelSelector()
+	if selector.MatchLabels["api.openshift.com/managed"] != "true" || len(selector.MatchExpressions) != 1 {
+		t.Errorf("Expected the default selector with one match expression, got %v", selector)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		replace [2]string
+		errMsg  string
+	}{
+		{
+			name:    "unknown field",
+			replace: [2]string{"exempt:", "exmept:"},
+			errMsg:  "unknown field",
+		},
+		{
+			name:    "bad name",
+			replace: [2]string{"name: test-validation", "name: Test_Validation"},
+			errMsg:  "lowercase DNS label",
+		},
+		{
+			name:    "missing message",
+			replace: [2]string{"message: Only admins may delete widgets", ""},
+			errMsg:  "message is required",
+		},
+		{
+			name:    "bad pattern",
+			replace: [2]string{"widget-controller$", "widget-controller$("},
+			errMsg:  "invalid user pattern",
+		},
+		{
+			name:    "no targets",
+			replace: [2]string{"hypershift: true", "hypershift: false"},
+			errMsg:  "targets",
+		},
+		{
+			name:    "extra without users",
+			replace: [2]string{"exempt:\n  users: [\"admin\"]\n  userPatterns: [\"^system:serviceaccount:openshift-.*:widget-controller$\"]\n  groups: [\"admins\"]", "exempt:\n  excludedExtra: {scopes.authorization.openshift.io: []}"},
+			errMsg:  "need users or groups",
+		},
+		{
+			name:    "bad failure policy",
+			replace: [2]string{"targets:", "failurePolicy: Sometimes\ntargets:"},
+			errMsg:  "failurePolicy",
+		},
+		{
+			name:    "timeout too long",
+			replace: [2]string{"targets:", "timeoutSeconds: 31\ntargets:"},
+			errMsg:  "timeoutSeconds",
+		},
+		{
+			name:    "unknown validation action",
+			replace: [2]string{"targets:", "validationActions: [Block]\ntargets:"},
+			errMsg:  "unknown validation action",
+		},
+		{
+			name:    "deny and warn",
+			replace: [2]string{"targets:", "validationActions: [Deny, Warn]\ntargets:"},
+			errMsg:  "both Deny and Warn",
+		},
+		{
+			name:    "unknown code",
+			replace: [2]string{"message: Only admins may delete widgets", "code: MCVW-XX-001"},
+			errMsg:  "not in the message catalog",
+		},
+		{
+			name:    "code rendered from other args",
+			replace: [2]string{"message: Only admins may delete widgets", "code: MCVW-CR-001"},
+			errMsg:  "can't be rendered",
+		},
+		{
+			name:    "code and message",
+			replace: [2]string{"targets:", "code: MCVW-HC-001\ntargets:"},
+			errMsg:  "message comes from code",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Parse([]byte(strings.Replace(testPolicy, test.replace[0], test.replace[1], 1)))
+			if err == nil || !strings.Contains(err.Error(), test.errMsg) {
+				t.Errorf("Expected error containing %q, got %v", test.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestLoadRejectsDuplicates(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.yaml": {Data: []byte(testPolicy)},
+		"b.yaml": {Data: []byte(testPolicy)},
+	}
+	if _, err := Load(fsys); err == nil || !strings.Contains(err.Error(), "already defined by a.yaml") {
+		t.Errorf("Expected a duplicate policy error, got %v", err)
+	}
+}
+
+func TestEmbedded(t *testing.T) {
+	policies := Embedded()
+	if len(policies) == 0 {
+		t.Fatal("Expected at least one embedded policy")
+	}
+	for _, p := range policies {
+		if !strings.HasSuffix(p.Name, "-validation") {
+			t.Errorf("Expected policy %s to be named like the other webhooks", p.Name)
+		}
+	}
+}
+
+func TestAuthorized(t *testing.T) {
+	p, err := Parse([]byte(testPolicy))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	webhook := NewWebhook(p)
+	tests := []struct {
+		name            string
+		username        string
+		groups          []string
+		operation       admissionv1.Operation
+		allowlisted     bool
+		shouldBeAllowed bool
+	}{
+		{
+			name:            "exempt user",
+			username:        "admin",
+			operation:       admissionv1.Delete,
+			shouldBeAllowed: true,
+		},
+		{
+			name:            "exempt user pattern",
+			username:        "system:serviceaccount:openshift-widgets:widget-controller",
+			operation:       admissionv1.Delete,
+			shouldBeAllowed: true,
+		},
+		{
+			name:            "exempt group",
+			username:        "someone",
+			groups:          []string{"admins"},
+			operation:       admissionv1.Delete,
+			shouldBeAllowed: true,
+		},
+		{
+			name:            "allowlisted user",
+			username:        "someone",
+			operation:       admissionv1.Delete,
+			allowlisted:     true,
+			shouldBeAllowed: true,
+		},
+		{
+			name:            "other user",
+			username:        "someone",
+			groups:          []string{"system:authenticated"},
+			operation:       admissionv1.Delete,
+			shouldBeAllowed: false,
+		},
+		{
+			name:            "unrestricted operation",
+			username:        "someone",
+			operation:       admissionv1.Update,
+			shouldBeAllowed: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.allowlisted {
+				config, err := allowlist.Parse([]byte("version: v1\nwebhooks:\n  test-validation:\n    users: [someone]\n"))
+				if err != nil {
+					t.Fatalf("Unexpected error: %s", err)
+				}
+				allowlist.Set(config)
+				defer allowlist.Set(nil)
+			}
+			response := webhook.Authorized(request(test.username, test.groups, test.operation, "example.com", "Widget"))
+			if response.Allowed != test.shouldBeAllowed {
+				t.Errorf("Expected allowed %v, got %v: %s", test.shouldBeAllowed, response.Allowed, response.Result.Message)
+			}
+			if response.UID != "test-uid" {
+				t.Errorf("Expected the request's UID, got %q", response.UID)
+			}
+			if !response.Allowed && response.Result.Message != "Only admins may delete widgets" {
+				t.Errorf("Unexpected denial message %q", response.Result.Message)
+			}
+		})
+	}
+}
+
+func TestHostedClusterAuthorized(t *testing.T) {
+	tests := []struct {
+		name            string
+		username        string
+		operation       admissionv1.Operation
+		shouldBeAllowed bool
+	}{
+		{
+			name:            "Allowed service account can delete hostedcluster",
+			username:        "system:serviceaccount:open-cluster-management-agent:klusterlet-work-sa",
+			operation:       admissionv1.Delete,
+			shouldBeAllowed: true,
+		},
+		{
+			name:            "Random user cannot delete hostedcluster",
+			username:        "unknown-user",
+			operation:       admissionv1.Delete,
+			shouldBeAllowed: false,
+		},
+		{
+			name:            "Non-DELETE operation should be allowed",
+			username:        "unknown-user",
+			operation:       admissionv1.Create,
+			shouldBeAllowed: true,
+		},
+	}
+
+	webhook := embeddedWebhook(t, "hostedcluster-validation")
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := webhook.Authorized(request(test.username, nil, test.operation, "hypershift.openshift.io", "HostedCluster"))
+			if response.Allowed != test.shouldBeAllowed {
+				t.Errorf("Unexpected response for %s. Got %v, expected %v", test.name, response.Allowed, test.shouldBeAllowed)
+			}
+		})
+	}
+}
+
+func TestManifestWorksAuthorized(t *testing.T) {
+	tests := []struct {
+		name            string
+		username        string
+		operation       admissionv1.Operation
+		shouldBeAllowed bool
+	}{
+		{
+			name:            "OCM SA can delete manifestworks",
+			username:        "system:serviceaccount:ocm:ocm",
+			operation:       admissionv1.Delete,
+			shouldBeAllowed: true,
+		},
+		{
+			name:            "ocm-foundation-s SA can delete manifestworks",
+			username:        "system:serviceaccount:multicluster-engine:ocm-foundation-sa",
+			operation:       admissionv1.Delete,
+			shouldBeAllowed: true,
+		},
+		{
+			name:            "Random user cannot delete manifestworks",
+			username:        "unknown-user",
+			operation:       admissionv1.Delete,
+			shouldBeAllowed: false,
+		},
+		{
+			name:            "Non-DELETE operation should be allowed",
+			username:        "unknown-user",
+			operation:       admissionv1.Create,
+			shouldBeAllowed: true,
+		},
+	}
+
+	webhook := embeddedWebhook(t, "manifestworks-validation")
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := webhook.Authorized(request(test.username, nil, test.operation, "work.open-cluster-management.io", "ManifestWork"))
+			if response.Allowed != test.shouldBeAllowed {
+				t.Errorf("Unexpected response for %s. Got %v, expected %v", test.name, response.Allowed, test.shouldBeAllowed)
+			}
+		})
+	}
+}
+
+func TestHostedControlPlaneAuthorized(t *testing.T) {
+	tests := []struct {
+		name            string
+		username        string
+		operation       admissionv1.Operation
+		shouldBeAllowed bool
+	}{
+		{
+			name:            "Allowed service account can delete hostedcontrolplane",
+			username:        "system:serviceaccount:open-cluster-management-agent:klusterlet-work-sa",
+			operation:       admissionv1.Delete,
+			shouldBeAllowed: true,
+		},
+		{
+			name:            "HyperShift operator can delete hostedcontrolplane",
+			username:        "system:serviceaccount:hypershift:operator",
+			operation:       admissionv1.Delete,
+			shouldBeAllowed: true,
+		},
+		{
+			name:            "Control plane's cluster-api can delete hostedcontrolplane",
+			username:        "system:serviceaccount:clusters-example:cluster-api",
+			operation:       admissionv1.Delete,
+			shouldBeAllowed: true,
+		},
+		{
+			name:            "Control plane's pki operator can delete hostedcontrolplane",
+			username:        "system:serviceaccount:clusters-example:control-plane-pki-operator",
+			operation:       admissionv1.Delete,
+			shouldBeAllowed: true,
+		},
+		{
+			name:            "User named like a service account cannot delete hostedcontrolplane",
+			username:        "cluster-api",
+			operation:       admissionv1.Delete,
+			shouldBeAllowed: false,
+		},
+		{
+			name:            "Random user cannot delete hostedcontrolplane",
+			username:        "unknown-user",
+			operation:       admissionv1.Delete,
+			shouldBeAllowed: false,
+		},
+		{
+			name:            "Non-DELETE operation should be allowed",
+			username:        "unknown-user",
+			operation:       admissionv1.Update,
+			shouldBeAllowed: true,
+		},
+	}
+
+	webhook := embeddedWebhook(t, "hostedcontrolplane-validation")
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := webhook.Authorized(request(test.username, nil, test.operation, "hypershift.openshift.io", "HostedControlPlane"))
+			if response.Allowed != test.shouldBeAllowed {
+				t.Errorf("Unexpected response for %s. Got %v, expected %v", test.name, response.Allowed, test.shouldBeAllowed)
+			}
+		})
+	}
+}
+
+func TestHiveOwnershipAuthorized(t *testing.T) {
+	tests := []struct {
+		name            string
+		username        string
+		groups          []string
+		operation       admissionv1.Operation
+		shouldBeAllowed bool
+	}{
+		{
+			name:            "kube:admin can update managed quotas",
+			username:        "kube:admin",
+			groups:          []string{"system:authenticated"},
+			operation:       admissionv1.Update,
+			shouldBeAllowed: true,
+		},
+		{
+			name:            "backplane-cluster-admin can delete managed quotas",
+			username:        "backplane-cluster-admin",
+			groups:          []string{"system:authenticated"},
+			operation:       admissionv1.Delete,
+			shouldBeAllowed: true,
+		},
+		{
+			name:            "SRE can update managed quotas",
+			username:        "sre-foo@redhat.com",
+			groups:          []string{"system:serviceaccounts:openshift-backplane-srep", "system:authenticated"},
+			operation:       admissionv1.Update,
+			shouldBeAllowed: true,
+		},
+		{
+			name:            "dedicated-admin cannot update managed quotas",
+			username:        "bob@foo.com",
+			groups:          []string{"dedicated-admins", "system:authenticated"},
+			operation:       admissionv1.Update,
+			shouldBeAllowed: false,
+		},
+		{
+			name:            "Unprivileged user cannot delete managed quotas",
+			username:        "unpriv-user",
+			groups:          []string{"system:authenticated"},
+			operation:       admissionv1.Delete,
+			shouldBeAllowed: false,
+		},
+		{
+			name:            "Non-UPDATE or DELETE operation should be allowed",
+			username:        "unpriv-user",
+			groups:          []string{"system:authenticated"},
+			operation:       admissionv1.Create,
+			shouldBeAllowed: true,
+		},
+	}
+
+	webhook := embeddedWebhook(t, "hiveownership-validation")
+	if selector := webhook.ObjectSelector(); selector == nil || selector.MatchLabels["hive.openshift.io/managed"] != "true" {
+		t.Fatalf("Expected the webhook to only intercept hive managed resources, got %v", selector)
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := webhook.Authorized(request(test.username, test.groups, test.operation, "quota.openshift.io", "ClusterResourceQuota"))
+			if response.Allowed != test.shouldBeAllowed {
+				t.Errorf("Unexpected response for %s. Got %v, expected %v", test.name, response.Allowed, test.shouldBeAllowed)
+			}
+		})
+	}
+}
+
+func TestEmbeddedWebhooks(t *testing.T) {
+	tests := []struct {
+		name            string
+		uri             string
+		group           string
+		kind            string
+		clusterType     string
+		code            helpers.Code
+		message         string
+		expressionCount int
+	}{
+		{
+			name:            "hostedcluster-validation",
+			uri:             "/hostedcluster-validation",
+			group:           "hypershift.openshift.io",
+			kind:            "HostedCluster",
+			clusterType:     "management-cluster",
+			code:            helpers.CodeHostedClusterDelete,
+			message:         "Only authorized service accounts [system:serviceaccount:open-cluster-management-agent:klusterlet-work-sa] can delete HostedCluster resources.",
+			expressionCount: 1,
+		},
+		{
+			name:            "hiveownership-validation",
+			uri:             "/hiveownership-validation",
+			group:           "quota.openshift.io",
+			kind:            "ClusterResourceQuota",
+			code:            helpers.CodeManagedResource,
+			message:         "Prevented from accessing Red Hat managed resources. This is in an effort to prevent harmful actions that may cause unintended consequences or affect the stability of the cluster.",
+			expressionCount: 0,
+		},
+		{
+			name:            "hostedcontrolplane-validation",
+			uri:             "/hostedcontrolplane-validation",
+			group:           "hypershift.openshift.io",
+			kind:            "HostedControlPlane",
+			clusterType:     "management-cluster",
+			code:            helpers.CodeHostedControlPlaneDelete,
+			message:         "Only authorized service accounts [system:serviceaccount:open-cluster-management-agent:klusterlet-work-sa system:serviceaccount:kube-system:generic-garbage-collector system:serviceaccount:hypershift:operator] and those matching [^system:serviceaccount:[^:]+:(cluster-api|control-plane-pki-operator)$] can delete HostedControlPlane resources.",
+			expressionCount: 1,
+		},
+		{
+			name:            "manifestworks-validation",
+			uri:             "/manifestworks-validation",
+			group:           "work.open-cluster-management.io",
+			kind:            "ManifestWork",
+			clusterType:     "service-cluster",
+			code:            helpers.CodeManifestWorkDelete,
+			message:         "Only authorized service accounts can delete ManifestWork resources. Allowed service accounts: [system:serviceaccount:ocm:ocm system:serviceaccount:kube-system:generic-garbage-collector system:serviceaccount:multicluster-engine:ocm-foundation-sa system:serviceaccount:multicluster-hub:grc-policy-addon-sa system:serviceaccount:multicluster-engine:managedcluster-import-controller-v2 system:serviceaccount:kube-system:namespace-controller]",
+			expressionCount: 2,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			webhook := embeddedWebhook(t, test.name)
+			if webhook.GetURI() != test.uri {
+				t.Errorf("Expected URI to be %s, got %s", test.uri, webhook.GetURI())
+			}
+			if len(webhook.Rules()) == 0 {
+				t.Error("Expected at least one rule")
+			}
+			if webhook.Doc() == "" {
+				t.Error("Expected non-empty documentation string")
+			}
+			if webhook.TimeoutSeconds() != 2 {
+				t.Errorf("Expected timeout to be 2, got %d", webhook.TimeoutSeconds())
+			}
+			if !webhook.ClassicEnabled() || webhook.HypershiftEnabled() {
+				t.Error("Expected the webhook to be deployed to classic clusters only")
+			}
+			selector := webhook.SyncSetLabelSelector()
+			if len(selector.MatchExpressions) != test.expressionCount || (test.expressionCount > 0 && selector.MatchExpressions[0].Values[0] != test.clusterType) {
+				t.Errorf("Unexpected SyncSet label selector %v", selector)
+			}
+
+			response := webhook.Authorized(request("unknown-user", nil, admissionv1.Delete, test.group, test.kind))
+			if response.Result.Message != test.code.Format(test.message) || response.Result.Reason != metav1.StatusReason(test.code) {
+				t.Errorf("Expected denial %s %q, got %s %q", test.code, test.message, response.Result.Reason, response.Result.Message)
+			}
+		})
+	}
+}
+
+// TestEmbeddedValidate checks each embedded policy only accepts well-formed
+// requests for its kind
+func TestEmbeddedValidate(t *testing.T) {
+	policies := []struct {
+		name  string
+		group string
+		kind  string
+	}{
+		{"hiveownership-validation", "quota.openshift.io", "ClusterResourceQuota"},
+		{"hostedcluster-validation", "hypershift.openshift.io", "HostedCluster"},
+		{"hostedcontrolplane-validation", "hypershift.openshift.io", "HostedControlPlane"},
+		{"manifestworks-validation", "work.open-cluster-management.io", "ManifestWork"},
+	}
+	for _, p := range policies {
+		webhook := embeddedWebhook(t, p.name)
+		tests := []struct {
+			name     string
+			request  admissionctl.Request
+			expected bool
+		}{
+			{"Valid request", request("test-user", nil, admissionv1.Delete, p.group, p.kind), true},
+			{"Invalid request without username", request("", nil, admissionv1.Delete, p.group, p.kind), false},
+			{"Invalid request with wrong kind", request("test-user", nil, admissionv1.Delete, p.group, "Pod"), false},
+			{"Invalid request with wrong group", request("test-user", nil, admissionv1.Delete, "apps", p.kind), false},
+		}
+		for _, test := range tests {
+			t.Run(p.name+"/"+test.name, func(t *testing.T) {
+				if result := webhook.Validate(test.request); result != test.expected {
+					t.Errorf("Expected %v, got %v", test.expected, result)
+				}
+			})
+		}
+	}
+}
+
+const testCELPolicy = `
+name: test-cel-validation
+doc: Widgets must keep their size and be no bigger than the limit
+rules:
+- operations: ["CREATE", "UPDATE", "DELETE"]
+  apiGroups: ["example.com"]
+  apiVersions: ["*"]
+  resources: ["widgets"]
+kinds:
+- group: example.com
+  kind: Widget
+exempt:
+  users: ["admin"]
+matchConditions:
+- name: not-deleted
+  expression: request.operation != "DELETE"
+variables:
+- name: size
+  expression: object.spec.size
+- name: limit
+  expression: params.maxSize
+validations:
+- expression: oldObject == null || oldObject.spec.size == variables.size
+  message: The size of a widget cannot be changed
+- expression: variables.size <= variables.limit
+  messageExpression: "'widget ' + request.name + ' is bigger than ' + string(variables.limit)"
+  reason: Invalid
+- expression: object.metadata.name.startsWith("widget-")
+- expression: variables.size + 1 > 1
+  message: Widgets must have a size
+params:
+  maxSize: 10
+message: Widgets must be named widget-*
+failurePolicy: Fail
+targets:
+  classic: true
+`
+
+func celRequest(username string, operation admissionv1.Operation, name string, size, oldSize int) admissionctl.Request {
+	req := request(username, nil, operation, "example.com", "Widget")
+	req.Name = name
+	object := func(size int) runtime.RawExtension {
+		if size < 0 {
+			return runtime.RawExtension{}
+		}
+		return runtime.RawExtension{Raw: []byte(fmt.Sprintf(`{"metadata":{"name":%q},"spec":{"size":%d}}`, name, size))}
+	}
+	req.Object = object(size)
+	req.OldObject = object(oldSize)
+	return req
+}
+
+func TestCELValidations(t *testing.T) {
+	p, err := Parse([]byte(testCELPolicy))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	webhook := NewWebhook(p)
+	tests := []struct {
+		name            string
+		request         admissionctl.Request
+		shouldBeAllowed bool
+		errored         bool
+		message         string
+		reason          metav1.StatusReason
+	}{
+		{
+			name:            "valid create",
+			request:         celRequest("someone", admissionv1.Create, "widget-a", 5, -1),
+			shouldBeAllowed: true,
+		},
+		{
+			name:            "valid update",
+			request:         celRequest("someone", admissionv1.Update, "widget-a", 5, 5),
+			shouldBeAllowed: true,
+		},
+		{
+			name:            "changed size",
+			request:         celRequest("someone", admissionv1.Update, "widget-a", 6, 5),
+			shouldBeAllowed: false,
+			message:         "The size of a widget cannot be changed",
+			reason:          metav1.StatusReasonForbidden,
+		},
+		{
+			name:            "message expression",
+			request:         celRequest("someone", admissionv1.Create, "widget-a", 11, -1),
+			shouldBeAllowed: false,
+			message:         "widget widget-a is bigger than 10",
+			reason:          metav1.StatusReasonInvalid,
+		},
+		{
+			name:            "policy message",
+			request:         celRequest("someone", admissionv1.Create, "gadget", 1, -1),
+			shouldBeAllowed: false,
+			message:         "Widgets must be named widget-*",
+		},
+		{
+			name:            "integer arithmetic",
+			request:         celRequest("someone", admissionv1.Create, "widget-a", 0, -1),
+			shouldBeAllowed: false,
+			message:         "Widgets must have a size",
+		},
+		{
+			name:            "exempt user",
+			request:         celRequest("admin", admissionv1.Create, "gadget", 11, -1),
+			shouldBeAllowed: true,
+		},
+		{
+			name:            "match condition not met",
+			request:         celRequest("someone", admissionv1.Delete, "gadget", -1, 11),
+			shouldBeAllowed: true,
+		},
+		{
+			name:            "evaluation error fails closed",
+			request:         celRequest("someone", admissionv1.Create, "widget-a", -1, -1),
+			shouldBeAllowed: false,
+			errored:         true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := webhook.Authorized(test.request)
+			if response.Allowed != test.shouldBeAllowed {
+				t.Fatalf("Expected allowed %v, got %v: %s", test.shouldBeAllowed, response.Allowed, response.Result.Message)
+			}
+			if test.errored != (response.Result.Code == http.StatusInternalServerError) {
+				t.Errorf("Expected errored %v, got code %d", test.errored, response.Result.Code)
+			}
+			if test.message != "" && response.Result.Message != test.message {
+				t.Errorf("Expected message %q, got %q", test.message, response.Result.Message)
+			}
+			if test.reason != "" && response.Result.Reason != test.reason {
+				t.Errorf("Expected reason %q, got %q", test.reason, response.Result.Reason)
+			}
+		})
+	}
+}
+
+func TestCELErrorsIgnored(t *testing.T) {
+	p, err := Parse([]byte(strings.Replace(testCELPolicy, "failurePolicy: Fail", "failurePolicy: Ignore", 1)))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	response := NewWebhook(p).Authorized(celRequest("someone", admissionv1.Create, "widget-a", -1, -1))
+	if !response.Allowed {
+		t.Errorf("Expected an evaluation error to be ignored, got %s", response.Result.Message)
+	}
+}
+
+func TestAuthorizedTrace(t *testing.T) {
+	p, err := Parse([]byte(testCELPolicy))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	trace := explain.New()
+	ctx := explain.NewContext(context.Background(), trace)
+	response := NewWebhook(p).AuthorizedWithContext(ctx, celRequest("someone", admissionv1.Update, "widget-a", 6, 5))
+	if response.Allowed {
+		t.Fatalf("Expected the request to be denied")
+	}
+	expected := []string{
+		`exempt: user in ["admin"] → no`,
+		"user in allowlist → no",
+		"policy restricts UPDATE → yes",
+		`match condition "request.operation != \"DELETE\"" → yes`,
+		`validation "oldObject == null || oldObject.spec.size == variables.size" → no`,
+	}
+	checks := trace.Checks()
+	if len(checks) != len(expected) {
+		t.Fatalf("Expected %d checks, got %v", len(expected), checks)
+	}
+	for i, check := range checks {
+		if check.String() != expected[i] {
+			t.Errorf("Expected check %d to be %q, got %q", i, expected[i], check)
+		}
+	}
+}
+
+func TestCELRequestVariable(t *testing.T) {
+	policy := strings.Replace(testCELPolicy, `object.metadata.name.startsWith("widget-")`,
+		`request.userInfo.username == "someone" && request.kind.kind == "Widget" && request.uid == "test-uid"`, 1)
+	p, err := Parse([]byte(policy))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	webhook := NewWebhook(p)
+	if response := webhook.Authorized(celRequest("someone", admissionv1.Create, "gadget", 1, -1)); !response.Allowed {
+		t.Errorf("Expected the request variable to match, got %s", response.Result.Message)
+	}
+	if response := webhook.Authorized(celRequest("someone-else", admissionv1.Create, "gadget", 1, -1)); response.Allowed {
+		t.Error("Expected the request variable not to match")
+	}
+}
+
+func TestCELCompileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		replace [2]string
+		errMsg  string
+	}{
+		{
+			name:    "syntax error",
+			replace: [2]string{"variables.size <= variables.limit", "variables.size <="},
+			errMsg:  "couldn't compile",
+		},
+		{
+			name:    "not a bool",
+			replace: [2]string{`object.metadata.name.startsWith("widget-")`, "1 + 1"},
+			errMsg:  "not bool",
+		},
+		{
+			name:    "undeclared variable",
+			replace: [2]string{"params.maxSize", "namespaceObject.metadata"},
+			errMsg:  "undeclared reference",
+		},
+		{
+			name:    "bad variable name",
+			replace: [2]string{"name: limit", "name: the-limit"},
+			errMsg:  "CEL identifier",
+		},
+		{
+			name:    "message required",
+			replace: [2]string{"message: Widgets must be named widget-*", ""},
+			errMsg:  "message is required",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Parse([]byte(strings.Replace(testCELPolicy, test.replace[0], test.replace[1], 1)))
+			if err == nil || !strings.Contains(err.Error(), test.errMsg) {
+				t.Errorf("Expected error containing %q, got %v", test.errMsg, err)
+			}
+		})
+	}
+}
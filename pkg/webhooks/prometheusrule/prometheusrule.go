@@ -1,12 +1,15 @@
 package prometheusrule
 
 import (
+	"context"
 	"fmt"
 	"net/http"
-	"regexp"
 	"slices"
 
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/allowlist"
 	hookconfig "github.com/openshift/managed-cluster-validating-webhooks/pkg/config"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/explain"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/helpers"
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
 
 	admissionregv1 "k8s.io/api/admissionregistration/v1"
@@ -23,13 +26,14 @@ const (
 )
 
 var (
-	timeout                          int32 = 2
-	allowedUsers                           = []string{"kube:admin", "system:admin", "backplane-cluster-admin"}
-	sreAdminGroups                         = []string{"system:serviceaccounts:openshift-backplane-srep"}
-	privilegedServiceAccountGroupsRe       = regexp.MustCompile(utils.PrivilegedServiceAccountGroups)
-	privilegedLabels                       = map[string]string{"app.kubernetes.io/name": "stackrox"}
-	scope                                  = admissionregv1.NamespacedScope
-	rules                                  = []admissionregv1.RuleWithOperations{
+	timeout           int32 = 2
+	allowedPrincipals       = utils.Principals{
+		Users:  []string{"kube:admin", "system:admin", "backplane-cluster-admin"},
+		Groups: []string{"system:serviceaccounts:openshift-backplane-srep"},
+	}
+	privilegedLabels = map[string]string{"app.kubernetes.io/name": "stackrox"}
+	scope            = admissionregv1.NamespacedScope
+	rules            = []admissionregv1.RuleWithOperations{
 		{
 			Operations: []admissionregv1.OperationType{
 				admissionregv1.Create,
@@ -71,10 +75,15 @@ func NewWebhook() *prometheusruleWebhook {
 
 // Authorized implements Webhook interface
 func (s *prometheusruleWebhook) Authorized(request admissionctl.Request) admissionctl.Response {
-	return s.authorized(request)
+	return s.authorized(context.Background(), request)
+}
+
+// AuthorizedWithContext implements ContextWebhook interface
+func (s *prometheusruleWebhook) AuthorizedWithContext(ctx context.Context, request admissionctl.Request) admissionctl.Response {
+	return s.authorized(ctx, request)
 }
 
-func (s *prometheusruleWebhook) authorized(request admissionctl.Request) admissionctl.Response {
+func (s *prometheusruleWebhook) authorized(ctx context.Context, request admissionctl.Request) admissionctl.Response {
 	var ret admissionctl.Response
 
 	pr, err := s.renderPrometheusRule(request)
@@ -84,30 +93,28 @@ func (s *prometheusruleWebhook) authorized(request admissionctl.Request) admissi
 	}
 
 	// This block covers the denial flow for PrivilegedNamespaces, excluding some special case namespaces.
-	if hookconfig.IsPrivilegedNamespace(pr.GetNamespace()) && !slices.Contains(privilegedNamespacesAllowed, pr.GetNamespace()) {
+	trace := explain.FromContext(ctx)
+	if trace.Recordf(hookconfig.IsPrivilegedNamespace(pr.GetNamespace()) && !slices.Contains(privilegedNamespacesAllowed, pr.GetNamespace()), "namespace is privileged and not in %q", privilegedNamespacesAllowed) {
 		log.Info(fmt.Sprintf("%s operation detected on managed namespace: %s", request.Operation, pr.GetNamespace()))
-		if isAllowedUser(request) {
+		if isAllowedUser(ctx, request) {
 			ret = admissionctl.Allowed(fmt.Sprintf("User can do operations on PrometheusRules"))
 			ret.UID = request.AdmissionRequest.UID
 			return ret
 		}
-		for _, group := range request.UserInfo.Groups {
-			if privilegedServiceAccountGroupsRe.Match([]byte(group)) {
-				ret = admissionctl.Allowed("Privileged service accounts do operations on PrometheusRules")
-				ret.UID = request.AdmissionRequest.UID
-				return ret
-			}
+		if match, ok := utils.PrivilegedServiceAccounts.ExplainRequest(ctx, "privileged service accounts", request); ok {
+			ret = admissionctl.Allowed(fmt.Sprintf("Privileged service accounts do operations on PrometheusRules: %s", match))
+			ret.UID = request.AdmissionRequest.UID
+			return ret
 		}
 
 		// TODO: [OSD-20025] Remove this exception after MON-3518 is completed
-		if hasPrivilegedLabel(pr) {
+		if trace.Recordf(hasPrivilegedLabel(pr), "rule has one of the labels %v", privilegedLabels) {
 			ret = admissionctl.Allowed("PrometheusRules with privileged labels can be modified")
 			ret.UID = request.AdmissionRequest.UID
 			return ret
 		}
 
-		ret = admissionctl.Denied(fmt.Sprintf("Prevented from accessing Red Hat managed resources. This is in an effort to prevent harmful actions that may cause unintended consequences or affect the stability of the cluster. If you have any questions about this, please reach out to Red Hat support at https://access.redhat.com/support"))
-		ret.UID = request.AdmissionRequest.UID
+		ret = utils.WebhookResponse(request, false, "", utils.WithCode(helpers.CodeManagedResource, nil))
 		return ret
 	}
 
@@ -117,18 +124,9 @@ func (s *prometheusruleWebhook) authorized(request admissionctl.Request) admissi
 }
 
 // isAllowedUser checks if the user or group is allowed to perform the action
-func isAllowedUser(request admissionctl.Request) bool {
-	if slices.Contains(allowedUsers, request.UserInfo.Username) {
-		return true
-	}
-
-	for _, group := range sreAdminGroups {
-		if slices.Contains(request.UserInfo.Groups, group) {
-			return true
-		}
-	}
-
-	return false
+func isAllowedUser(ctx context.Context, request admissionctl.Request) bool {
+	return explain.FromContext(ctx).Record(allowlist.Allowed(WebhookName, request.AdmissionRequest.UserInfo), "user in allowlist") ||
+		allowedPrincipals.ExplainsRequest(ctx, "allowed principals", request)
 }
 
 // hasPrivilegedLabel checks if the rendered rule's labels match one of the privilegedLabels
@@ -155,6 +153,11 @@ func (s *prometheusruleWebhook) Validate(request admissionctl.Request) bool {
 	return valid
 }
 
+// SelfTestKind implements SelfTestWebhook interface
+func (s *prometheusruleWebhook) SelfTestKind() metav1.GroupKind {
+	return metav1.GroupKind{Group: "monitoring.coreos.com", Kind: "PrometheusRule"}
+}
+
 func (s *prometheusruleWebhook) renderPrometheusRule(req admissionctl.Request) (*prometheusRule, error) {
 	decoder := admissionctl.NewDecoder(&s.s)
 	prometheusRule := &prometheusRule{}
@@ -220,3 +223,6 @@ func (s *prometheusruleWebhook) SyncSetLabelSelector() metav1.LabelSelector {
 func (s *prometheusruleWebhook) ClassicEnabled() bool { return true }
 
 func (s *prometheusruleWebhook) HypershiftEnabled() bool { return false }
+
+// AllowlistEnabled implements AllowlistWebhook interface
+func (s *prometheusruleWebhook) AllowlistEnabled() bool { return true }
@@ -1,6 +1,8 @@
 package webhooks
 
 import (
+	"context"
+
 	admissionregv1 "k8s.io/api/admissionregistration/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
@@ -53,6 +55,63 @@ type Webhook interface {
 	HypershiftEnabled() bool
 }
 
+// ContextWebhook is implemented by webhooks which do work, such as calling the
+// API server, that should stop when the admission request's deadline passes,
+// and by webhooks which record their checks in the explain.Trace the context
+// carries, so that the /explain endpoint can show why a request was allowed
+// or denied. The dispatcher calls AuthorizedWithContext instead of Authorized
+// for these.
+type ContextWebhook interface {
+	Webhook
+	// AuthorizedWithContext will determine if the request is allowed, giving up
+	// when ctx is done
+	AuthorizedWithContext(ctx context.Context, request admissionctl.Request) admissionctl.Response
+}
+
+// ClusterAccessWebhook is implemented by webhooks which need to reach the API
+// server to reach a decision. The server does not report itself ready until
+// CheckClusterAccess succeeds.
+type ClusterAccessWebhook interface {
+	Webhook
+	// CheckClusterAccess returns an error if the webhook cannot reach the API
+	// resources it needs
+	CheckClusterAccess(ctx context.Context) error
+}
+
+// AllowlistWebhook is implemented by webhooks which exempt the principals the
+// allowlist configures for them, in addition to their hardcoded exemptions.
+// The allowlist may only name these webhooks, as an entry for any other would
+// grant nothing.
+type AllowlistWebhook interface {
+	Webhook
+	// AllowlistEnabled will return true if the webhook exempts allowlisted principals
+	AllowlistEnabled() bool
+}
+
+// SelfTestWebhook is implemented by webhooks whose Validate only accepts
+// requests for particular kinds of object. The dispatcher's self-test sends
+// them a request for SelfTestKind, so that it reaches Authorized.
+type SelfTestWebhook interface {
+	Webhook
+	// SelfTestKind returns the kind of object the self-test request is for
+	SelfTestKind() metav1.GroupKind
+}
+
+// AdmissionPolicyWebhook is implemented by webhooks whose logic can be
+// expressed in CEL, so that build/resources.go can render them as a
+// ValidatingAdmissionPolicy and ValidatingAdmissionPolicyBinding on clusters
+// which support them.
+type AdmissionPolicyWebhook interface {
+	Webhook
+	// AdmissionPolicy returns the match conditions, variables and validations
+	// of the ValidatingAdmissionPolicy, or false if the webhook can't be
+	// expressed as one. Its match constraints and failure policy come from
+	// Rules, ObjectSelector, MatchPolicy and FailurePolicy.
+	AdmissionPolicy() (admissionregv1.ValidatingAdmissionPolicySpec, bool)
+	// ValidationActions are how the binding enforces the policy
+	ValidationActions() []admissionregv1.ValidationAction
+}
+
 // WebhookFactory return a kind of Webhook
 type WebhookFactory func() Webhook
 
@@ -0,0 +1,13 @@
+package webhooks
+
+import "testing"
+
+// TestWebhooksRecordChecks makes sure every registered webhook records its
+// checks when a request is explained
+func TestWebhooksRecordChecks(t *testing.T) {
+	for name, factory := range Webhooks {
+		if _, ok := factory().(ContextWebhook); !ok {
+			t.Errorf("webhook %s does not implement ContextWebhook, so its checks can't be explained", name)
+		}
+	}
+}
@@ -1,13 +1,17 @@
 package common
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"slices"
-	"strings"
 
 	networkv1 "github.com/openshift/api/network/v1"
+	"github.com/openshift/managed-cluster-validating-webhooks/config"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/allowlist"
 	hookconfig "github.com/openshift/managed-cluster-validating-webhooks/pkg/config"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/explain"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/helpers"
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/namespace"
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
 	admissionv1 "k8s.io/api/admission/v1"
@@ -43,19 +47,57 @@ const (
 )
 
 var (
-	adminGroups         = []string{"system:serviceaccounts:openshift-backplane-srep"}
-	adminUsers          = []string{"backplane-cluster-admin"}
-	clusterVersionUsers = []string{
-		"system:serviceaccount:openshift-managed-upgrade-operator:managed-upgrade-operator",
-		"system:serviceaccount:openshift-cluster-version:default",
-		"system:serviceaccount:openshift-cluster-version:cluster-version-operator",
+	admins = utils.Principals{
+		Users:  []string{"backplane-cluster-admin"},
+		Groups: []string{"system:serviceaccounts:openshift-backplane-srep"},
 	}
-	machineConfigUsers = []string{
-		"system:serviceaccount:openshift-cluster-node-tuning-operator:cluster-node-tuning-operator",
-		"system:serviceaccount:openshift-machine-config-operator:machine-config-controller",
-		"system:admin",
+	// customerAdmins are the customer's own administrators
+	customerAdmins = utils.Principals{
+		Groups: []string{"cluster-admins", "dedicated-admins"},
+	}
+	mustGatherPrincipals = utils.Principals{
+		Groups: []string{"system:serviceaccounts:openshift-backplane-cee"},
+	}
+	clusterVersionPrincipals = utils.Principals{
+		Users: []string{
+			"system:serviceaccount:openshift-managed-upgrade-operator:managed-upgrade-operator",
+			"system:serviceaccount:openshift-cluster-version:default",
+			"system:serviceaccount:openshift-cluster-version:cluster-version-operator",
+			"backplane-cluster-admin",
+		},
+		Groups: admins.Groups,
+	}
+	// clusterVersionSystemUsers may also modify ClusterVersion resources
+	clusterVersionSystemUsers = utils.Principals{
+		UserPrefixes:         []string{"system:"},
+		ExcludedUserPrefixes: []string{"system:serviceaccount:"},
+	}
+	machineConfigPrincipals = utils.Principals{
+		Users: []string{
+			"backplane-cluster-admin",
+			"system:serviceaccount:openshift-cluster-node-tuning-operator:cluster-node-tuning-operator",
+			"system:serviceaccount:openshift-machine-config-operator:machine-config-controller",
+			"system:admin",
+		},
+		Groups: []string{"cluster-admins"},
+	}
+	// systemUsers are allowed once the checks of specific kinds have passed.
+	// kube: users are allowed before them.
+	systemUsers = utils.Principals{
+		UserPrefixes: []string{"system:"},
+	}
+	kubeUsers = utils.Principals{
+		UserPrefixes: []string{"kube:"},
+	}
+	// webhookConfigMaps configure the webhooks themselves, in
+	// config.OperatorNamespace, and so may not be changed by those the
+	// webhooks constrain
+	webhookConfigMaps = []string{"webhook-allowlist", "webhook-breakglass"}
+	// webhookConfigPrincipals are SRE and hive, which syncs the ConfigMaps
+	webhookConfigPrincipals = utils.Principals{
+		Users:  []string{"backplane-cluster-admin", "system:admin"},
+		Groups: admins.Groups,
 	}
-	ceeGroup = "system:serviceaccounts:openshift-backplane-cee"
 
 	scope = admissionregv1.AllScopes
 	rules = []admissionregv1.RuleWithOperations{
@@ -211,159 +253,136 @@ func (s *RegularuserWebhook) Validate(req admissionctl.Request) bool {
 
 // Authorized implements Webhook interface
 func (s *RegularuserWebhook) Authorized(request admissionctl.Request) admissionctl.Response {
-	return s.authorized(request)
+	return s.authorized(context.Background(), request)
+}
+
+// AuthorizedWithContext implements ContextWebhook interface
+func (s *RegularuserWebhook) AuthorizedWithContext(ctx context.Context, request admissionctl.Request) admissionctl.Response {
+	return s.authorized(ctx, request)
 }
 
-func (s *RegularuserWebhook) authorized(request admissionctl.Request) admissionctl.Response {
+func (s *RegularuserWebhook) authorized(ctx context.Context, request admissionctl.Request) admissionctl.Response {
 	var ret admissionctl.Response
+	trace := explain.FromContext(ctx)
 
-	if request.AdmissionRequest.UserInfo.Username == "system:unauthenticated" {
+	if utils.UnauthenticatedUsers.ExplainsRequest(ctx, "unauthenticated users", request) {
 		// This could highlight a significant problem with RBAC since an
 		// unauthenticated user should have no permissions.
 		log.Info("system:unauthenticated made a webhook request. Check RBAC rules", "request", request.AdmissionRequest)
-		ret = admissionctl.Denied("Unauthenticated")
-		ret.UID = request.AdmissionRequest.UID
+		ret = utils.WebhookResponse(request, false, "", utils.WithCode(helpers.CodeUnauthenticated, nil))
 		return ret
 	}
 
 	// Check MachineConfig resources first - only cluster-admins group allowed
-	if request.Kind.Group == machineConfigGroup {
-		if isMachineConfigAuthorized(request) {
+	if trace.Recordf(request.Kind.Group == machineConfigGroup, "group is %s", machineConfigGroup) {
+		if isMachineConfigAuthorized(ctx, request) {
 			return utils.WebhookResponse(request, true, "")
 		} else {
 			log.Info("Denying access", "request", request.AdmissionRequest)
-			return utils.WebhookResponse(request, false, "Prevented from accessing Red Hat managed resources. This is in an effort to prevent harmful actions that may cause unintended consequences or affect the stability of the cluster. If you have any questions about this, please reach out to Red Hat support at https://access.redhat.com/support")
+			return utils.WebhookResponse(request, false, "", utils.WithCode(helpers.CodeManagedResource, nil))
 		}
 	}
 
-	if strings.HasPrefix(request.AdmissionRequest.UserInfo.Username, "kube:") {
-		ret = admissionctl.Allowed("kube: users are allowed")
+	if match, ok := kubeUsers.ExplainRequest(ctx, "kube users", request); ok {
+		ret = admissionctl.Allowed(fmt.Sprintf("kube: users are allowed: %s", match))
 		ret.UID = request.AdmissionRequest.UID
 		return ret
 	}
 
 	switch {
-	case utils.RequestMatchesGroupKind(request, mustGatherKind, mustGatherGroup):
-		if isMustGatherAuthorized(request) {
+	case trace.Recordf(utils.RequestMatchesGroupKind(request, mustGatherKind, mustGatherGroup), "kind is %s.%s", mustGatherKind, mustGatherGroup):
+		if isMustGatherAuthorized(ctx, request) {
 			ret = admissionctl.Allowed("Management of MustGather CR is authorized")
 			ret.UID = request.AdmissionRequest.UID
 			return ret
 		}
-	case utils.RequestMatchesGroupKind(request, customDomainKind, customDomainGroup):
-		if isCustomDomainAuthorized(request) {
+	case trace.Recordf(utils.RequestMatchesGroupKind(request, customDomainKind, customDomainGroup), "kind is %s.%s", customDomainKind, customDomainGroup):
+		if isCustomDomainAuthorized(ctx, request) {
 			ret = admissionctl.Allowed("Management of CustomDomain CR is authorized")
 			ret.UID = request.AdmissionRequest.UID
 			return ret
 		}
-	case utils.RequestMatchesGroupKind(request, clusterVersionKind, clusterVersionGroup):
-		if isClusterVersionAuthorized(request) {
+	case trace.Recordf(utils.RequestMatchesGroupKind(request, clusterVersionKind, clusterVersionGroup), "kind is %s.%s", clusterVersionKind, clusterVersionGroup):
+		if isClusterVersionAuthorized(ctx, request) {
 			return utils.WebhookResponse(request, true, "")
 		} else {
 			log.Info("Denying access", "request", request.AdmissionRequest)
-			return utils.WebhookResponse(request, false, "Prevented from accessing Red Hat managed resources. This is in an effort to prevent harmful actions that may cause unintended consequences or affect the stability of the cluster. If you have any questions about this, please reach out to Red Hat support at https://access.redhat.com/support")
+			return utils.WebhookResponse(request, false, "", utils.WithCode(helpers.CodeManagedResource, nil))
 		}
-	case utils.RequestMatchesGroupKind(request, netNamespaceKind, netNamespaceGroup):
-		if isNetNamespaceAuthorized(s, request) {
+	case trace.Recordf(utils.RequestMatchesGroupKind(request, netNamespaceKind, netNamespaceGroup), "kind is %s.%s", netNamespaceKind, netNamespaceGroup):
+		if isNetNamespaceAuthorized(ctx, s, request) {
 			ret = admissionctl.Allowed("Management of NetNamespace CR is authorized")
 			ret.UID = request.AdmissionRequest.UID
 			return ret
 		}
+	case request.Kind.Kind == "ConfigMap" && trace.Recordf(isWebhookConfigMap(s, request), "ConfigMap is %s/%v", config.OperatorNamespace, webhookConfigMaps):
+		// Checked before system users, which include every serviceaccount
+		if webhookConfigPrincipals.ExplainsRequest(ctx, "webhook config principals", request) {
+			return utils.WebhookResponse(request, true, "")
+		}
+		log.Info("Denying access", "request", request.AdmissionRequest)
+		return utils.WebhookResponse(request, false, "", utils.WithCode(helpers.CodeManagedResource, nil))
 	}
 
 	// TODO: Do not allow all system:serviceaccount:* users or belong to system:serviceaccounts:* groups
 	// https://kubernetes.io/docs/reference/access-authn-authz/rbac/
-	if strings.HasPrefix(request.AdmissionRequest.UserInfo.Username, "system:") {
-		ret = admissionctl.Allowed("authenticated system: users are allowed")
+	if match, ok := systemUsers.ExplainRequest(ctx, "system users", request); ok {
+		ret = admissionctl.Allowed(fmt.Sprintf("authenticated system: users are allowed: %s", match))
 		ret.UID = request.AdmissionRequest.UID
 		return ret
 	}
 
-	if slices.Contains(adminUsers, request.AdmissionRequest.UserInfo.Username) {
-		ret = admissionctl.Allowed("Specified admin users are allowed")
+	if match, ok := admins.ExplainRequest(ctx, "admins", request); ok {
+		ret = admissionctl.Allowed(fmt.Sprintf("Specified admin users and members of admin groups are allowed: %s", match))
 		ret.UID = request.AdmissionRequest.UID
 		return ret
 	}
 
-	for _, userGroup := range request.UserInfo.Groups {
-		if slices.Contains(adminGroups, userGroup) {
-			ret = admissionctl.Allowed("Members of admin groups are allowed")
-			ret.UID = request.AdmissionRequest.UID
-			return ret
-		}
+	if trace.Record(allowlist.Allowed(WebhookName, request.AdmissionRequest.UserInfo), "user in allowlist") {
+		ret = admissionctl.Allowed("Allowlisted users are allowed")
+		ret.UID = request.AdmissionRequest.UID
+		return ret
 	}
 
-	if request.Kind.Kind == "ConfigMap" && shouldAllowConfigMapChange(s, request) {
+	if request.Kind.Kind == "ConfigMap" && trace.Record(shouldAllowConfigMapChange(s, request), "ConfigMap is not openshift-config/user-ca-bundle") {
 		ret = admissionctl.Allowed("Modification of Config Maps that are not user-ca-bundle are allowed")
 		ret.UID = request.AdmissionRequest.UID
 		return ret
 	}
 
 	log.Info("Denying access", "request", request.AdmissionRequest)
-	ret = admissionctl.Denied("Prevented from accessing Red Hat managed resources. This is in an effort to prevent harmful actions that may cause unintended consequences or affect the stability of the cluster. If you have any questions about this, please reach out to Red Hat support at https://access.redhat.com/support")
-	ret.UID = request.AdmissionRequest.UID
+	ret = utils.WebhookResponse(request, false, "", utils.WithCode(helpers.CodeManagedResource, nil))
 	return ret
 }
 
 // isMustGatherAuthorized check if request is authorized for MustGather CR
-func isMustGatherAuthorized(request admissionctl.Request) bool {
-	return slices.Contains(request.UserInfo.Groups, ceeGroup)
+func isMustGatherAuthorized(ctx context.Context, request admissionctl.Request) bool {
+	return mustGatherPrincipals.ExplainsRequest(ctx, "must-gather principals", request)
 }
 
 // isCustomDomainAuthorized check if request is authorized for CustomDomain CR
-func isCustomDomainAuthorized(request admissionctl.Request) bool {
-	return slices.Contains(request.UserInfo.Groups, "cluster-admins") ||
-		slices.Contains(request.UserInfo.Groups, "dedicated-admins")
+func isCustomDomainAuthorized(ctx context.Context, request admissionctl.Request) bool {
+	return customerAdmins.ExplainsRequest(ctx, "customer admins", request)
 }
 
 // isNetNamespaceAuthorized check if request is authorized for NetNamespace CR
-func isNetNamespaceAuthorized(s *RegularuserWebhook, request admissionctl.Request) bool {
-	return (slices.Contains(request.UserInfo.Groups, "cluster-admins") ||
-		slices.Contains(request.UserInfo.Groups, "dedicated-admins")) &&
-		isNetNamespaceValid(s, request)
+func isNetNamespaceAuthorized(ctx context.Context, s *RegularuserWebhook, request admissionctl.Request) bool {
+	return customerAdmins.ExplainsRequest(ctx, "customer admins", request) &&
+		explain.FromContext(ctx).Record(isNetNamespaceValid(s, request), "NetNamespace is not privileged")
 }
 
 // isClusterVersionAuthorized only allows specific K8s serviceaccounts to modify ClusterVersion resources
-func isClusterVersionAuthorized(request admissionctl.Request) bool {
-	if slices.Contains(clusterVersionUsers, request.UserInfo.Username) {
-		return true
-	}
-
-	if slices.Contains(adminUsers, request.AdmissionRequest.UserInfo.Username) {
-		return true
-	}
-
-	for _, userGroup := range request.UserInfo.Groups {
-		if slices.Contains(adminGroups, userGroup) {
-			return true
-		}
-	}
-
-	if strings.HasPrefix(request.AdmissionRequest.UserInfo.Username, "system:") && !strings.HasPrefix(request.AdmissionRequest.UserInfo.Username, "system:serviceaccount:") {
-		return true
-	}
-
-	return false
+func isClusterVersionAuthorized(ctx context.Context, request admissionctl.Request) bool {
+	return clusterVersionPrincipals.ExplainsRequest(ctx, "cluster version principals", request) ||
+		explain.FromContext(ctx).Record(allowlist.Allowed(WebhookName, request.AdmissionRequest.UserInfo), "user in allowlist") ||
+		clusterVersionSystemUsers.ExplainsRequest(ctx, "cluster version system users", request)
 }
 
 // isMachineConfigAuthorized allows cluster-admins group, backplane-cluster-admin user,
 // and specific serviceaccounts to modify MachineConfig resources
-func isMachineConfigAuthorized(request admissionctl.Request) bool {
-	// Allow cluster-admins group
-	if slices.Contains(request.UserInfo.Groups, "cluster-admins") {
-		return true
-	}
-
-	// Allow backplane-cluster-admin user
-	if slices.Contains(adminUsers, request.AdmissionRequest.UserInfo.Username) {
-		return true
-	}
-
-	// Allow specific serviceaccounts that need to manage machineconfigs
-	if slices.Contains(machineConfigUsers, request.AdmissionRequest.UserInfo.Username) {
-		return true
-	}
-
-	return false
+func isMachineConfigAuthorized(ctx context.Context, request admissionctl.Request) bool {
+	return machineConfigPrincipals.ExplainsRequest(ctx, "machine config principals", request) ||
+		explain.FromContext(ctx).Record(allowlist.Allowed(WebhookName, request.AdmissionRequest.UserInfo), "user in allowlist")
 }
 
 // isNetNamespaceValid check if the NetNamespace is valid
@@ -386,8 +405,8 @@ func isNetNamespaceValid(s *RegularuserWebhook, request admissionctl.Request) bo
 	return true
 }
 
-// allow if a ConfigMap is being updated that does not live under openshift-config or is not called user-ca-bundle under openshift-config
-func shouldAllowConfigMapChange(s *RegularuserWebhook, request admissionctl.Request) bool {
+// decodeConfigMap decodes the ConfigMap being changed, or deleted
+func decodeConfigMap(s *RegularuserWebhook, request admissionctl.Request) (*corev1.ConfigMap, error) {
 	decoder := admissionctl.NewDecoder(&s.s)
 	configMap := &corev1.ConfigMap{}
 	var err error
@@ -396,6 +415,26 @@ func shouldAllowConfigMapChange(s *RegularuserWebhook, request admissionctl.Requ
 	} else {
 		err = decoder.DecodeRaw(request.Object, configMap)
 	}
+	return configMap, err
+}
+
+// isWebhookConfigMap returns true if the ConfigMap is one of webhookConfigMaps
+func isWebhookConfigMap(s *RegularuserWebhook, request admissionctl.Request) bool {
+	ns, name := request.Namespace, request.Name
+	if configMap, err := decodeConfigMap(s, request); err == nil {
+		if configMap.Namespace != "" {
+			ns = configMap.Namespace
+		}
+		if configMap.Name != "" {
+			name = configMap.Name
+		}
+	}
+	return ns == config.OperatorNamespace && slices.Contains(webhookConfigMaps, name)
+}
+
+// allow if a ConfigMap is being updated that does not live under openshift-config or is not called user-ca-bundle under openshift-config
+func shouldAllowConfigMapChange(s *RegularuserWebhook, request admissionctl.Request) bool {
+	configMap, err := decodeConfigMap(s, request)
 	if err != nil {
 		return false
 	}
@@ -416,6 +455,9 @@ func (s *RegularuserWebhook) ClassicEnabled() bool { return true }
 
 func (s *RegularuserWebhook) HypershiftEnabled() bool { return true }
 
+// AllowlistEnabled implements AllowlistWebhook interface
+func (s *RegularuserWebhook) AllowlistEnabled() bool { return true }
+
 // NewWebhook creates a new webhook
 func NewWebhook() *RegularuserWebhook {
 
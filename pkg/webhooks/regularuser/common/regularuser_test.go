@@ -1010,6 +1010,77 @@ func TestAPIServers(t *testing.T) {
 	runRegularuserTests(t, tests)
 }
 
+func TestWebhookConfigMaps(t *testing.T) {
+	tests := []regularuserTests{}
+	for _, name := range webhookConfigMaps {
+		for _, operation := range []admissionv1.Operation{admissionv1.Create, admissionv1.Update, admissionv1.Delete} {
+			tests = append(tests,
+				regularuserTests{
+					testID:          fmt.Sprintf("%s-%s-cluster-admin", name, operation),
+					targetResource:  "configmaps",
+					targetKind:      "ConfigMap",
+					targetVersion:   "v1",
+					targetName:      name,
+					targetNamespace: "openshift-validation-webhook",
+					username:        "my-name",
+					userGroups:      []string{"cluster-admins", "system:authenticated", "system:authenticated:oauth"},
+					operation:       operation,
+					shouldBeAllowed: false,
+				},
+				regularuserTests{
+					testID:          fmt.Sprintf("%s-%s-customer-serviceaccount", name, operation),
+					targetResource:  "configmaps",
+					targetKind:      "ConfigMap",
+					targetVersion:   "v1",
+					targetName:      name,
+					targetNamespace: "openshift-validation-webhook",
+					username:        "system:serviceaccount:customer:writer",
+					userGroups:      []string{"system:serviceaccounts", "system:serviceaccounts:customer", "system:authenticated"},
+					operation:       operation,
+					shouldBeAllowed: false,
+				},
+				regularuserTests{
+					testID:          fmt.Sprintf("%s-%s-sre-group", name, operation),
+					targetResource:  "configmaps",
+					targetKind:      "ConfigMap",
+					targetVersion:   "v1",
+					targetName:      name,
+					targetNamespace: "openshift-validation-webhook",
+					username:        "my-name",
+					userGroups:      []string{"system:serviceaccounts:openshift-backplane-srep", "system:authenticated", "system:authenticated:oauth"},
+					operation:       operation,
+					shouldBeAllowed: true,
+				},
+				regularuserTests{
+					testID:          fmt.Sprintf("%s-%s-hive", name, operation),
+					targetResource:  "configmaps",
+					targetKind:      "ConfigMap",
+					targetVersion:   "v1",
+					targetName:      name,
+					targetNamespace: "openshift-validation-webhook",
+					username:        "system:admin",
+					userGroups:      []string{"system:masters", "system:authenticated"},
+					operation:       operation,
+					shouldBeAllowed: true,
+				},
+			)
+		}
+	}
+	tests = append(tests, regularuserTests{
+		testID:          "webhook-allowlist-in-another-namespace",
+		targetResource:  "configmaps",
+		targetKind:      "ConfigMap",
+		targetVersion:   "v1",
+		targetName:      "webhook-allowlist",
+		targetNamespace: "customer",
+		username:        "my-name",
+		userGroups:      []string{"cluster-admins", "system:authenticated", "system:authenticated:oauth"},
+		operation:       admissionv1.Create,
+		shouldBeAllowed: true,
+	})
+	runRegularuserTests(t, tests)
+}
+
 func TestName(t *testing.T) {
 	if NewWebhook().Name() == "" {
 		t.Fatalf("Empty hook name")
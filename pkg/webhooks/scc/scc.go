@@ -1,11 +1,14 @@
 package scc
 
 import (
+	"context"
 	"fmt"
 	"net/http"
-	"slices"
 
 	securityv1 "github.com/openshift/api/security/v1"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/allowlist"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/explain"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/helpers"
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
 	admissionv1 "k8s.io/api/admission/v1"
 	admissionregv1 "k8s.io/api/admissionregistration/v1"
@@ -35,14 +38,15 @@ var (
 			},
 		},
 	}
-	allowedUsers = []string{
-		"system:serviceaccount:openshift-kube-apiserver-operator:kube-apiserver-operator",
-		"system:serviceaccount:openshift-monitoring:cluster-monitoring-operator",
-		"system:serviceaccount:openshift-cluster-version:default",
-		"system:admin",
+	allowedPrincipals = utils.Principals{
+		Users: []string{
+			"system:serviceaccount:openshift-kube-apiserver-operator:kube-apiserver-operator",
+			"system:serviceaccount:openshift-monitoring:cluster-monitoring-operator",
+			"system:serviceaccount:openshift-cluster-version:default",
+			"system:admin",
+		},
 	}
-	allowedGroups = []string{}
-	defaultSCCs   = []string{
+	defaultSCCs = []string{
 		"anyuid",
 		"hostaccess",
 		"hostmount-anyuid",
@@ -70,10 +74,15 @@ func NewWebhook() *SCCWebHook {
 
 // Authorized implements Webhook interface
 func (s *SCCWebHook) Authorized(request admissionctl.Request) admissionctl.Response {
-	return s.authorized(request)
+	return s.authorized(context.Background(), request)
+}
+
+// AuthorizedWithContext implements ContextWebhook interface
+func (s *SCCWebHook) AuthorizedWithContext(ctx context.Context, request admissionctl.Request) admissionctl.Response {
+	return s.authorized(ctx, request)
 }
 
-func (s *SCCWebHook) authorized(request admissionctl.Request) admissionctl.Response {
+func (s *SCCWebHook) authorized(ctx context.Context, request admissionctl.Request) admissionctl.Response {
 	var ret admissionctl.Response
 
 	scc, err := s.renderSCC(request)
@@ -82,17 +91,15 @@ func (s *SCCWebHook) authorized(request admissionctl.Request) admissionctl.Respo
 		return admissionctl.Errored(http.StatusBadRequest, err)
 	}
 
-	if isDefaultSCC(scc) && !isAllowedUserGroup(request) {
+	if explain.FromContext(ctx).Recordf(isDefaultSCC(scc), "SCC in %q", defaultSCCs) && !isAllowedUserGroup(ctx, request) {
 		switch request.Operation {
 		case admissionv1.Delete:
 			log.Info(fmt.Sprintf("Deleting operation detected on default SCC: %v", scc.Name))
-			ret = admissionctl.Denied(fmt.Sprintf("Deleting default SCCs %v is not allowed", defaultSCCs))
-			ret.UID = request.AdmissionRequest.UID
+			ret = utils.WebhookResponse(request, false, "", utils.WithCode(helpers.CodeSCCDelete, helpers.Args{"SCCs": defaultSCCs}))
 			return ret
 		case admissionv1.Update:
 			log.Info(fmt.Sprintf("Updating operation detected on default SCC: %v", scc.Name))
-			ret = admissionctl.Denied(fmt.Sprintf("Modifying default SCCs %v is not allowed", defaultSCCs))
-			ret.UID = request.AdmissionRequest.UID
+			ret = utils.WebhookResponse(request, false, "", utils.WithCode(helpers.CodeSCCModify, helpers.Args{"SCCs": defaultSCCs}))
 			return ret
 		}
 	}
@@ -119,18 +126,9 @@ func (s *SCCWebHook) renderSCC(request admissionctl.Request) (*securityv1.Securi
 }
 
 // isAllowedUserGroup checks if the user or group is allowed to perform the action
-func isAllowedUserGroup(request admissionctl.Request) bool {
-	if slices.Contains(allowedUsers, request.UserInfo.Username) {
-		return true
-	}
-
-	for _, group := range allowedGroups {
-		if slices.Contains(request.UserInfo.Groups, group) {
-			return true
-		}
-	}
-
-	return false
+func isAllowedUserGroup(ctx context.Context, request admissionctl.Request) bool {
+	return explain.FromContext(ctx).Record(allowlist.Allowed(WebhookName, request.AdmissionRequest.UserInfo), "user in allowlist") ||
+		allowedPrincipals.ExplainsRequest(ctx, "allowed principals", request)
 }
 
 // isDefaultSCC checks if the request is going to operate on the SCC in the
@@ -158,6 +156,11 @@ func (s *SCCWebHook) Validate(request admissionctl.Request) bool {
 	return valid
 }
 
+// SelfTestKind implements SelfTestWebhook interface
+func (s *SCCWebHook) SelfTestKind() metav1.GroupKind {
+	return metav1.GroupKind{Group: "security.openshift.io", Kind: "SecurityContextConstraints"}
+}
+
 // Name implements Webhook interface
 func (s *SCCWebHook) Name() string {
 	return WebhookName
@@ -207,3 +210,6 @@ func (s *SCCWebHook) SyncSetLabelSelector() metav1.LabelSelector {
 func (s *SCCWebHook) ClassicEnabled() bool { return true }
 
 func (s *SCCWebHook) HypershiftEnabled() bool { return true }
+
+// AllowlistEnabled implements AllowlistWebhook interface
+func (s *SCCWebHook) AllowlistEnabled() bool { return true }
@@ -1,17 +1,21 @@
 package sdnmigration
 
 import (
+	"context"
+	"fmt"
 	"net/http"
-	"regexp"
 
 	configv1 "github.com/openshift/api/config/v1"
 	admissionv1 "k8s.io/api/admission/v1"
 	admissionregv1 "k8s.io/api/admissionregistration/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/explain"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/helpers"
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
 )
 
@@ -23,8 +27,7 @@ const (
 )
 
 var (
-	log                         = logf.Log.WithName(WebhookName)
-	privilegedServiceAccountsRe = regexp.MustCompile(utils.PrivilegedServiceAccountGroups)
+	log = logf.Log.WithName(WebhookName)
 
 	scope = admissionregv1.ClusterScope
 	rules = []admissionregv1.RuleWithOperations{
@@ -46,23 +49,31 @@ type NetworkConfigWebhook struct {
 
 // Authorized will determine if the request is allowed
 func (w *NetworkConfigWebhook) Authorized(request admissionctl.Request) admissionctl.Response {
+	return w.authorized(context.Background(), request)
+}
+
+// AuthorizedWithContext implements ContextWebhook interface
+func (w *NetworkConfigWebhook) AuthorizedWithContext(ctx context.Context, request admissionctl.Request) admissionctl.Response {
+	return w.authorized(ctx, request)
+}
+
+func (w *NetworkConfigWebhook) authorized(ctx context.Context, request admissionctl.Request) admissionctl.Response {
+	trace := explain.FromContext(ctx)
 	// We are doing this check to ensure that hive can trigger the
 	// migration process. Once a cluster install completes successfully,
 	// the admin password and kubeconfig will be uploaded as secrets and linked to the ClusterDeployment resource
 	// on hive under the cluster namespace. Hive uses this credentials for the user "admin-kubeconfig-signer"
 	// in order to call the api on the clusters and execute administrative tasks.
-	if request.UserInfo.Username == privilegedHiveUserAccount {
+	if trace.Recordf(request.UserInfo.Username == privilegedHiveUserAccount, "user is %s", privilegedHiveUserAccount) {
 		return utils.WebhookResponse(request, true, "Privileged user may access")
 	}
 
 	// allow if modified by an allow listed service account
-	for _, group := range request.UserInfo.Groups {
-		if privilegedServiceAccountsRe.Match([]byte(group)) {
-			return utils.WebhookResponse(request, true, "Privileged service accounts may access")
-		}
+	if match, ok := utils.PrivilegedServiceAccounts.ExplainRequest(ctx, "privileged service accounts", request); ok {
+		return utils.WebhookResponse(request, true, fmt.Sprintf("Privileged service accounts may access: %s", match))
 	}
 
-	if request.Operation == admissionv1.Update {
+	if trace.Recordf(request.Operation == admissionv1.Update, "operation is %s", admissionv1.Update) {
 		decoder := admissionctl.NewDecoder(&w.s)
 		object := &configv1.Network{}
 		oldObject := &configv1.Network{}
@@ -80,18 +91,22 @@ func (w *NetworkConfigWebhook) Authorized(request admissionctl.Request) admissio
 			return ret
 		}
 
-		if v, ok := oldObject.Annotations[overrideAnnotation]; ok && v == "true" {
+		if v, ok := oldObject.Annotations[overrideAnnotation]; trace.Recordf(ok && v == "true", "annotation %s is true", overrideAnnotation) {
 			return utils.WebhookResponse(request, true, "`red-hat-internal-testing: true` annotation present")
 		}
 
-		if object.Spec.NetworkType != oldObject.Status.NetworkType {
-			return utils.WebhookResponse(request, false, "Changing the network type is not allowed")
+		if trace.Record(object.Spec.NetworkType != oldObject.Status.NetworkType, "network type changed") {
+			path := field.NewPath("spec", "networkType")
+			detail := fmt.Sprintf("may not be changed from %s", oldObject.Status.NetworkType)
+			return utils.WebhookResponse(request, false, "",
+				utils.WithCode(helpers.CodeSDNMigration, nil),
+				utils.WithFieldErrors(field.ErrorList{field.Forbidden(path, detail)}))
 		}
 
 		return utils.WebhookResponse(request, true, "allowed action")
 	}
 
-	return utils.WebhookResponse(request, false, "Changing the network type is not allowed")
+	return utils.WebhookResponse(request, false, "", utils.WithCode(helpers.CodeSDNMigration, nil))
 }
 
 // GetURI returns the URI for the webhook
@@ -106,6 +121,11 @@ func (w *NetworkConfigWebhook) Validate(req admissionctl.Request) bool {
 	return valid
 }
 
+// SelfTestKind implements SelfTestWebhook interface
+func (w *NetworkConfigWebhook) SelfTestKind() metav1.GroupKind {
+	return metav1.GroupKind{Group: "config.openshift.io", Kind: "Network"}
+}
+
 // Name is the name of the webhook
 func (w *NetworkConfigWebhook) Name() string { return WebhookName }
 
@@ -18,6 +18,7 @@ func TestAuthorized(t *testing.T) {
 		Name          string
 		Request       admissionctl.Request
 		ExpectAllowed bool
+		ExpectFields  []string
 	}{
 		{
 			Name: "privileged account should be allowed",
@@ -87,6 +88,7 @@ func TestAuthorized(t *testing.T) {
 				},
 			},
 			ExpectAllowed: false,
+			ExpectFields:  []string{"spec.networkType"},
 		},
 		{
 			Name: "disallow requests to modify the networkType from OVN to SDN",
@@ -120,6 +122,7 @@ func TestAuthorized(t *testing.T) {
 				},
 			},
 			ExpectAllowed: false,
+			ExpectFields:  []string{"spec.networkType"},
 		},
 		{
 			Name: "allow adding annotations",
@@ -216,6 +219,17 @@ func TestAuthorized(t *testing.T) {
 			if ret.Allowed != test.ExpectAllowed {
 				t.Errorf("TestAuthorized() %s: request %v - allowed: %t, expected: %t\n", test.Name, test.Request, ret.Allowed, test.ExpectAllowed)
 			}
+			var fields []string
+			if ret.Result.Details != nil {
+				for _, cause := range ret.Result.Details.Causes {
+					if cause.Field != "" {
+						fields = append(fields, cause.Field)
+					}
+				}
+			}
+			if !reflect.DeepEqual(fields, test.ExpectFields) {
+				t.Errorf("TestAuthorized() %s: expected field causes %v, got %v", test.Name, test.ExpectFields, fields)
+			}
 		})
 	}
 }
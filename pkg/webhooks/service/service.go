@@ -1,11 +1,13 @@
 package service
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"slices"
 	"strings"
 
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/explain"
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
 	"gomodules.xyz/jsonpatch/v2"
 
@@ -60,15 +62,20 @@ func NewWebhook() *ServiceWebhook {
 
 // Authorized implements Webhook interface
 func (s *ServiceWebhook) Authorized(request admissionctl.Request) admissionctl.Response {
-	//Implement authorized next
-	return s.authorizeOrMutate(request)
+	return s.authorizeOrMutate(context.Background(), request)
+}
+
+// AuthorizedWithContext implements ContextWebhook interface
+func (s *ServiceWebhook) AuthorizedWithContext(ctx context.Context, request admissionctl.Request) admissionctl.Response {
+	return s.authorizeOrMutate(ctx, request)
 }
 
 // authorizeOrMutate decides whether the Request requires mutation before it's allowed to proceed.
 // For this webhook, this function ensures that any LoadBalancer-type Service touched by this
 // Request is annotated with the proper compliance tags
-func (s *ServiceWebhook) authorizeOrMutate(request admissionctl.Request) admissionctl.Response {
+func (s *ServiceWebhook) authorizeOrMutate(ctx context.Context, request admissionctl.Request) admissionctl.Response {
 	var ret admissionctl.Response
+	trace := explain.FromContext(ctx)
 
 	service, err := s.renderService(request)
 	if err != nil {
@@ -76,13 +83,13 @@ func (s *ServiceWebhook) authorizeOrMutate(request admissionctl.Request) admissi
 		return admissionctl.Errored(http.StatusBadRequest, err)
 	}
 
-	if service.Spec.Type != corev1.ServiceTypeLoadBalancer {
+	if !trace.Recordf(service.Spec.Type == corev1.ServiceTypeLoadBalancer, "Service type is %s", corev1.ServiceTypeLoadBalancer) {
 		ret = admissionctl.Allowed("Non-LoadBalancer Services are exempt from compliance annotation requirements")
 		ret.UID = request.AdmissionRequest.UID
 		return ret
 	}
 
-	if hasRedHatManagedTag(service.GetAnnotations()) {
+	if trace.Recordf(hasRedHatManagedTag(service.GetAnnotations()), "annotation %s has the tag %s", annotationKey, annotationValuePrefix+annotationValueSuffix) {
 		ret = admissionctl.Allowed(fmt.Sprintf("Service '%s' contains the proper compliance annotation", service.GetName()))
 		ret.UID = request.AdmissionRequest.UID
 		return ret
@@ -169,6 +176,11 @@ func (s *ServiceWebhook) Validate(request admissionctl.Request) bool {
 	return valid
 }
 
+// SelfTestKind implements SelfTestWebhook interface
+func (s *ServiceWebhook) SelfTestKind() metav1.GroupKind {
+	return metav1.GroupKind{Kind: "Service"}
+}
+
 // Name implements Webhook interface
 func (s *ServiceWebhook) Name() string {
 	return WebhookName
@@ -1,11 +1,11 @@
 package serviceaccount
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"os"
 	"slices"
-	"strings"
 
 	admissionv1 "k8s.io/api/admission/v1"
 	admissionregv1 "k8s.io/api/admissionregistration/v1"
@@ -15,7 +15,10 @@ import (
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/allowlist"
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/config"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/explain"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/helpers"
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
 )
 
@@ -39,11 +42,13 @@ var (
 			},
 		},
 	}
-	allowedUsers = []string{
-		"backplane-cluster-admin",
-	}
-	allowedGroups = []string{
-		"system:serviceaccounts:openshift-backplane-srep",
+	allowedPrincipals = utils.Principals{
+		Users: []string{
+			"backplane-cluster-admin",
+		},
+		Groups: []string{
+			"system:serviceaccounts:openshift-backplane-srep",
+		},
 	}
 	allowedServiceAccounts = []string{
 		"builder",
@@ -83,27 +88,27 @@ func NewWebhook() *serviceAccountWebhook {
 
 // Authorized implements Webhook interface
 func (s *serviceAccountWebhook) Authorized(request admissionctl.Request) admissionctl.Response {
-	return s.authorized(request)
+	return s.authorized(context.Background(), request)
+}
+
+// AuthorizedWithContext implements ContextWebhook interface
+func (s *serviceAccountWebhook) AuthorizedWithContext(ctx context.Context, request admissionctl.Request) admissionctl.Response {
+	return s.authorized(ctx, request)
 }
 
-func (s *serviceAccountWebhook) authorized(request admissionctl.Request) admissionctl.Response {
+func (s *serviceAccountWebhook) authorized(ctx context.Context, request admissionctl.Request) admissionctl.Response {
 	var ret admissionctl.Response
+	trace := explain.FromContext(ctx)
 
-	if request.AdmissionRequest.UserInfo.Username == "system:unauthenticated" {
+	if utils.UnauthenticatedUsers.ExplainsRequest(ctx, "unauthenticated users", request) {
 		// This could highlight a significant problem with RBAC since an
 		// unauthenticated user should have no permissions.
 		log.Info("system:unauthenticated made a webhook request. Check RBAC rules", "request", request.AdmissionRequest)
-		ret = admissionctl.Denied("Unauthenticated")
-		ret.UID = request.AdmissionRequest.UID
+		ret = utils.WebhookResponse(request, false, "", utils.WithCode(helpers.CodeUnauthenticated, nil))
 		return ret
 	}
-	if strings.HasPrefix(request.AdmissionRequest.UserInfo.Username, "system:") {
-		ret = admissionctl.Allowed("authenticated system: users are allowed")
-		ret.UID = request.AdmissionRequest.UID
-		return ret
-	}
-	if strings.HasPrefix(request.AdmissionRequest.UserInfo.Username, "kube:") {
-		ret = admissionctl.Allowed("kube: users are allowed")
+	if match, ok := utils.SystemUsers.ExplainRequest(ctx, "system users", request); ok {
+		ret = admissionctl.Allowed(fmt.Sprintf("authenticated system users are allowed: %s", match))
 		ret.UID = request.AdmissionRequest.UID
 		return ret
 	}
@@ -114,11 +119,11 @@ func (s *serviceAccountWebhook) authorized(request admissionctl.Request) admissi
 		return admissionctl.Errored(http.StatusBadRequest, err)
 	}
 
-	if isProtectedNamespace(request) && !isAllowedUserGroup(request) {
-		if request.Operation == admissionv1.Delete && !isAllowedServiceAccount(sa) {
+	if trace.Recordf(isProtectedNamespace(request), "namespace is privileged and not in %q", exceptionNamespaces) && !isAllowedUserGroup(ctx, request) {
+		if trace.Recordf(request.Operation == admissionv1.Delete, "operation is %s", admissionv1.Delete) &&
+			!trace.Recordf(isAllowedServiceAccount(sa), "service account in %q", allowedServiceAccounts) {
 			log.Info(fmt.Sprintf("Deleting operation detected on proteced serviceaccount: %v", sa.Name))
-			ret = admissionctl.Denied(fmt.Sprintf("Deleting protected service account under namespace %v is not allowed", request.Namespace))
-			ret.UID = request.AdmissionRequest.UID
+			ret = utils.WebhookResponse(request, false, "", utils.WithCode(helpers.CodeServiceAccountDelete, helpers.Args{"Namespace": request.Namespace}))
 			return ret
 		}
 	}
@@ -145,18 +150,9 @@ func (s *serviceAccountWebhook) renderServiceAccount(request admissionctl.Reques
 }
 
 // isAllowedUserGroup checks if the user or group is allowed to perform the action
-func isAllowedUserGroup(request admissionctl.Request) bool {
-	if slices.Contains(allowedUsers, request.UserInfo.Username) {
-		return true
-	}
-
-	for _, group := range allowedGroups {
-		if slices.Contains(request.UserInfo.Groups, group) {
-			return true
-		}
-	}
-
-	return false
+func isAllowedUserGroup(ctx context.Context, request admissionctl.Request) bool {
+	return explain.FromContext(ctx).Record(allowlist.Allowed(WebhookName, request.AdmissionRequest.UserInfo), "user in allowlist") ||
+		allowedPrincipals.ExplainsRequest(ctx, "allowed principals", request)
 }
 
 // isProtectedNamespace checks if the request is going to operate on the serviceaccount in the
@@ -194,6 +190,11 @@ func (s *serviceAccountWebhook) Validate(request admissionctl.Request) bool {
 	return valid
 }
 
+// SelfTestKind implements SelfTestWebhook interface
+func (s *serviceAccountWebhook) SelfTestKind() metav1.GroupKind {
+	return metav1.GroupKind{Kind: "ServiceAccount"}
+}
+
 // Name implements Webhook interface
 func (s *serviceAccountWebhook) Name() string {
 	return WebhookName
@@ -243,3 +244,6 @@ func (s *serviceAccountWebhook) SyncSetLabelSelector() metav1.LabelSelector {
 func (s *serviceAccountWebhook) ClassicEnabled() bool { return true }
 
 func (s *serviceAccountWebhook) HypershiftEnabled() bool { return true }
+
+// AllowlistEnabled implements AllowlistWebhook interface
+func (s *serviceAccountWebhook) AllowlistEnabled() bool { return true }
@@ -1,11 +1,14 @@
 package techpreviewnoupgrade
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"os"
 
 	configv1 "github.com/openshift/api/config/v1"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/explain"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/helpers"
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
 	admissionv1 "k8s.io/api/admission/v1"
 	admissionregv1 "k8s.io/api/admissionregistration/v1"
@@ -76,8 +79,18 @@ func (s *TechPreviewNoUpgradeWebhook) Validate(req admissionctl.Request) bool {
 	return valid
 }
 
+// SelfTestKind implements SelfTestWebhook interface
+func (s *TechPreviewNoUpgradeWebhook) SelfTestKind() metav1.GroupKind {
+	return metav1.GroupKind{Group: "config.openshift.io", Kind: "FeatureGate"}
+}
+
 func (s *TechPreviewNoUpgradeWebhook) Authorized(request admissionctl.Request) admissionctl.Response {
-	return s.authorized(request)
+	return s.authorized(context.Background(), request)
+}
+
+// AuthorizedWithContext implements ContextWebhook interface
+func (s *TechPreviewNoUpgradeWebhook) AuthorizedWithContext(ctx context.Context, request admissionctl.Request) admissionctl.Response {
+	return s.authorized(ctx, request)
 }
 
 func (s *TechPreviewNoUpgradeWebhook) SyncSetLabelSelector() metav1.LabelSelector {
@@ -101,7 +114,7 @@ func (s *TechPreviewNoUpgradeWebhook) renderFeatureGate(request admissionctl.Req
 	return featureGate, nil
 }
 
-func (s *TechPreviewNoUpgradeWebhook) authorized(request admissionctl.Request) admissionctl.Response {
+func (s *TechPreviewNoUpgradeWebhook) authorized(ctx context.Context, request admissionctl.Request) admissionctl.Response {
 	var ret admissionctl.Response
 
 	featureGate, err := s.renderFeatureGate(request)
@@ -115,11 +128,10 @@ func (s *TechPreviewNoUpgradeWebhook) authorized(request admissionctl.Request) a
 		return ret
 	}
 
-	if featureGate != nil && featureGate.Spec.FeatureSet == "TechPreviewNoUpgrade" {
+	if explain.FromContext(ctx).Record(featureGate != nil && featureGate.Spec.FeatureSet == "TechPreviewNoUpgrade", "feature set is TechPreviewNoUpgrade") {
 		log.Info("Not allowing access because of TechPreviewNoUpgrade Feature Gate", "request", request.AdmissionRequest)
 
-		ret = admissionctl.Denied("The TechPreviewNoUpgrade Feature Gate is not allowed")
-		ret.UID = request.AdmissionRequest.UID
+		ret = utils.WebhookResponse(request, false, "", utils.WithCode(helpers.CodeTechPreviewNoUpgrade, nil))
 
 		return ret
 	}
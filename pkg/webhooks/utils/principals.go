@@ -0,0 +1,232 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/explain"
+)
+
+// Clause is the kind of rule in Principals which matched a user
+type Clause string
+
+const (
+	// UserClause matches an exact username
+	UserClause Clause = "user"
+	// UserPrefixClause matches usernames with a prefix
+	UserPrefixClause Clause = "user prefix"
+	// UserPatternClause matches usernames with a regular expression
+	UserPatternClause Clause = "user pattern"
+	// GroupClause matches an exact group name
+	GroupClause Clause = "group"
+	// GroupPatternClause matches group names with a regular expression
+	GroupPatternClause Clause = "group pattern"
+)
+
+// ScopesExtraKey is the key of UserInfo.Extra which holds the scopes of an
+// OpenShift OAuth token. It is absent for tokens with full access and other
+// credentials.
+const ScopesExtraKey = "scopes.authorization.openshift.io"
+
+var (
+	// PrivilegedServiceAccountGroupsRe is PrivilegedServiceAccountGroups compiled
+	PrivilegedServiceAccountGroupsRe = regexp.MustCompile(PrivilegedServiceAccountGroups)
+
+	// PrivilegedServiceAccounts are the serviceaccounts our webhooks commonly
+	// allow to perform restricted actions
+	PrivilegedServiceAccounts = Principals{
+		GroupPatterns: []*regexp.Regexp{PrivilegedServiceAccountGroupsRe},
+	}
+	// UnauthenticatedUsers are requests without credentials. RBAC should
+	// never let them reach a webhook, so webhooks deny them.
+	UnauthenticatedUsers = Principals{
+		Users: []string{"system:unauthenticated"},
+	}
+	// SystemUsers are the users of the API server, controllers and nodes.
+	// Check UnauthenticatedUsers first, whose username has the same prefix.
+	SystemUsers = Principals{
+		UserPrefixes: []string{"system:", "kube:"},
+	}
+	// ServiceAccounts are every serviceaccount
+	ServiceAccounts = Principals{
+		UserPrefixes: []string{"system:serviceaccount:"},
+	}
+	// SREUsers are Red Hat SRE acting through backplane, as classified in
+	// metrics. Webhooks may allow fewer of them.
+	SREUsers = Principals{
+		Users: []string{"backplane-cluster-admin"},
+		Groups: []string{
+			"system:serviceaccounts:openshift-backplane-srep",
+			"system:serviceaccounts:openshift-backplane-cee",
+		},
+	}
+)
+
+// Principals is a set of users, described by their username or groups, which
+// a webhook allows or denies. The zero value matches nobody.
+type Principals struct {
+	// Users are exact usernames
+	Users []string
+	// UserPrefixes match usernames which start with any of them
+	UserPrefixes []string
+	// UserPatterns are matched against the username
+	UserPatterns []*regexp.Regexp
+	// Groups are exact group names
+	Groups []string
+	// GroupPatterns are matched against each of the user's groups
+	GroupPatterns []*regexp.Regexp
+	// ExcludedUsers are never matched, even by Users or the user's groups
+	ExcludedUsers []string
+	// ExcludedUserPrefixes exclude usernames which start with any of them,
+	// e.g. to match the system: users which are not serviceaccounts
+	ExcludedUserPrefixes []string
+	// RequiredExtra restricts the other rules to users with each of its keys
+	// in UserInfo.Extra, and one of the key's values if it has any
+	RequiredExtra map[string][]string
+	// ExcludedExtra are never matched: users with any of its keys, or one of
+	// the key's values if it has any, e.g. ScopesExtraKey to exclude scoped
+	// tokens
+	ExcludedExtra map[string][]string
+}
+
+// PrincipalMatch explains which rule of Principals matched a user
+type PrincipalMatch struct {
+	Clause Clause
+	// Rule is the username, prefix, group or pattern which matched
+	Rule string
+	// Value is the username or group it matched
+	Value string
+}
+
+// String describes the match, e.g.
+// `group pattern "^system:serviceaccounts:..." matched "system:serviceaccounts:openshift-foo"`
+func (m PrincipalMatch) String() string {
+	return fmt.Sprintf("%s %q matched %q", m.Clause, m.Rule, m.Value)
+}
+
+// Match returns which rule matched userInfo, checking Users, UserPrefixes,
+// UserPatterns, Groups and GroupPatterns in that order, once the user has
+// passed the exclusions and requirements
+func (p Principals) Match(userInfo authenticationv1.UserInfo) (PrincipalMatch, bool) {
+	return p.match(userInfo, nil)
+}
+
+// match is Match, recording each rule it evaluates in trace
+func (p Principals) match(userInfo authenticationv1.UserInfo, trace *explain.Trace) (PrincipalMatch, bool) {
+	username := userInfo.Username
+	if len(p.ExcludedUsers) > 0 && trace.Recordf(slices.Contains(p.ExcludedUsers, username), "user in excluded %q", p.ExcludedUsers) {
+		return PrincipalMatch{}, false
+	}
+	for _, prefix := range p.ExcludedUserPrefixes {
+		if trace.Recordf(strings.HasPrefix(username, prefix), "user prefix %s excluded", prefix) {
+			return PrincipalMatch{}, false
+		}
+	}
+	for _, key := range slices.Sorted(maps.Keys(p.ExcludedExtra)) {
+		values := p.ExcludedExtra[key]
+		if trace.Recordf(hasExtra(userInfo, key, values), "%s excluded", extraRule(key, values)) {
+			return PrincipalMatch{}, false
+		}
+	}
+	for _, key := range slices.Sorted(maps.Keys(p.RequiredExtra)) {
+		values := p.RequiredExtra[key]
+		if !trace.Recordf(hasExtra(userInfo, key, values), "%s required", extraRule(key, values)) {
+			return PrincipalMatch{}, false
+		}
+	}
+	if len(p.Users) > 0 && trace.Recordf(slices.Contains(p.Users, username), "user in %q", p.Users) {
+		return PrincipalMatch{Clause: UserClause, Rule: username, Value: username}, true
+	}
+	for _, prefix := range p.UserPrefixes {
+		if trace.Recordf(strings.HasPrefix(username, prefix), "user prefix %s", prefix) {
+			return PrincipalMatch{Clause: UserPrefixClause, Rule: prefix, Value: username}, true
+		}
+	}
+	for _, re := range p.UserPatterns {
+		if trace.Recordf(re.MatchString(username), "user pattern %s", re) {
+			return PrincipalMatch{Clause: UserPatternClause, Rule: re.String(), Value: username}, true
+		}
+	}
+	if len(p.Groups) > 0 {
+		for _, group := range userInfo.Groups {
+			if slices.Contains(p.Groups, group) {
+				trace.Recordf(true, "group in %q", p.Groups)
+				return PrincipalMatch{Clause: GroupClause, Rule: group, Value: group}, true
+			}
+		}
+		trace.Recordf(false, "group in %q", p.Groups)
+	}
+	for _, re := range p.GroupPatterns {
+		for _, group := range userInfo.Groups {
+			if re.MatchString(group) {
+				trace.Recordf(true, "group pattern %s", re)
+				return PrincipalMatch{Clause: GroupPatternClause, Rule: re.String(), Value: group}, true
+			}
+		}
+		trace.Recordf(false, "group pattern %s", re)
+	}
+	return PrincipalMatch{}, false
+}
+
+// Matches returns true if any rule matches userInfo
+func (p Principals) Matches(userInfo authenticationv1.UserInfo) bool {
+	_, ok := p.Match(userInfo)
+	return ok
+}
+
+// MatchRequest returns which rule matched the user making request
+func (p Principals) MatchRequest(request admissionctl.Request) (PrincipalMatch, bool) {
+	return p.Match(request.AdmissionRequest.UserInfo)
+}
+
+// MatchesRequest returns true if any rule matches the user making request
+func (p Principals) MatchesRequest(request admissionctl.Request) bool {
+	return p.Matches(request.AdmissionRequest.UserInfo)
+}
+
+// ExplainRequest is MatchRequest, recording each rule it evaluates, as the
+// check prefixed with name, in the trace for the /explain endpoint carried by
+// ctx
+func (p Principals) ExplainRequest(ctx context.Context, name string, request admissionctl.Request) (PrincipalMatch, bool) {
+	return p.match(request.AdmissionRequest.UserInfo, explain.FromContext(ctx).Prefixed(name+": "))
+}
+
+// ExplainsRequest is MatchesRequest, recording each rule it evaluates as
+// ExplainRequest does
+func (p Principals) ExplainsRequest(ctx context.Context, name string, request admissionctl.Request) bool {
+	_, ok := p.ExplainRequest(ctx, name, request)
+	return ok
+}
+
+// Scopes returns the scopes of the OpenShift OAuth token userInfo
+// authenticated with, which are empty when the token has full access
+func Scopes(userInfo authenticationv1.UserInfo) []string {
+	return userInfo.Extra[ScopesExtraKey]
+}
+
+// hasExtra returns true if userInfo has key in its Extra, with one of values
+// if there are any
+func hasExtra(userInfo authenticationv1.UserInfo, key string, values []string) bool {
+	extra, ok := userInfo.Extra[key]
+	if !ok {
+		return false
+	}
+	return len(values) == 0 || slices.ContainsFunc(extra, func(v string) bool {
+		return slices.Contains(values, v)
+	})
+}
+
+// extraRule describes a rule on key in UserInfo.Extra
+func extraRule(key string, values []string) string {
+	if len(values) == 0 {
+		return fmt.Sprintf("extra %s", key)
+	}
+	return fmt.Sprintf("extra %s in %q", key, values)
+}
@@ -0,0 +1,208 @@
+package utils
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/explain"
+)
+
+func TestPrincipalsMatch(t *testing.T) {
+	principals := Principals{
+		Users:         []string{"backplane-cluster-admin", "system:admin"},
+		UserPrefixes:  []string{"kube:"},
+		UserPatterns:  []*regexp.Regexp{regexp.MustCompile(`^system:serviceaccount:openshift-example:`)},
+		Groups:        []string{"system:serviceaccounts:openshift-backplane-srep"},
+		GroupPatterns: []*regexp.Regexp{PrivilegedServiceAccountGroupsRe},
+		ExcludedUsers: []string{"kube:excluded"},
+	}
+
+	tests := []struct {
+		name     string
+		userInfo authenticationv1.UserInfo
+		expected PrincipalMatch
+		matches  bool
+	}{
+		{
+			name:     "user",
+			userInfo: authenticationv1.UserInfo{Username: "backplane-cluster-admin"},
+			expected: PrincipalMatch{Clause: UserClause, Rule: "backplane-cluster-admin", Value: "backplane-cluster-admin"},
+			matches:  true,
+		},
+		{
+			name:     "user prefix",
+			userInfo: authenticationv1.UserInfo{Username: "kube:admin"},
+			expected: PrincipalMatch{Clause: UserPrefixClause, Rule: "kube:", Value: "kube:admin"},
+			matches:  true,
+		},
+		{
+			name:     "user pattern",
+			userInfo: authenticationv1.UserInfo{Username: "system:serviceaccount:openshift-example:operator"},
+			expected: PrincipalMatch{Clause: UserPatternClause, Rule: `^system:serviceaccount:openshift-example:`, Value: "system:serviceaccount:openshift-example:operator"},
+			matches:  true,
+		},
+		{
+			name:     "group",
+			userInfo: authenticationv1.UserInfo{Username: "sre", Groups: []string{"system:authenticated", "system:serviceaccounts:openshift-backplane-srep"}},
+			expected: PrincipalMatch{Clause: GroupClause, Rule: "system:serviceaccounts:openshift-backplane-srep", Value: "system:serviceaccounts:openshift-backplane-srep"},
+			matches:  true,
+		},
+		{
+			name:     "group pattern",
+			userInfo: authenticationv1.UserInfo{Username: "system:serviceaccount:openshift-foo:bar", Groups: []string{"system:serviceaccounts", "system:serviceaccounts:openshift-foo"}},
+			expected: PrincipalMatch{Clause: GroupPatternClause, Rule: PrivilegedServiceAccountGroups, Value: "system:serviceaccounts:openshift-foo"},
+			matches:  true,
+		},
+		{
+			name:     "user takes precedence over group",
+			userInfo: authenticationv1.UserInfo{Username: "system:admin", Groups: []string{"system:serviceaccounts:openshift-backplane-srep"}},
+			expected: PrincipalMatch{Clause: UserClause, Rule: "system:admin", Value: "system:admin"},
+			matches:  true,
+		},
+		{
+			name:     "excluded user",
+			userInfo: authenticationv1.UserInfo{Username: "kube:excluded", Groups: []string{"system:serviceaccounts:openshift-backplane-srep"}},
+		},
+		{
+			name:     "no match",
+			userInfo: authenticationv1.UserInfo{Username: "customer", Groups: []string{"dedicated-admins", "system:serviceaccounts:customer"}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			match, ok := principals.Match(test.userInfo)
+			if ok != test.matches {
+				t.Fatalf("expected matches=%v, got %v (%s)", test.matches, ok, match)
+			}
+			if match != test.expected {
+				t.Errorf("expected %s, got %s", test.expected, match)
+			}
+		})
+	}
+}
+
+func TestPrincipalsExcludedUserPrefixes(t *testing.T) {
+	principals := Principals{
+		UserPrefixes:         []string{"system:"},
+		ExcludedUserPrefixes: []string{"system:serviceaccount:"},
+	}
+	if !principals.Matches(authenticationv1.UserInfo{Username: "system:kube-controller-manager"}) {
+		t.Error("expected a system: user to match")
+	}
+	if principals.Matches(authenticationv1.UserInfo{Username: "system:serviceaccount:openshift-foo:bar"}) {
+		t.Error("expected a serviceaccount to be excluded")
+	}
+}
+
+func TestCommonPrincipals(t *testing.T) {
+	unauthenticated := authenticationv1.UserInfo{Username: "system:unauthenticated", Groups: []string{"system:unauthenticated"}}
+	if !UnauthenticatedUsers.Matches(unauthenticated) {
+		t.Error("expected system:unauthenticated to be an unauthenticated user")
+	}
+	for _, username := range []string{"system:admin", "kube:admin", "system:serviceaccount:openshift-foo:bar"} {
+		if !SystemUsers.Matches(authenticationv1.UserInfo{Username: username}) {
+			t.Errorf("expected %s to be a system user", username)
+		}
+	}
+	if SystemUsers.Matches(authenticationv1.UserInfo{Username: "backplane-cluster-admin"}) {
+		t.Error("expected backplane-cluster-admin not to be a system user")
+	}
+	for _, group := range []string{"system:serviceaccounts:openshift-foo", "system:serviceaccounts:redhat-bar", "system:serviceaccounts:osde2e-h-abcde"} {
+		if !PrivilegedServiceAccounts.Matches(authenticationv1.UserInfo{Groups: []string{group}}) {
+			t.Errorf("expected group %s to be privileged", group)
+		}
+	}
+	if PrivilegedServiceAccounts.Matches(authenticationv1.UserInfo{Groups: []string{"system:serviceaccounts:customer"}}) {
+		t.Error("expected a customer's serviceaccounts not to be privileged")
+	}
+}
+
+func TestPrincipalsExtra(t *testing.T) {
+	principals := Principals{
+		Users:         []string{"backplane-cluster-admin"},
+		ExcludedExtra: map[string][]string{ScopesExtraKey: nil},
+	}
+	if !principals.Matches(authenticationv1.UserInfo{Username: "backplane-cluster-admin"}) {
+		t.Error("expected a user with a full access token to match")
+	}
+	if principals.Matches(authenticationv1.UserInfo{Username: "backplane-cluster-admin", Extra: map[string]authenticationv1.ExtraValue{
+		ScopesExtraKey: {"user:info"},
+	}}) {
+		t.Error("expected a user with a scoped token not to match")
+	}
+
+	required := Principals{
+		UserPrefixes:  []string{"system:serviceaccount:"},
+		RequiredExtra: map[string][]string{"authentication.kubernetes.io/node-name": nil, ScopesExtraKey: {"user:full", "role:admin"}},
+	}
+	if !required.Matches(authenticationv1.UserInfo{Username: "system:serviceaccount:foo:bar", Extra: map[string]authenticationv1.ExtraValue{
+		"authentication.kubernetes.io/node-name": {"node-a"},
+		ScopesExtraKey:                           {"role:admin"},
+	}}) {
+		t.Error("expected a user with the required extra to match")
+	}
+	if required.Matches(authenticationv1.UserInfo{Username: "system:serviceaccount:foo:bar", Extra: map[string]authenticationv1.ExtraValue{
+		"authentication.kubernetes.io/node-name": {"node-a"},
+		ScopesExtraKey:                           {"user:info"},
+	}}) {
+		t.Error("expected a user without a required value not to match")
+	}
+}
+
+func TestScopes(t *testing.T) {
+	user := authenticationv1.UserInfo{Username: "customer"}
+	if scopes := Scopes(user); len(scopes) != 0 {
+		t.Errorf("expected a full access token to have no scopes, got %v", scopes)
+	}
+	user.Extra = map[string]authenticationv1.ExtraValue{ScopesExtraKey: {"user:full"}}
+	if scopes := Scopes(user); len(scopes) != 1 || scopes[0] != "user:full" {
+		t.Errorf("expected the token's scopes, got %v", scopes)
+	}
+}
+
+func TestPrincipalsExplainRequest(t *testing.T) {
+	principals := Principals{
+		Users:                []string{"backplane-cluster-admin"},
+		UserPrefixes:         []string{"system:"},
+		Groups:               []string{"cluster-admins"},
+		GroupPatterns:        []*regexp.Regexp{regexp.MustCompile(`^sre-`)},
+		ExcludedUserPrefixes: []string{"system:serviceaccount:"},
+		ExcludedExtra:        map[string][]string{ScopesExtraKey: nil},
+	}
+	request := admissionctl.Request{AdmissionRequest: admissionv1.AdmissionRequest{UserInfo: authenticationv1.UserInfo{
+		Username: "customer",
+		Groups:   []string{"sre-team"},
+	}}}
+	trace := explain.New()
+	match, ok := principals.ExplainRequest(explain.NewContext(context.Background(), trace), "admins", request)
+	if !ok || match.Clause != GroupPatternClause {
+		t.Fatalf("expected the group pattern to match, got %v, %v", match, ok)
+	}
+	expected := []string{
+		"admins: user prefix system:serviceaccount: excluded → no",
+		"admins: extra scopes.authorization.openshift.io excluded → no",
+		`admins: user in ["backplane-cluster-admin"] → no`,
+		"admins: user prefix system: → no",
+		`admins: group in ["cluster-admins"] → no`,
+		"admins: group pattern ^sre- → yes",
+	}
+	checks := trace.Checks()
+	if len(checks) != len(expected) {
+		t.Fatalf("expected %d checks, got %v", len(expected), checks)
+	}
+	for i, check := range checks {
+		if check.String() != expected[i] {
+			t.Errorf("expected check %d to be %q, got %q", i, expected[i], check)
+		}
+	}
+
+	// Without a Trace in the context it is MatchRequest
+	if _, ok := principals.ExplainRequest(context.Background(), "admins", request); !ok {
+		t.Error("expected a match without a trace")
+	}
+}
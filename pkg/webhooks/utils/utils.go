@@ -4,16 +4,23 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
 	"regexp"
 	"slices"
+	"strings"
 
 	admissionv1 "k8s.io/api/admission/v1"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/helpers"
 )
 
 const (
@@ -25,11 +32,38 @@ const (
 	PrivilegedServiceAccountGroups string = `^system:serviceaccounts:(kube-.*|openshift|openshift-.*|default|redhat-.*|osde2e-(h-)?[a-z0-9]{5})`
 )
 
+// Audit annotations describing a decision. The dispatcher adds
+// WebhookAnnotation and DecisionCodeAnnotation to every response.
+const (
+	// WebhookAnnotation is the name of the webhook which made the decision
+	WebhookAnnotation = "webhook"
+	// DecisionCodeAnnotation is the HTTP status code of the decision
+	DecisionCodeAnnotation = "decision-code"
+	// RuleIDAnnotation identifies which of the webhook's rules made the
+	// decision
+	RuleIDAnnotation = "rule-id"
+)
+
 var (
 	admissionScheme = runtime.NewScheme()
 	admissionCodecs = serializer.NewCodecFactory(admissionScheme)
+
+	// SupportedAdmissionReviewVersions are the AdmissionReview versions
+	// ParseHTTPRequestVersion understands, in order of preference
+	SupportedAdmissionReviewVersions = []string{
+		admissionv1.SchemeGroupVersion.Version,
+		admissionv1beta1.SchemeGroupVersion.Version,
+	}
 )
 
+// unversionedAdmissionReview is used to decode both v1 and v1beta1
+// AdmissionReviews
+type unversionedAdmissionReview struct {
+	admissionv1.AdmissionReview
+}
+
+var _ runtime.Object = &unversionedAdmissionReview{}
+
 func RequestMatchesGroupKind(req admissionctl.Request, kind, group string) bool {
 	return req.Kind.Kind == kind && req.Kind.Group == group
 }
@@ -68,59 +102,228 @@ func RegexSliceContains(needle string, haystack []string) bool {
 	return false
 }
 
+// ParseHTTPRequest decodes the AdmissionReview in r. See
+// ParseHTTPRequestVersion.
 func ParseHTTPRequest(r *http.Request) (admissionctl.Request, admissionctl.Response, error) {
+	req, resp, _, err := ParseHTTPRequestVersion(r)
+	return req, resp, err
+}
+
+// ParseHTTPRequestVersion decodes a v1 or v1beta1 AdmissionReview from r. It
+// also returns the AdmissionReview's GroupVersionKind so that the response can
+// be sent in the version the caller used.
+func ParseHTTPRequestVersion(r *http.Request) (admissionctl.Request, admissionctl.Response, schema.GroupVersionKind, error) {
 	var resp admissionctl.Response
 	var req admissionctl.Request
 	var err error
 	var body []byte
+	gvk := admissionv1.SchemeGroupVersion.WithKind("AdmissionReview")
 	if r.Body != nil {
 		if body, err = io.ReadAll(r.Body); err != nil {
 			resp = admissionctl.Errored(http.StatusBadRequest, err)
-			return req, resp, err
+			return req, resp, gvk, err
 		}
 	} else {
 		err := errors.New("request body is nil")
 		resp = admissionctl.Errored(http.StatusBadRequest, err)
-		return req, resp, err
+		return req, resp, gvk, err
 	}
 	if len(body) == 0 {
 		err := errors.New("request body is empty")
 		resp = admissionctl.Errored(http.StatusBadRequest, err)
-		return req, resp, err
+		return req, resp, gvk, err
 	}
 	contentType := r.Header.Get("Content-Type")
-	if contentType != validContentType {
+	if !isValidContentType(contentType) {
 		err := fmt.Errorf("contentType=%s, expected application/json", contentType)
 		resp = admissionctl.Errored(http.StatusBadRequest, err)
-		return req, resp, err
+		return req, resp, gvk, err
+	}
+	// v1 and v1beta1 AdmissionReviews are identical, so both are decoded into
+	// the v1 type. The decoder picks the type to decode into by the
+	// TypeMeta of an unregistered type, which coerces v1beta1 to v1.
+	ar := unversionedAdmissionReview{}
+	ar.SetGroupVersionKind(gvk)
+	_, actualGVK, err := admissionCodecs.UniversalDeserializer().Decode(body, nil, &ar)
+	if err != nil {
+		resp = admissionctl.Errored(http.StatusBadRequest, err)
+		return req, resp, gvk, err
+	}
+	// A review without a TypeMeta is taken to be v1, as it always has been
+	if actualGVK != nil && !actualGVK.Empty() {
+		gvk = *actualGVK
 	}
-	ar := admissionv1.AdmissionReview{}
-	if _, _, err := admissionCodecs.UniversalDeserializer().Decode(body, nil, &ar); err != nil {
+	if gvk.Group != admissionv1.GroupName || gvk.Kind != "AdmissionReview" || !slices.Contains(SupportedAdmissionReviewVersions, gvk.Version) {
+		err = fmt.Errorf("unsupported %s, expected an AdmissionReview of version %s", gvk, strings.Join(SupportedAdmissionReviewVersions, " or "))
+		gvk = admissionv1.SchemeGroupVersion.WithKind("AdmissionReview")
 		resp = admissionctl.Errored(http.StatusBadRequest, err)
-		return req, resp, err
+		return req, resp, gvk, err
 	}
 
 	// Copy for tracking
 	if ar.Request == nil {
 		err = fmt.Errorf("No request in request body")
 		resp = admissionctl.Errored(http.StatusBadRequest, err)
-		return req, resp, err
+		return req, resp, gvk, err
 	}
 	resp.UID = ar.Request.UID
 	req = admissionctl.Request{
 		AdmissionRequest: *ar.Request,
 	}
-	return req, resp, nil
+	return req, resp, gvk, nil
+}
+
+// isValidContentType accepts application/json, optionally with a UTF-8
+// charset parameter
+func isValidContentType(contentType string) bool {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != validContentType {
+		return false
+	}
+	if charset, ok := params["charset"]; ok && !strings.EqualFold(charset, "utf-8") {
+		return false
+	}
+	return true
 }
 
 // WebhookResponse assembles an allowed or denied admission response with the same UID as the provided request.
 // The reason for allowed admission responses is not shown to the end user and is commonly empty string: ""
-func WebhookResponse(request admissionctl.Request, allowed bool, reason string) admissionctl.Response {
+func WebhookResponse(request admissionctl.Request, allowed bool, reason string, opts ...ResponseOption) admissionctl.Response {
 	resp := admissionctl.ValidationResponse(allowed, reason)
 	resp.UID = request.UID
+	for _, opt := range opts {
+		opt(&resp)
+	}
+	// Identify the object for clients reading the details an option added
+	if resp.Result != nil && resp.Result.Details != nil && resp.Result.Details.Kind == "" {
+		resp.Result.Details.Name = request.Name
+		resp.Result.Details.Group = request.Kind.Group
+		resp.Result.Details.Kind = request.Kind.Kind
+	}
+	addFieldsToMessage(&resp)
 	return resp
 }
 
+// ResponseOption adds to an admission response. Options can be passed to
+// WebhookResponse or applied to any other response directly.
+type ResponseOption func(*admissionctl.Response)
+
+// WithWarnings adds admission warnings, which are shown to the user whether or
+// not the request is allowed, for example to announce that a request will be
+// rejected in a future release
+func WithWarnings(warnings ...string) ResponseOption {
+	return func(resp *admissionctl.Response) {
+		resp.Warnings = append(resp.Warnings, warnings...)
+	}
+}
+
+// WithAuditAnnotation adds an audit annotation explaining the decision. The
+// API server prefixes key with the webhook's name in its audit log.
+func WithAuditAnnotation(key, value string) ResponseOption {
+	return func(resp *admissionctl.Response) {
+		if resp.AuditAnnotations == nil {
+			resp.AuditAnnotations = map[string]string{}
+		}
+		resp.AuditAnnotations[key] = value
+	}
+}
+
+// WithRuleID records which of the webhook's rules made the decision
+func WithRuleID(id string) ResponseOption {
+	return WithAuditAnnotation(RuleIDAnnotation, id)
+}
+
+// WithCode replaces the message of a denial with the catalog message for
+// code, rendered with args, and sets code as the status reason and a cause in
+// the status details, so that programmatic clients can tell denials apart
+// without parsing their messages. The reason passed to WebhookResponse is
+// ignored.
+func WithCode(code helpers.Code, args helpers.Args) ResponseOption {
+	return WithCodeText(code, code.Text(args))
+}
+
+// WithCodeText is WithCode for a message which was not rendered from the
+// catalog, such as a declarative policy's CEL message
+func WithCodeText(code helpers.Code, text string) ResponseOption {
+	return func(resp *admissionctl.Response) {
+		if resp.Result == nil {
+			resp.Result = &metav1.Status{}
+		}
+		resp.Result.Message = code.Format(text)
+		resp.Result.Reason = metav1.StatusReason(code)
+		if resp.Result.Details == nil {
+			resp.Result.Details = &metav1.StatusDetails{}
+		}
+		resp.Result.Details.Causes = append(resp.Result.Details.Causes, metav1.StatusCause{
+			Type:    metav1.CauseType(code),
+			Message: text,
+		})
+	}
+}
+
+// WithFieldErrors adds a cause to the status details for each field which led
+// to a denial, with the field's path and the type of the error, in the same
+// way as the API server's own validation. WebhookResponse also lists the
+// fields in the message, since oc and kubectl only print the causes of
+// responses with the Invalid reason.
+func WithFieldErrors(errs field.ErrorList) ResponseOption {
+	return func(resp *admissionctl.Response) {
+		if len(errs) == 0 {
+			return
+		}
+		if resp.Result == nil {
+			resp.Result = &metav1.Status{}
+		}
+		if resp.Result.Details == nil {
+			resp.Result.Details = &metav1.StatusDetails{}
+		}
+		for _, err := range errs {
+			resp.Result.Details.Causes = append(resp.Result.Details.Causes, metav1.StatusCause{
+				Type:    metav1.CauseType(err.Type),
+				Field:   err.Field,
+				Message: err.ErrorBody(),
+			})
+		}
+	}
+}
+
+// addFieldsToMessage appends the causes added by WithFieldErrors to the
+// message. For a coded denial they are added to the catalog text, before the
+// links WithCode adds, whichever order the options were passed in.
+func addFieldsToMessage(resp *admissionctl.Response) {
+	if resp.Result == nil || resp.Result.Details == nil {
+		return
+	}
+	var fields []string
+	text, coded := resp.Result.Message, false
+	for _, cause := range resp.Result.Details.Causes {
+		if cause.Field != "" {
+			fields = append(fields, fmt.Sprintf("%s: %s", cause.Field, cause.Message))
+			continue
+		}
+		if _, ok := helpers.Lookup(helpers.Code(cause.Type)); ok && string(cause.Type) == string(resp.Result.Reason) {
+			text, coded = cause.Message, true
+		}
+	}
+	if len(fields) == 0 {
+		return
+	}
+	text = strings.TrimSpace(sentence(text) + " Fields: " + sentence(strings.Join(fields, "; ")))
+	if coded {
+		text = helpers.Code(resp.Result.Reason).Format(text)
+	}
+	resp.Result.Message = text
+}
+
+// sentence ends s with a full stop if it is not empty and doesn't have one
+func sentence(s string) string {
+	if s == "" || strings.HasSuffix(s, ".") {
+		return s
+	}
+	return s + "."
+}
+
 func init() {
 	utilruntime.Must(admissionv1.AddToScheme(admissionScheme))
+	utilruntime.Must(admissionv1beta1.AddToScheme(admissionScheme))
 }
@@ -1,11 +1,17 @@
 package utils
 
 import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	admissionv1 "k8s.io/api/admission/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/helpers"
 )
 
 func TestRequestMatchesGroupKind(t *testing.T) {
@@ -55,3 +61,170 @@ func TestRequestMatchesGroupKind(t *testing.T) {
 		})
 	}
 }
+
+func TestParseHTTPRequestVersion(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantVersion string
+		wantErr     bool
+	}{
+		{
+			name:        "v1",
+			contentType: "application/json",
+			body:        `{"kind":"AdmissionReview","apiVersion":"admission.k8s.io/v1","request":{"uid":"test-uid"}}`,
+			wantVersion: "v1",
+		},
+		{
+			name:        "v1beta1",
+			contentType: "application/json",
+			body:        `{"kind":"AdmissionReview","apiVersion":"admission.k8s.io/v1beta1","request":{"uid":"test-uid"}}`,
+			wantVersion: "v1beta1",
+		},
+		{
+			name:        "utf-8 charset",
+			contentType: "application/json; charset=UTF-8",
+			body:        `{"kind":"AdmissionReview","apiVersion":"admission.k8s.io/v1","request":{"uid":"test-uid"}}`,
+			wantVersion: "v1",
+		},
+		{
+			name:        "other charset",
+			contentType: "application/json; charset=iso-8859-1",
+			body:        `{"kind":"AdmissionReview","apiVersion":"admission.k8s.io/v1","request":{"uid":"test-uid"}}`,
+			wantErr:     true,
+		},
+		{
+			name:        "other media type",
+			contentType: "application/yaml",
+			body:        `{"kind":"AdmissionReview","apiVersion":"admission.k8s.io/v1","request":{"uid":"test-uid"}}`,
+			wantErr:     true,
+		},
+		{
+			name:        "unsupported version",
+			contentType: "application/json",
+			body:        `{"kind":"AdmissionReview","apiVersion":"admission.k8s.io/v2","request":{"uid":"test-uid"}}`,
+			wantErr:     true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(test.body))
+			r.Header.Set("Content-Type", test.contentType)
+			req, resp, gvk, err := ParseHTTPRequestVersion(r)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("expected error=%v, got %v", test.wantErr, err)
+			}
+			if test.wantErr {
+				if resp.Result == nil || resp.Result.Code != http.StatusBadRequest {
+					t.Errorf("expected a %d response, got %v", http.StatusBadRequest, resp.Result)
+				}
+				return
+			}
+			if gvk.Version != test.wantVersion {
+				t.Errorf("expected version %s, got %s", test.wantVersion, gvk.Version)
+			}
+			if req.UID != "test-uid" {
+				t.Errorf("expected UID test-uid, got %q", req.UID)
+			}
+		})
+	}
+}
+
+func TestWebhookResponseOptions(t *testing.T) {
+	request := admissionctl.Request{AdmissionRequest: admissionv1.AdmissionRequest{UID: "test-uid"}}
+	resp := WebhookResponse(request, false, "not allowed",
+		WithRuleID("no-infra-toleration"),
+		WithAuditAnnotation("toleration", "node-role.kubernetes.io/infra"),
+		WithWarnings("first", "second"),
+	)
+	if resp.Allowed || resp.UID != "test-uid" || resp.Result.Message != "not allowed" {
+		t.Errorf("expected a denial for test-uid, got %+v", resp)
+	}
+	if resp.AuditAnnotations[RuleIDAnnotation] != "no-infra-toleration" || resp.AuditAnnotations["toleration"] != "node-role.kubernetes.io/infra" {
+		t.Errorf("expected both audit annotations, got %v", resp.AuditAnnotations)
+	}
+	if len(resp.Warnings) != 2 || resp.Warnings[0] != "first" {
+		t.Errorf("expected two warnings in order, got %v", resp.Warnings)
+	}
+
+	resp = WebhookResponse(request, true, "")
+	if !resp.Allowed || resp.AuditAnnotations != nil || resp.Warnings != nil {
+		t.Errorf("expected a plain allowed response without options, got %+v", resp)
+	}
+}
+
+func TestWithCode(t *testing.T) {
+	request := admissionctl.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+		UID:  "test-uid",
+		Name: "cluster-admin",
+		Kind: metav1.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "ClusterRole"},
+	}}
+	resp := WebhookResponse(request, false, "", WithCode(helpers.CodeClusterRoleDelete, helpers.Args{"Name": "cluster-admin"}), WithRuleID("delete"))
+	text := "Deleting ClusterRole cluster-admin is not allowed."
+	if resp.Allowed || resp.Result.Message != helpers.CodeClusterRoleDelete.Format(text) {
+		t.Errorf("expected a denial with the catalog message, got %+v", resp.Result)
+	}
+	if resp.Result.Reason != metav1.StatusReason(helpers.CodeClusterRoleDelete) {
+		t.Errorf("expected the code as the reason, got %q", resp.Result.Reason)
+	}
+	details := resp.Result.Details
+	if details == nil || details.Name != "cluster-admin" || details.Kind != "ClusterRole" || details.Group != "rbac.authorization.k8s.io" {
+		t.Fatalf("expected details identifying the object, got %+v", details)
+	}
+	if len(details.Causes) != 1 || string(details.Causes[0].Type) != string(helpers.CodeClusterRoleDelete) || details.Causes[0].Message != text {
+		t.Errorf("expected the code as a cause, got %+v", details.Causes)
+	}
+	if resp.AuditAnnotations[RuleIDAnnotation] != "delete" {
+		t.Errorf("expected other options to apply, got %v", resp.AuditAnnotations)
+	}
+}
+
+func TestWithFieldErrors(t *testing.T) {
+	request := admissionctl.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+		UID:  "test-uid",
+		Name: "cluster",
+		Kind: metav1.GroupVersionKind{Group: "config.openshift.io", Version: "v1", Kind: "Network"},
+	}}
+	path := field.NewPath("spec", "networkType")
+	errs := field.ErrorList{field.Forbidden(path, "may not be changed from OpenShiftSDN")}
+	text := "Changing the network type is not allowed. Fields: spec.networkType: Forbidden: may not be changed from OpenShiftSDN."
+
+	for name, opts := range map[string][]ResponseOption{
+		"fields first": {WithFieldErrors(errs), WithCode(helpers.CodeSDNMigration, nil)},
+		"code first":   {WithCode(helpers.CodeSDNMigration, nil), WithFieldErrors(errs)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			resp := WebhookResponse(request, false, "", opts...)
+			if resp.Allowed || resp.Result.Message != helpers.CodeSDNMigration.Format(text) {
+				t.Errorf("expected the fields in the catalog message, got %q", resp.Result.Message)
+			}
+			if resp.Result.Reason != metav1.StatusReason(helpers.CodeSDNMigration) {
+				t.Errorf("expected the code as the reason, got %q", resp.Result.Reason)
+			}
+			var cause *metav1.StatusCause
+			for i := range resp.Result.Details.Causes {
+				if resp.Result.Details.Causes[i].Field != "" {
+					cause = &resp.Result.Details.Causes[i]
+				}
+			}
+			if cause == nil || cause.Field != "spec.networkType" || cause.Type != metav1.CauseType(field.ErrorTypeForbidden) {
+				t.Errorf("expected a cause for the field, got %+v", resp.Result.Details.Causes)
+			}
+		})
+	}
+
+	resp := WebhookResponse(request, false, "Prevented", WithFieldErrors(errs))
+	if resp.Result.Message != "Prevented. Fields: spec.networkType: Forbidden: may not be changed from OpenShiftSDN." {
+		t.Errorf("expected the fields in the message, got %q", resp.Result.Message)
+	}
+	if resp.Result.Details.Kind != "Network" || len(resp.Result.Details.Causes) != 1 {
+		t.Errorf("expected details identifying the object, got %+v", resp.Result.Details)
+	}
+
+	resp = WebhookResponse(request, false, "Prevented", WithFieldErrors(nil))
+	if resp.Result.Message != "Prevented" || resp.Result.Details != nil {
+		t.Errorf("expected no field errors to leave the response alone, got %+v", resp.Result)
+	}
+}